@@ -0,0 +1,110 @@
+package main
+
+import (
+	"aeds2-tp1/domain"
+	"aeds2-tp1/infrastructure"
+	"aeds2-tp1/storage"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+)
+
+func runCompare(args []string) error {
+	flags := flag.NewFlagSet("compare", flag.ContinueOnError)
+	numRecords := flags.Int("records", 10000, "número de registros gerados")
+	blockSizesSpec := flags.String("block-sizes", "256-4096", "tamanhos de bloco: lista (256,512), intervalo dobrando (256-4096) ou intervalo com passo (256-4096:256)")
+	lookups := flags.Int("lookups", 1000, "número de buscas por matrícula em cada execução")
+	formatName := flags.String("format", string(infrastructure.FormatCSV), "formato da tabela: texto, json, csv ou markdown")
+	output := flags.String("output", "", "arquivo da tabela (padrão comparacao.csv, .json, .md ou .txt)")
+	dir := flags.String("dir", "", "diretório dos arquivos temporários (padrão o diretório temporário do sistema)")
+	seed := flags.Uint64("seed", 0, "semente do gerador de dados (sem a opção, uma semente aleatória é sorteada e exibida)")
+	encodingName := flags.String("encoding", "utf-8", "codificação dos textos nos arquivos: utf-8 ou latin1 (ISO-8859-1)")
+	profile := flags.String("profile", domain.DefaultProfileName, "perfil do gerador: "+strings.Join(domain.BuiltinProfileNames(), ", ")+" ou caminho de um arquivo JSON")
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return err
+	}
+
+	if *numRecords <= 0 {
+		return fmt.Errorf("número de registros deve ser positivo")
+	}
+	if *lookups < 0 {
+		return fmt.Errorf("número de buscas não pode ser negativo")
+	}
+
+	blockSizes, err := infrastructure.ParseBlockSizes(*blockSizesSpec)
+	if err != nil {
+		return err
+	}
+
+	format := infrastructure.ReportFormat(*formatName)
+	if !slices.Contains(infrastructure.ReportFormats, format) {
+		return fmt.Errorf("formato desconhecido: %q", *formatName)
+	}
+	path := *output
+	if path == "" {
+		path = "comparacao" + format.Extension()
+	}
+
+	textEncoding, err := storage.ParseEncoding(*encodingName)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	generator, err := newStudentGenerator(flags, *seed, *profile)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Gerando %d registros... (Ctrl+C para cancelar)\n", *numRecords)
+	students, err := generator.Generate(*numRecords)
+	if err != nil {
+		return err
+	}
+
+	results, err := infrastructure.RunBenchmark(ctx, students, infrastructure.BenchmarkOptions{
+		BlockSizes: blockSizes,
+		Lookups:    *lookups,
+		Dir:        *dir,
+		Seed:       generator.Seed(),
+		Encoding:   textEncoding,
+		OnResult: func(result infrastructure.BenchmarkResult) {
+			if result.Error != "" {
+				fmt.Printf("Modo %s, bloco de %d bytes: %s\n", result.Mode, result.BlockSize, result.Error)
+				return
+			}
+			fmt.Printf("Modo %s, bloco de %d bytes: concluído\n", result.Mode, result.BlockSize)
+		},
+	})
+	if errors.Is(err, context.Canceled) {
+		return fmt.Errorf("comparação cancelada")
+	}
+	if err != nil {
+		return err
+	}
+
+	infrastructure.NewBenchmarkReporter(results).PrintSummary()
+
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := infrastructure.NewBenchmarkReporterTo(file, results).Export(format); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	fmt.Printf("\nTabela da comparação gravada em %s\n", path)
+	return nil
+}
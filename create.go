@@ -0,0 +1,203 @@
+package main
+
+import (
+	"aeds2-tp1/domain"
+	"aeds2-tp1/entity"
+	"aeds2-tp1/storage"
+	"context"
+	"encoding/csv"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"os"
+	"slices"
+	"strings"
+)
+
+func runCreate(args []string) error {
+	flags := flag.NewFlagSet("create", flag.ContinueOnError)
+	schemaSpec := flags.String("schema", entity.StudentSchemaName, "esquema dos registros: "+strings.Join(entity.BuiltinSchemaNames(), ", ")+" ou caminho de um arquivo .json ou .sql")
+	modeName := flags.String("mode", "variavel", "modo de armazenamento: fixo, variavel ou espalhado")
+	blockSize := flags.Int("block-size", 4096, "tamanho máximo do bloco em bytes")
+	numRecords := flags.Int("records", 1000, "número de registros gerados (ignorado com -input)")
+	input := flags.String("input", "", "arquivo CSV com os registros, um campo por coluna na ordem do esquema (cabeçalho opcional)")
+	output := flags.String("output", "", "arquivo gravado (padrão <esquema>.dat)")
+	encodingName := flags.String("encoding", "utf-8", "codificação dos textos no arquivo: utf-8 ou latin1 (ISO-8859-1)")
+	seed := flags.Uint64("seed", 0, "semente do gerador de dados (sem a opção, uma semente aleatória é sorteada e exibida)")
+	show := flags.Int("show", 5, "número de registros lidos de volta e exibidos ao final")
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return err
+	}
+
+	schema, err := entity.ResolveSchema(*schemaSpec)
+	if err != nil {
+		return err
+	}
+	mode, err := storage.ParseMode(*modeName)
+	if err != nil {
+		return err
+	}
+	textEncoding, err := storage.ParseEncoding(*encodingName)
+	if err != nil {
+		return err
+	}
+	if *input == "" && *numRecords <= 0 {
+		return fmt.Errorf("número de registros deve ser positivo")
+	}
+	path := *output
+	if path == "" {
+		path = schema.Name + ".dat"
+	}
+
+	fmt.Printf("Esquema: %s\n", schema.Name)
+	for _, field := range schema.Fields {
+		fmt.Printf("  %s\n", describeField(field))
+	}
+	fmt.Printf("Modo: %s, bloco de %d bytes, codificação %s\n", mode, *blockSize, textEncoding)
+
+	var records []entity.Record
+	if *input != "" {
+		if records, err = readCSVRecords(*input, schema); err != nil {
+			return err
+		}
+		fmt.Printf("Lidos %d registros de %s\n", len(records), *input)
+	} else {
+		generatorSeed := rand.Uint64()
+		flags.Visit(func(f *flag.Flag) {
+			if f.Name == "seed" {
+				generatorSeed = *seed
+			}
+		})
+		fmt.Printf("Semente do gerador: %d (use -seed %d para repetir os mesmos dados)\n", generatorSeed, generatorSeed)
+		if records, err = domain.NewRecordGenerator(generatorSeed, schema).Generate(*numRecords); err != nil {
+			return err
+		}
+	}
+
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("não foi possível deletar o arquivo %s existente: %w", path, err)
+	}
+	handle, err := storage.Open(path, storage.Options{
+		Mode:        mode,
+		BlockSize:   *blockSize,
+		LockTimeout: lockTimeout,
+		Encoding:    textEncoding,
+		Schema:      schema,
+	})
+	if err != nil {
+		return err
+	}
+	defer handle.Close()
+
+	fmt.Printf("\nGravando registros no arquivo %s... (Ctrl+C para cancelar)\n", path)
+	err = runOperation(func(ctx context.Context) error {
+		return handle.WriteRecords(ctx, records)
+	})
+	if errors.Is(err, context.Canceled) {
+		fmt.Println("Gravação cancelada. O arquivo não foi alterado.")
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("erro ao gravar arquivo: %w", err)
+	}
+	fmt.Printf("Gravados %d registros de %s\n", len(records), schema.Name)
+	showStorageReport(handle)
+
+	if *show <= 0 {
+		return nil
+	}
+	var stored []entity.Record
+	err = runOperation(func(ctx context.Context) error {
+		stored, err = handle.GetAllRecords(ctx)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("erro ao ler registros: %w", err)
+	}
+	fmt.Printf("\n=== PRIMEIROS %d REGISTROS LIDOS DO ARQUIVO ===\n", min(*show, len(stored)))
+	for _, record := range stored[:min(*show, len(stored))] {
+		printRecord(schema, record)
+	}
+	return nil
+}
+
+func describeField(field entity.Field) string {
+	var description strings.Builder
+	description.WriteString(field.Name + " " + string(field.Type))
+	switch field.Type {
+	case entity.StringField, entity.VarStringField:
+		fmt.Fprintf(&description, "(%d)", field.Length)
+	case entity.DecimalField:
+		fmt.Fprintf(&description, "(%d, %d)", field.Precision, field.Scale)
+	}
+	if field.Key {
+		description.WriteString(" chave")
+	}
+	if field.Min != "" || field.Max != "" {
+		low, high := field.Limits()
+		fmt.Fprintf(&description, " entre %s e %s", field.Format(low), field.Format(high))
+	}
+	if field.Check != "" {
+		description.WriteString(" verificação " + field.Check)
+	}
+	return description.String()
+}
+
+func readCSVRecords(path string, schema *entity.Schema) ([]entity.Record, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao abrir CSV: %w", err)
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = len(schema.Fields)
+	names := make([]string, len(schema.Fields))
+	for i, field := range schema.Fields {
+		names[i] = strings.ToLower(field.Name)
+	}
+
+	records := make([]entity.Record, 0)
+	for line := 1; ; line++ {
+		values, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return records, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("CSV inválido: %w", err)
+		}
+		if line == 1 && slices.Equal(lowerAll(values), names) {
+			continue
+		}
+		record, err := schema.ParseRecord(values)
+		if err != nil {
+			return nil, fmt.Errorf("CSV linha %d: %w", line, err)
+		}
+		records = append(records, record)
+	}
+}
+
+func lowerAll(values []string) []string {
+	lowered := make([]string, len(values))
+	for i, value := range values {
+		lowered[i] = strings.ToLower(strings.TrimSpace(value))
+	}
+	return lowered
+}
+
+func printRecord(schema *entity.Schema, record entity.Record) {
+	fmt.Println()
+	width := 0
+	for _, field := range schema.Fields {
+		width = max(width, entity.TextLength(field.Name))
+	}
+	for i, value := range schema.FormatRecord(record) {
+		name := schema.Fields[i].Name
+		fmt.Printf("%s:%s %s\n", name, strings.Repeat(" ", width-entity.TextLength(name)), value)
+	}
+}
@@ -0,0 +1,169 @@
+package domain
+
+import (
+	"aeds2-tp1/entity"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+type EdgeCase string
+
+const (
+	MaxLengthEdgeCase     EdgeCase = "campos-maximos"
+	MinLengthEdgeCase     EdgeCase = "campos-minimos"
+	BlockBoundaryEdgeCase EdgeCase = "limite-de-bloco"
+	CAExtremesEdgeCase    EdgeCase = "ca-extremos"
+	SpecialCharsEdgeCase  EdgeCase = "caracteres-especiais"
+)
+
+var EdgeCases = []EdgeCase{
+	MaxLengthEdgeCase,
+	MinLengthEdgeCase,
+	BlockBoundaryEdgeCase,
+	CAExtremesEdgeCase,
+	SpecialCharsEdgeCase,
+}
+
+type EdgeCaseOptions struct {
+	BlockSize  int
+	RecordSize func(entity.Student) int
+}
+
+var specialTexts = []string{
+	"#",
+	"#João",
+	"Ana#Maria",
+	"Conceição#",
+	"###",
+	"Zoë Müller-Łukasz",
+	"Ñandú Çedilha ß",
+	"李小龍",
+	"🎓 Formando 🎓",
+	"Ação#Ção#",
+}
+
+var lengthFiller = []byte("abcdefghijklmnopqrstuvwxyz")
+
+func (sg *StudentGenerator) GenerateEdgeCase(edgeCase EdgeCase, count int, options EdgeCaseOptions) ([]entity.Student, error) {
+	students := make([]entity.Student, 0, count)
+	for i := range count {
+		if sg.matricula > entity.MaxMatricula {
+			return nil, fmt.Errorf("matrículas esgotadas após %d", entity.MaxMatricula)
+		}
+		student := sg.baseStudent(sg.matricula)
+		sg.matricula++
+		switch edgeCase {
+		case MaxLengthEdgeCase:
+			student.Nome = sg.textOfLength(entity.MaxNomeLength)
+			student.Curso = sg.textOfLength(entity.MaxCursoLength)
+			student.FiliacaoMae = sg.textOfLength(entity.MaxFiliacaoLength)
+			student.FiliacaoPai = sg.textOfLength(entity.MaxFiliacaoLength)
+			student.AnoIngresso = entity.AnoIngressoMax
+			student.CA = entity.CA_MAX
+		case MinLengthEdgeCase:
+			student.Nome = sg.textOfLength(1)
+			student.Curso = sg.textOfLength(1)
+			student.FiliacaoMae = sg.textOfLength(1)
+			student.FiliacaoPai = sg.textOfLength(1)
+			student.AnoIngresso = entity.AnoIngressoMin
+			student.CA = entity.CA_MIN
+		case BlockBoundaryEdgeCase:
+			if err := sg.fillToBlockBoundary(&student, i, options); err != nil {
+				return nil, err
+			}
+		case CAExtremesEdgeCase:
+			switch i % 3 {
+			case 0:
+				student.CA = entity.CA_MIN
+			case 1:
+				student.CA = entity.CA_MAX
+			default:
+				student.CA = float64(i/3%1001) / 100
+			}
+		case SpecialCharsEdgeCase:
+			student.Nome = specialTexts[i%len(specialTexts)]
+			student.Curso = specialTexts[(i+3)%len(specialTexts)]
+			student.FiliacaoMae = specialTexts[(i+5)%len(specialTexts)]
+			student.FiliacaoPai = padToLength(specialTexts[(i+7)%len(specialTexts)], entity.MaxFiliacaoLength, '#')
+		default:
+			return nil, fmt.Errorf("caso de borda desconhecido: %q", edgeCase)
+		}
+
+		if err := student.Validate(); err != nil {
+			return nil, fmt.Errorf("registro %d do caso %s inválido: %w", i+1, edgeCase, err)
+		}
+		students = append(students, student)
+	}
+	return students, nil
+}
+
+func (sg *StudentGenerator) baseStudent(matricula int64) entity.Student {
+	course := sg.pickCourse()
+	ca := sg.sample(course.CA)
+	return entity.Student{
+		Matricula:   matricula,
+		Nome:        sg.pickName(sg.profile.Names, entity.MaxNomeLength),
+		CPF:         sg.generateCPF(),
+		Curso:       course.Name,
+		FiliacaoMae: sg.pickName(sg.profile.Mothers, entity.MaxFiliacaoLength),
+		FiliacaoPai: sg.pickName(sg.profile.Fathers, entity.MaxFiliacaoLength),
+		AnoIngresso: sg.sampleInt(sg.profile.AnoIngresso),
+		CA:          float64(int(ca*100)) / 100,
+	}
+}
+
+func (sg *StudentGenerator) textOfLength(length int) string {
+	text := make([]byte, length)
+	for i := range text {
+		text[i] = lengthFiller[sg.intN(len(lengthFiller))]
+	}
+	text[0] -= 'a' - 'A'
+	return string(text)
+}
+
+func padToLength(text string, length int, pad byte) string {
+	if len(text) >= length {
+		return text
+	}
+	return text + strings.Repeat(string(pad), length-len(text))
+}
+
+func (sg *StudentGenerator) fillToBlockBoundary(student *entity.Student, index int, options EdgeCaseOptions) error {
+	if options.RecordSize == nil || options.BlockSize <= 0 {
+		return errors.New("o caso limite-de-bloco exige o tamanho do bloco e a função de tamanho do registro")
+	}
+
+	student.Nome, student.Curso, student.FiliacaoMae, student.FiliacaoPai = "A", "A", "A", "A"
+	overhead := options.RecordSize(*student) - 4
+	limits := []int{entity.MaxNomeLength, entity.MaxCursoLength, entity.MaxFiliacaoLength, entity.MaxFiliacaoLength}
+	largest := overhead
+	for _, limit := range limits {
+		largest += limit
+	}
+
+	recordsPerBlock := (options.BlockSize + largest - 1) / largest
+	if (overhead+len(limits))*recordsPerBlock > options.BlockSize {
+		return fmt.Errorf("não há combinação de registros que preencha exatamente um bloco de %d bytes", options.BlockSize)
+	}
+
+	size := options.BlockSize / recordsPerBlock
+	if index%recordsPerBlock < options.BlockSize%recordsPerBlock {
+		size++
+	}
+
+	payload := size - overhead
+	fields := make([]string, len(limits))
+	for i, limit := range limits {
+		remaining := len(limits) - i - 1
+		length := min(limit, payload-remaining)
+		fields[i] = sg.textOfLength(length)
+		payload -= length
+	}
+	student.Nome, student.Curso, student.FiliacaoMae, student.FiliacaoPai = fields[0], fields[1], fields[2], fields[3]
+
+	if got := options.RecordSize(*student); got != size {
+		return fmt.Errorf("registro de %d bytes gerado com %d bytes", size, got)
+	}
+	return nil
+}
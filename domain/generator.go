@@ -3,81 +3,166 @@ package domain
 import (
 	"aeds2-tp1/entity"
 	"fmt"
-	"math/rand"
-	"time"
+	"iter"
+	"math/bits"
+	"math/rand/v2"
+	"strings"
+)
+
+const (
+	FirstMatricula  = 100000001
+	generatorStream = 0x9e3779b97f4a7c15
+	cpfBases        = 1_000_000_000
+
+	maxGenerationAttempts = 100
 )
 
 type StudentGenerator struct {
-	random *rand.Rand
+	seed      uint64
+	profile   *GeneratorProfile
+	matricula int64
+	random    *rand.PCG
+	cpfIndex  uint64
+	cpfStep   uint64
+	cpfOffset uint64
 }
 
 func NewStudentGenerator() *StudentGenerator {
-	return &StudentGenerator{
-		random: rand.New(rand.NewSource(time.Now().UnixNano())),
+	return NewStudentGeneratorWithSeed(rand.Uint64())
+}
+
+func NewStudentGeneratorWithSeed(seed uint64) *StudentGenerator {
+	profile, err := BuiltinProfile(DefaultProfileName)
+	if err != nil {
+		panic(err)
 	}
+	return NewStudentGeneratorWithProfile(seed, profile)
 }
 
-func (sg *StudentGenerator) Generate(count int) []entity.Student {
-	students := make([]entity.Student, 0, count)
-	
-	nomes := []string{
-		"João Silva", "Maria Santos", "Pedro Oliveira", "Ana Costa", "Carlos Ferreira",
-		"Juliana Almeida", "Roberto Souza", "Fernanda Lima", "Lucas Rodrigues", "Patricia Gomes",
-		"Marcos Pereira", "Camila Martins", "Rafael Barbosa", "Bruna Rocha", "Thiago Dias",
-		"Larissa Araujo", "Felipe Ribeiro", "Gabriela Nascimento", "Gustavo Moura", "Isabela Teixeira",
+func NewStudentGeneratorWithProfile(seed uint64, profile *GeneratorProfile) *StudentGenerator {
+	cpfRandom := rand.New(rand.NewPCG(seed, ^uint64(generatorStream)))
+	cpfStep := cpfRandom.Uint64N(cpfBases/10)*10 + []uint64{1, 3, 7, 9}[cpfRandom.IntN(4)]
+	return &StudentGenerator{
+		seed:      seed,
+		profile:   profile,
+		matricula: FirstMatricula,
+		random:    rand.NewPCG(seed, generatorStream),
+		cpfStep:   cpfStep,
+		cpfOffset: cpfRandom.Uint64N(cpfBases),
 	}
-	
-	cursos := []string{
-		"Ciência da Computação", "Engenharia de Software", "Sistemas de Informação",
-		"Engenharia Civil", "Administração", "Direito", "Medicina", "Psicologia",
-		"Economia", "Arquitetura",
+}
+
+func (sg *StudentGenerator) Seed() uint64 {
+	return sg.seed
+}
+
+func (sg *StudentGenerator) Profile() *GeneratorProfile {
+	return sg.profile
+}
+
+func (sg *StudentGenerator) StartAt(matricula int64) error {
+	if matricula < 1 || matricula > entity.MaxMatricula {
+		return fmt.Errorf("matrícula inicial deve estar entre 1 e %d", entity.MaxMatricula)
 	}
-	
-	nomesMae := []string{
-		"Maria Silva", "Ana Santos", "Carmen Oliveira", "Lucia Costa", "Rosa Ferreira",
-		"Tereza Almeida", "Julia Souza", "Helena Lima", "Cecilia Rodrigues", "Marta Gomes",
+	sg.matricula = matricula
+	return nil
+}
+
+func (sg *StudentGenerator) NextMatricula() int64 {
+	return sg.matricula
+}
+
+func (sg *StudentGenerator) intN(n int) int {
+	bound := uint64(n)
+	high, low := bits.Mul64(sg.random.Uint64(), bound)
+	if low < bound {
+		threshold := -bound % bound
+		for low < threshold {
+			high, low = bits.Mul64(sg.random.Uint64(), bound)
+		}
 	}
-	
-	nomesPai := []string{
-		"José Silva", "Antonio Santos", "Paulo Oliveira", "Carlos Costa", "Francisco Ferreira",
-		"Roberto Almeida", "João Souza", "Marcos Lima", "Ricardo Rodrigues", "Eduardo Gomes",
+	return int(high)
+}
+
+func (sg *StudentGenerator) float64() float64 {
+	return float64(sg.random.Uint64()>>11) / (1 << 53)
+}
+
+func (sg *StudentGenerator) Generate(count int) ([]entity.Student, error) {
+	students := make([]entity.Student, 0, count)
+	for student, err := range sg.Stream(count) {
+		if err != nil {
+			return nil, err
+		}
+		students = append(students, student)
 	}
-	
-	generated := 0
-	for generated < count {
-		matricula := 100000000 + generated + 1
-		cpf := sg.generateCPF()
-		anoIngresso := 2015 + sg.random.Intn(10)
-		ca := 5.0 + sg.random.Float64()*5.0
-		ca = float64(int(ca*100)) / 100
-		
-		student := entity.Student{
-			Matricula:   matricula,
-			Nome:        nomes[sg.random.Intn(len(nomes))],
-			CPF:         cpf,
-			Curso:       cursos[sg.random.Intn(len(cursos))],
-			FiliacaoMae: nomesMae[sg.random.Intn(len(nomesMae))],
-			FiliacaoPai: nomesPai[sg.random.Intn(len(nomesPai))],
-			AnoIngresso: anoIngresso,
-			CA:          ca,
+	return students, nil
+}
+
+func (sg *StudentGenerator) Stream(count int) iter.Seq2[entity.Student, error] {
+	return func(yield func(entity.Student, error) bool) {
+		for range count {
+			student, err := sg.next()
+			if err != nil {
+				yield(entity.Student{}, err)
+				return
+			}
+			if !yield(student, nil) {
+				return
+			}
 		}
-		
-		student.TruncateFields()
-		if err := student.Validate(); err != nil {
-			fmt.Printf("Aviso: estudante gerado inválido: %v\n", err)
-			continue
+	}
+}
+
+func (sg *StudentGenerator) next() (entity.Student, error) {
+	if sg.matricula > entity.MaxMatricula {
+		return entity.Student{}, fmt.Errorf("matrículas esgotadas após %d", entity.MaxMatricula)
+	}
+
+	var err error
+	for range maxGenerationAttempts {
+		var student entity.Student
+		if student, err = sg.generateStudent(sg.matricula); err == nil {
+			sg.matricula++
+			return student, nil
 		}
-		students = append(students, student)
-		generated++
 	}
-	
-	return students
+	return entity.Student{}, fmt.Errorf("nenhum estudante válido gerado em %d tentativas para a matrícula %d: %w", maxGenerationAttempts, sg.matricula, err)
+}
+
+func (sg *StudentGenerator) generateStudent(matricula int64) (entity.Student, error) {
+	cpf := sg.generateCPF()
+	course := sg.pickCourse()
+	anoIngresso := sg.sampleInt(sg.profile.AnoIngresso)
+	ca := sg.sample(course.CA)
+	ca = float64(int(ca*100)) / 100
+
+	student := entity.Student{
+		Matricula:   matricula,
+		Nome:        sg.pickName(sg.profile.Names, entity.MaxNomeLength),
+		CPF:         cpf,
+		Curso:       course.Name,
+		FiliacaoMae: sg.pickName(sg.profile.Mothers, entity.MaxFiliacaoLength),
+		FiliacaoPai: sg.pickName(sg.profile.Fathers, entity.MaxFiliacaoLength),
+		AnoIngresso: anoIngresso,
+		CA:          ca,
+	}
+
+	student.TruncateFields()
+	if err := student.Validate(); err != nil {
+		return entity.Student{}, err
+	}
+	return student, nil
 }
 
 func (sg *StudentGenerator) generateCPF() string {
-	cpf := ""
-	for i := 0; i < 11; i++ {
-		cpf += fmt.Sprintf("%d", sg.random.Intn(10))
+	for {
+		base := fmt.Sprintf("%09d", (sg.cpfIndex*sg.cpfStep+sg.cpfOffset)%cpfBases)
+		sg.cpfIndex++
+		if strings.Count(base, base[:1]) == len(base) {
+			continue
+		}
+		first, second := entity.CPFCheckDigits(base)
+		return base + string([]byte{first, second})
 	}
-	return cpf
 }
@@ -0,0 +1,105 @@
+package domain
+
+import (
+	"aeds2-tp1/entity"
+	"strings"
+	"testing"
+)
+
+func TestGenerateStopsOnInvalidProfile(t *testing.T) {
+	profile, err := BuiltinProfile(DefaultProfileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	profile.Courses = []CourseProfile{{Name: "", Weight: 1, CA: profile.Courses[0].CA}}
+
+	generator := NewStudentGeneratorWithProfile(1, profile)
+	students, err := generator.Generate(10)
+	if err == nil || !strings.Contains(err.Error(), "tentativas") {
+		t.Fatalf("Generate com perfil inválido: %d alunos, erro %v", len(students), err)
+	}
+	if generator.NextMatricula() != FirstMatricula {
+		t.Fatalf("matrícula avançou para %d sem gerar alunos", generator.NextMatricula())
+	}
+}
+
+func TestStreamReportsExhaustedMatriculas(t *testing.T) {
+	generator := NewStudentGeneratorWithSeed(2)
+	if err := generator.StartAt(entity.MaxMatricula); err != nil {
+		t.Fatal(err)
+	}
+
+	count := 0
+	var streamErr error
+	for _, err := range generator.Stream(3) {
+		if err != nil {
+			streamErr = err
+			continue
+		}
+		count++
+	}
+	if count != 1 || streamErr == nil {
+		t.Fatalf("Stream gerou %d alunos além da última matrícula, erro %v", count, streamErr)
+	}
+
+	if _, err := generator.Generate(1); err == nil {
+		t.Fatal("Generate após esgotar as matrículas não retornou erro")
+	}
+}
+
+func TestGenerateIsDeterministic(t *testing.T) {
+	first, err := NewStudentGeneratorWithSeed(3).Generate(50)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := NewStudentGeneratorWithSeed(3).Generate(50)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range first {
+		if first[i] != second[i] {
+			t.Fatalf("aluno %d difere entre gerações com a mesma semente", i)
+		}
+	}
+}
+
+func TestRecordGeneratorReportsExhaustedKeys(t *testing.T) {
+	schema, err := entity.ParseDDL("CREATE TABLE itens (codigo INT PRIMARY KEY CHECK (codigo BETWEEN 1 AND 5), nome VARCHAR(10))")
+	if err != nil {
+		t.Fatal(err)
+	}
+	records, err := NewRecordGenerator(4, schema).Generate(8)
+	if err == nil || !strings.Contains(err.Error(), "esgotadas") {
+		t.Fatalf("Generate além das chaves disponíveis: %d registros, erro %v", len(records), err)
+	}
+}
+
+func TestGeneratedCPFsAreValidAndUnique(t *testing.T) {
+	const count = 100000
+	generator := NewStudentGeneratorWithSeed(5)
+	seen := make(map[string]int, count)
+	for i := range count {
+		cpf := generator.generateCPF()
+		if err := entity.ValidateCPF(cpf); err != nil {
+			t.Fatalf("CPF %d gerado inválido %q: %v", i, cpf, err)
+		}
+		if previous, ok := seen[cpf]; ok {
+			t.Fatalf("CPF %s gerado nas posições %d e %d", cpf, previous, i)
+		}
+		seen[cpf] = i
+	}
+}
+
+func TestGeneratedStudentsHaveUniqueCPFs(t *testing.T) {
+	students, err := NewStudentGeneratorWithSeed(6).Generate(2000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	seen := make(map[string]int64, len(students))
+	for _, student := range students {
+		if matricula, ok := seen[student.CPF]; ok {
+			t.Fatalf("CPF %s repetido nas matrículas %d e %d", student.CPF, matricula, student.Matricula)
+		}
+		seen[student.CPF] = student.Matricula
+	}
+}
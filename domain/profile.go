@@ -0,0 +1,247 @@
+package domain
+
+import (
+	"aeds2-tp1/entity"
+	"bytes"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+const DefaultProfileName = "padrao"
+
+//go:embed profiles/*.json
+var builtinProfiles embed.FS
+
+type DistributionType string
+
+const (
+	UniformDistribution DistributionType = "uniforme"
+	NormalDistribution  DistributionType = "normal"
+)
+
+type Distribution struct {
+	Type   DistributionType `json:"tipo"`
+	Min    float64          `json:"min"`
+	Max    float64          `json:"max"`
+	Mean   float64          `json:"media,omitempty"`
+	StdDev float64          `json:"desvio,omitempty"`
+}
+
+type NamePool struct {
+	FirstNames  []string `json:"prenomes"`
+	LastNames   []string `json:"sobrenomes,omitempty"`
+	MinLastName int      `json:"minSobrenomes,omitempty"`
+	MaxLastName int      `json:"maxSobrenomes,omitempty"`
+	Fill        bool     `json:"preencher,omitempty"`
+}
+
+type CourseProfile struct {
+	Name   string       `json:"nome"`
+	Weight float64      `json:"peso"`
+	CA     Distribution `json:"ca"`
+}
+
+type GeneratorProfile struct {
+	Name        string          `json:"nome"`
+	Description string          `json:"descricao,omitempty"`
+	Names       NamePool        `json:"nomes"`
+	Mothers     NamePool        `json:"maes"`
+	Fathers     NamePool        `json:"pais"`
+	Courses     []CourseProfile `json:"cursos"`
+	AnoIngresso Distribution    `json:"anoIngresso"`
+}
+
+func BuiltinProfileNames() []string {
+	entries, err := builtinProfiles.ReadDir("profiles")
+	if err != nil {
+		return nil
+	}
+
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		names = append(names, strings.TrimSuffix(entry.Name(), ".json"))
+	}
+	return names
+}
+
+func BuiltinProfile(name string) (*GeneratorProfile, error) {
+	data, err := builtinProfiles.ReadFile(path.Join("profiles", name+".json"))
+	if err != nil {
+		return nil, fmt.Errorf("perfil %q não existe (disponíveis: %s)", name, strings.Join(BuiltinProfileNames(), ", "))
+	}
+	return parseProfile(data)
+}
+
+func LoadProfile(filename string) (*GeneratorProfile, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao ler perfil: %w", err)
+	}
+	return parseProfile(data)
+}
+
+func ResolveProfile(spec string) (*GeneratorProfile, error) {
+	if spec == "" {
+		spec = DefaultProfileName
+	}
+	if slices.Contains(BuiltinProfileNames(), spec) || filepath.Ext(spec) == "" {
+		return BuiltinProfile(spec)
+	}
+	return LoadProfile(spec)
+}
+
+func parseProfile(data []byte) (*GeneratorProfile, error) {
+	var profile GeneratorProfile
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&profile); err != nil {
+		return nil, fmt.Errorf("perfil inválido: %w", err)
+	}
+	if err := profile.Validate(); err != nil {
+		return nil, err
+	}
+	return &profile, nil
+}
+
+func (p *GeneratorProfile) Validate() error {
+	if err := p.Names.validate("nomes", entity.MaxNomeLength); err != nil {
+		return err
+	}
+	if err := p.Mothers.validate("maes", entity.MaxFiliacaoLength); err != nil {
+		return err
+	}
+	if err := p.Fathers.validate("pais", entity.MaxFiliacaoLength); err != nil {
+		return err
+	}
+
+	if len(p.Courses) == 0 {
+		return errors.New("perfil inválido: nenhum curso informado")
+	}
+	var totalWeight float64
+	for _, course := range p.Courses {
+		if course.Name == "" || entity.TextLength(course.Name) > entity.MaxCursoLength {
+			return fmt.Errorf("perfil inválido: curso %q deve ter entre 1 e %d caracteres", course.Name, entity.MaxCursoLength)
+		}
+		if course.Weight < 0 {
+			return fmt.Errorf("perfil inválido: curso %q com peso negativo", course.Name)
+		}
+		if err := course.CA.validate("CA de "+course.Name, entity.CA_MIN, entity.CA_MAX); err != nil {
+			return err
+		}
+		totalWeight += course.Weight
+	}
+	if totalWeight <= 0 {
+		return errors.New("perfil inválido: a soma dos pesos dos cursos deve ser positiva")
+	}
+
+	if err := p.AnoIngresso.validate("anoIngresso", entity.AnoIngressoMin, entity.AnoIngressoMax); err != nil {
+		return err
+	}
+	if math.Ceil(p.AnoIngresso.Min) > math.Floor(p.AnoIngresso.Max) {
+		return errors.New("perfil inválido: anoIngresso não contém nenhum ano inteiro")
+	}
+	return nil
+}
+
+func (pool NamePool) validate(field string, maxLength int) error {
+	if len(pool.FirstNames) == 0 {
+		return fmt.Errorf("perfil inválido: %s sem prenomes", field)
+	}
+	if pool.MinLastName < 0 || pool.MaxLastName < pool.MinLastName {
+		return fmt.Errorf("perfil inválido: %s com quantidade de sobrenomes entre %d e %d", field, pool.MinLastName, pool.MaxLastName)
+	}
+	if (pool.MaxLastName > 0 || pool.Fill) && len(pool.LastNames) == 0 {
+		return fmt.Errorf("perfil inválido: %s sem sobrenomes", field)
+	}
+
+	for _, name := range slices.Concat(pool.FirstNames, pool.LastNames) {
+		if strings.TrimSpace(name) == "" || entity.TextLength(name) > maxLength {
+			return fmt.Errorf("perfil inválido: %s contém %q, que deve ter entre 1 e %d caracteres", field, name, maxLength)
+		}
+	}
+	return nil
+}
+
+func (d Distribution) validate(field string, lowest, highest float64) error {
+	if d.Min < lowest || d.Max > highest || d.Min > d.Max {
+		return fmt.Errorf("perfil inválido: %s deve ter min <= max dentro de [%g, %g]", field, lowest, highest)
+	}
+
+	switch d.Type {
+	case UniformDistribution:
+	case NormalDistribution:
+		if d.StdDev < 0 {
+			return fmt.Errorf("perfil inválido: %s com desvio padrão negativo", field)
+		}
+		if d.Mean < d.Min || d.Mean > d.Max {
+			return fmt.Errorf("perfil inválido: %s com média %g fora de [%g, %g]", field, d.Mean, d.Min, d.Max)
+		}
+	default:
+		return fmt.Errorf("perfil inválido: %s com distribuição desconhecida %q (use %q ou %q)", field, d.Type, UniformDistribution, NormalDistribution)
+	}
+	return nil
+}
+
+func (sg *StudentGenerator) sample(d Distribution) float64 {
+	if d.Type == NormalDistribution {
+		u1 := 1 - sg.float64()
+		u2 := sg.float64()
+		z := math.Sqrt(-2*math.Log(u1)) * math.Cos(2*math.Pi*u2)
+		return min(max(d.Mean+float64(d.StdDev*z), d.Min), d.Max)
+	}
+	return d.Min + float64(sg.float64()*(d.Max-d.Min))
+}
+
+func (sg *StudentGenerator) sampleInt(d Distribution) int {
+	if d.Type == NormalDistribution {
+		return int(math.Round(sg.sample(d)))
+	}
+	low, high := int(math.Ceil(d.Min)), int(math.Floor(d.Max))
+	return low + sg.intN(high-low+1)
+}
+
+func (sg *StudentGenerator) pickName(pool NamePool, maxLength int) string {
+	name := pool.FirstNames[sg.intN(len(pool.FirstNames))]
+	used := make(map[string]bool)
+	count := pool.MinLastName + sg.intN(pool.MaxLastName-pool.MinLastName+1)
+	for i := 0; i < count || pool.Fill; i++ {
+		candidates := make([]string, 0, len(pool.LastNames))
+		for _, lastName := range pool.LastNames {
+			if !used[lastName] && entity.TextLength(name)+1+entity.TextLength(lastName) <= maxLength {
+				candidates = append(candidates, lastName)
+			}
+		}
+		if len(candidates) == 0 {
+			break
+		}
+
+		lastName := candidates[sg.intN(len(candidates))]
+		used[lastName] = true
+		name += " " + lastName
+	}
+	return name
+}
+
+func (sg *StudentGenerator) pickCourse() CourseProfile {
+	var totalWeight float64
+	for _, course := range sg.profile.Courses {
+		totalWeight += course.Weight
+	}
+
+	target := sg.float64() * totalWeight
+	for _, course := range sg.profile.Courses {
+		if target < course.Weight {
+			return course
+		}
+		target -= course.Weight
+	}
+	return sg.profile.Courses[len(sg.profile.Courses)-1]
+}
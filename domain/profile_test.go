@@ -0,0 +1,124 @@
+package domain
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const validProfile = `{
+  "nome": "teste",
+  "nomes": {"prenomes": ["Ana", "Bruno"], "sobrenomes": ["Silva", "Costa"], "minSobrenomes": 1, "maxSobrenomes": 2},
+  "maes": {"prenomes": ["Maria Silva"]},
+  "pais": {"prenomes": ["José Silva"]},
+  "cursos": [
+    {"nome": "Direito", "peso": 2, "ca": {"tipo": "normal", "min": 0, "max": 10, "media": 7, "desvio": 1.5}},
+    {"nome": "Medicina", "peso": 1, "ca": {"tipo": "uniforme", "min": 5, "max": 10}}
+  ],
+  "anoIngresso": {"tipo": "uniforme", "min": 2015, "max": 2024}
+}`
+
+func writeProfile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "perfil.json")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadProfile(t *testing.T) {
+	profile, err := LoadProfile(writeProfile(t, validProfile))
+	if err != nil {
+		t.Fatalf("LoadProfile: %v", err)
+	}
+	if profile.Name != "teste" || len(profile.Courses) != 2 || profile.Courses[0].CA.Type != NormalDistribution || profile.Courses[0].CA.StdDev != 1.5 {
+		t.Fatalf("perfil carregado incorretamente: %+v", profile)
+	}
+	if _, err := NewStudentGeneratorWithProfile(1, profile).Generate(100); err != nil {
+		t.Fatalf("Generate com o perfil carregado: %v", err)
+	}
+}
+
+func TestLoadProfileRejectsInvalidProfiles(t *testing.T) {
+	cases := []struct {
+		name         string
+		replacements []string
+		wantErr      string
+	}{
+		{"campo-desconhecido", []string{`"nome": "teste",`, `"nome": "teste", "idade": 3,`}, `unknown field "idade"`},
+		{"campo-desconhecido-aninhado", []string{`"peso": 1,`, `"peso": 1, "vagas": 40,`}, `unknown field "vagas"`},
+		{"json-malformado", []string{`"cursos": [`, `"cursos": [[`}, "perfil inválido"},
+		{"distribuicao-desconhecida", []string{`"tipo": "uniforme", "min": 5`, `"tipo": "exponencial", "min": 5`}, `distribuição desconhecida "exponencial"`},
+		{"distribuicao-sem-tipo", []string{`"tipo": "uniforme", "min": 2015`, `"min": 2015`}, `anoIngresso com distribuição desconhecida ""`},
+		{"desvio-negativo", []string{`"desvio": 1.5`, `"desvio": -1.5`}, "CA de Direito com desvio padrão negativo"},
+		{"media-fora-dos-limites", []string{`"media": 7`, `"media": 11`}, "CA de Direito com média 11 fora de [0, 10]"},
+		{"ca-acima-do-maximo", []string{`"min": 5, "max": 10}`, `"min": 5, "max": 12}`}, "CA de Medicina deve ter min <= max dentro de [0, 10]"},
+		{"min-maior-que-max", []string{`"min": 2015, "max": 2024`, `"min": 2024, "max": 2015`}, "anoIngresso deve ter min <= max"},
+		{"ano-fora-da-faixa", []string{`"min": 2015, "max": 2024`, `"min": 999, "max": 2024`}, "anoIngresso deve ter min <= max dentro de [1000, 9999]"},
+		{"ano-sem-inteiro", []string{`"min": 2015, "max": 2024`, `"min": 2015.2, "max": 2015.8`}, "anoIngresso não contém nenhum ano inteiro"},
+		{"peso-negativo", []string{`"peso": 1,`, `"peso": -1,`}, `curso "Medicina" com peso negativo`},
+		{"pesos-zerados", []string{`"peso": 2,`, `"peso": 0,`, `"peso": 1,`, `"peso": 0,`}, "a soma dos pesos dos cursos deve ser positiva"},
+		{"curso-longo", []string{`"nome": "Medicina"`, `"nome": "` + strings.Repeat("M", 31) + `"`}, "deve ter entre 1 e 30 caracteres"},
+		{"sem-prenomes", []string{`"prenomes": ["Maria Silva"]`, `"prenomes": []`}, "maes sem prenomes"},
+		{"nome-vazio", []string{`["Ana", "Bruno"]`, `["Ana", " "]`}, `nomes contém " "`},
+		{"sobrenomes-invertidos", []string{`"minSobrenomes": 1, "maxSobrenomes": 2`, `"minSobrenomes": 3, "maxSobrenomes": 2`}, "nomes com quantidade de sobrenomes entre 3 e 2"},
+		{"sem-sobrenomes", []string{`"sobrenomes": ["Silva", "Costa"], `, ``}, "nomes sem sobrenomes"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			content := validProfile
+			for i := 0; i < len(tc.replacements); i += 2 {
+				if !strings.Contains(content, tc.replacements[i]) {
+					t.Fatalf("trecho %q não encontrado no perfil base", tc.replacements[i])
+				}
+				content = strings.Replace(content, tc.replacements[i], tc.replacements[i+1], 1)
+			}
+			profile, err := LoadProfile(writeProfile(t, content))
+			if err == nil {
+				t.Fatalf("LoadProfile aceitou o perfil inválido: %+v", profile)
+			}
+			if !strings.Contains(err.Error(), tc.wantErr) {
+				t.Fatalf("erro %q, esperado contendo %q", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateRequiresCourses(t *testing.T) {
+	profile, err := LoadProfile(writeProfile(t, validProfile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	profile.Courses = nil
+	if err := profile.Validate(); err == nil || !strings.Contains(err.Error(), "nenhum curso informado") {
+		t.Fatalf("Validate sem cursos: %v", err)
+	}
+}
+
+func TestLoadProfileMissingFile(t *testing.T) {
+	if _, err := LoadProfile(filepath.Join(t.TempDir(), "ausente.json")); err == nil || !strings.Contains(err.Error(), "erro ao ler perfil") {
+		t.Fatalf("LoadProfile de arquivo ausente: %v", err)
+	}
+}
+
+func TestBuiltinProfilesAreValid(t *testing.T) {
+	names := BuiltinProfileNames()
+	if len(names) == 0 {
+		t.Fatal("nenhum perfil embutido")
+	}
+	for _, name := range names {
+		profile, err := ResolveProfile(name)
+		if err != nil {
+			t.Errorf("perfil embutido %s: %v", name, err)
+			continue
+		}
+		if _, err := NewStudentGeneratorWithProfile(1, profile).Generate(200); err != nil {
+			t.Errorf("Generate com o perfil %s: %v", name, err)
+		}
+	}
+	if _, err := ResolveProfile("inexistente"); err == nil {
+		t.Error("ResolveProfile aceitou um perfil embutido inexistente")
+	}
+}
@@ -0,0 +1,195 @@
+package domain
+
+import (
+	"aeds2-tp1/entity"
+	"fmt"
+	"iter"
+	"math"
+	"math/rand/v2"
+	"strings"
+	"time"
+)
+
+const (
+	recordLetters        = "abcdefghijlmnopqrstuvxz"
+	recordCodeCharacters = "ABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+	decimalGeneratorSpan = 1e9
+)
+
+type RecordGenerator struct {
+	seed   uint64
+	schema *entity.Schema
+	key    int64
+	random *rand.Rand
+}
+
+func NewRecordGenerator(seed uint64, schema *entity.Schema) *RecordGenerator {
+	low, _ := schema.KeyField().Range()
+	return &RecordGenerator{
+		seed:   seed,
+		schema: schema,
+		key:    max(1, int64(low)),
+		random: rand.New(rand.NewPCG(seed, generatorStream)),
+	}
+}
+
+func (rg *RecordGenerator) Seed() uint64 {
+	return rg.seed
+}
+
+func (rg *RecordGenerator) Schema() *entity.Schema {
+	return rg.schema
+}
+
+func (rg *RecordGenerator) StartAt(key int64) error {
+	field := rg.schema.KeyField()
+	low, high := field.Range()
+	if float64(key) < low || float64(key) > high {
+		lowest, highest := field.Limits()
+		return fmt.Errorf("chave inicial deve estar entre %s e %s", field.Format(lowest), field.Format(highest))
+	}
+	rg.key = key
+	return nil
+}
+
+func (rg *RecordGenerator) NextKey() int64 {
+	return rg.key
+}
+
+func (rg *RecordGenerator) Generate(count int) ([]entity.Record, error) {
+	records := make([]entity.Record, 0, count)
+	for record, err := range rg.Stream(count) {
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+func (rg *RecordGenerator) Stream(count int) iter.Seq2[entity.Record, error] {
+	return func(yield func(entity.Record, error) bool) {
+		for range count {
+			record, err := rg.next()
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+			if !yield(record, nil) {
+				return
+			}
+		}
+	}
+}
+
+func (rg *RecordGenerator) next() (entity.Record, error) {
+	keyField := rg.schema.KeyField()
+	if _, highestKey := keyField.Range(); float64(rg.key) > highestKey {
+		_, lastKey := keyField.Limits()
+		return nil, fmt.Errorf("chaves de %s esgotadas após %s", keyField.Name, keyField.Format(lastKey))
+	}
+
+	var err error
+	for range maxGenerationAttempts {
+		record := rg.generateRecord(rg.key)
+		if err = rg.schema.ValidateRecord(record); err == nil {
+			rg.key++
+			return record, nil
+		}
+	}
+	return nil, fmt.Errorf("nenhum registro válido gerado em %d tentativas para %s %d: %w", maxGenerationAttempts, keyField.Name, rg.key, err)
+}
+
+func (rg *RecordGenerator) generateRecord(key int64) entity.Record {
+	record := make(entity.Record, len(rg.schema.Fields))
+	for i, field := range rg.schema.Fields {
+		if i == rg.schema.KeyIndex() {
+			record[i] = key
+			continue
+		}
+		record[i] = rg.generateValue(field)
+	}
+	return record
+}
+
+func (rg *RecordGenerator) generateValue(field entity.Field) any {
+	low, high := field.Range()
+	switch field.Type {
+	case entity.Int32Field, entity.Int64Field:
+		return rg.int64Between(clampInt64(low), clampInt64(high))
+	case entity.DecimalField:
+		low, high = max(low, -decimalGeneratorSpan), min(high, decimalGeneratorSpan)
+		scale := math.Pow10(field.Scale)
+		units := rg.int64Between(int64(math.Ceil(low*scale)), int64(math.Floor(high*scale)))
+		return float64(units) / scale
+	case entity.DateField:
+		first, last := dateFromNumber(low), dateFromNumber(high)
+		days := int64(last.Sub(first).Hours() / 24)
+		return first.AddDate(0, 0, int(rg.int64Between(0, days)))
+	case entity.StringField:
+		if field.Check == "cpf" {
+			return rg.generateCPF()
+		}
+		code := make([]byte, field.Length)
+		for i := range code {
+			code[i] = recordCodeCharacters[rg.random.IntN(len(recordCodeCharacters))]
+		}
+		return string(code)
+	default:
+		return rg.generateText(1 + rg.random.IntN(field.Length))
+	}
+}
+
+func (rg *RecordGenerator) int64Between(low, high int64) int64 {
+	span := uint64(high) - uint64(low)
+	if span == math.MaxUint64 {
+		return int64(rg.random.Uint64())
+	}
+	return low + int64(rg.random.Uint64N(span+1))
+}
+
+func (rg *RecordGenerator) generateText(length int) string {
+	var text strings.Builder
+	wordLength := 0
+	for text.Len() < length {
+		remaining := length - text.Len()
+		if wordLength >= 3 && remaining >= 2 && rg.random.IntN(6) == 0 {
+			text.WriteByte(' ')
+			wordLength = 0
+			continue
+		}
+		letter := recordLetters[rg.random.IntN(len(recordLetters))]
+		if wordLength == 0 {
+			letter -= 'a' - 'A'
+		}
+		text.WriteByte(letter)
+		wordLength++
+	}
+	return text.String()
+}
+
+func (rg *RecordGenerator) generateCPF() string {
+	for {
+		base := fmt.Sprintf("%09d", rg.random.IntN(cpfBases))
+		if strings.Count(base, base[:1]) == len(base) {
+			continue
+		}
+		first, second := entity.CPFCheckDigits(base)
+		return base + string([]byte{first, second})
+	}
+}
+
+func clampInt64(value float64) int64 {
+	if value >= math.MaxInt64 {
+		return math.MaxInt64
+	}
+	if value <= math.MinInt64 {
+		return math.MinInt64
+	}
+	return int64(value)
+}
+
+func dateFromNumber(number float64) time.Time {
+	n := int(number)
+	return time.Date(n/10000, time.Month(n/100%100), n%100, 0, 0, 0, 0, time.UTC)
+}
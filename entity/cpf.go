@@ -0,0 +1,68 @@
+package entity
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	ErrCPFRepeatedDigits = errors.New("CPF inválido: todos os dígitos são iguais")
+	ErrCPFCheckDigits    = errors.New("CPF inválido: dígitos verificadores não conferem")
+)
+
+func NormalizeCPF(cpf string) (string, error) {
+	cpf = strings.TrimSpace(cpf)
+	digits := strings.Map(func(char rune) rune {
+		if char == '.' || char == '-' {
+			return -1
+		}
+		return char
+	}, cpf)
+	if err := ValidateCPF(digits); err != nil {
+		return "", fmt.Errorf("%w: %q", err, cpf)
+	}
+	return digits, nil
+}
+
+func ValidateCPF(cpf string) error {
+	if len(cpf) != CPFLength {
+		return fmt.Errorf("CPF deve ter exatamente %d caracteres", CPFLength)
+	}
+
+	for _, char := range cpf {
+		if char < '0' || char > '9' {
+			return errors.New("CPF deve conter apenas dígitos")
+		}
+	}
+
+	if strings.Count(cpf, cpf[:1]) == CPFLength {
+		return ErrCPFRepeatedDigits
+	}
+
+	first, second := CPFCheckDigits(cpf[:CPFLength-2])
+	if cpf[CPFLength-2] != first || cpf[CPFLength-1] != second {
+		return ErrCPFCheckDigits
+	}
+	return nil
+}
+
+func CPFCheckDigits(base string) (byte, byte) {
+	first := cpfCheckDigit(base)
+	return first, cpfCheckDigit(base + string(first))
+}
+
+func cpfCheckDigit(digits string) byte {
+	sum := 0
+	weight := len(digits) + 1
+	for i := 0; i < len(digits); i++ {
+		sum += int(digits[i]-'0') * weight
+		weight--
+	}
+
+	remainder := sum % 11
+	if remainder < 2 {
+		return '0'
+	}
+	return byte('0' + 11 - remainder)
+}
@@ -0,0 +1,47 @@
+package entity
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestValidateCPF(t *testing.T) {
+	cases := []struct {
+		cpf  string
+		want error
+	}{
+		{"52998224725", nil},
+		{"11144477735", nil},
+		{"12345678909", nil},
+		{"00000000191", nil},
+		{"52998224724", ErrCPFCheckDigits},
+		{"52998224715", ErrCPFCheckDigits},
+		{"11144477736", ErrCPFCheckDigits},
+		{"12345678900", ErrCPFCheckDigits},
+		{"00000000000", ErrCPFRepeatedDigits},
+		{"11111111111", ErrCPFRepeatedDigits},
+		{"99999999999", ErrCPFRepeatedDigits},
+	}
+	for _, tc := range cases {
+		if err := ValidateCPF(tc.cpf); !errors.Is(err, tc.want) {
+			t.Errorf("ValidateCPF(%q) = %v, esperado %v", tc.cpf, err, tc.want)
+		}
+	}
+}
+
+func TestValidateCPFRejectsMalformed(t *testing.T) {
+	for _, cpf := range []string{"", "5299822472", "529982247250", "529.982.247-25", "5299822472a"} {
+		if err := ValidateCPF(cpf); err == nil {
+			t.Errorf("ValidateCPF(%q) aceitou um CPF malformado", cpf)
+		}
+	}
+}
+
+func TestCPFCheckDigits(t *testing.T) {
+	for base, want := range map[string]string{"529982247": "25", "111444777": "35", "123456789": "09", "000000001": "91"} {
+		first, second := CPFCheckDigits(base)
+		if got := string([]byte{first, second}); got != want {
+			t.Errorf("CPFCheckDigits(%q) = %s, esperado %s", base, got, want)
+		}
+	}
+}
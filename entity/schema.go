@@ -0,0 +1,388 @@
+package entity
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type FieldType string
+
+const (
+	Int32Field     FieldType = "int32"
+	Int64Field     FieldType = "int64"
+	StringField    FieldType = "string"
+	VarStringField FieldType = "varstring"
+	DecimalField   FieldType = "decimal"
+	DateField      FieldType = "date"
+)
+
+const (
+	DateLayout = "2006-01-02"
+	MaxScale   = 9
+)
+
+var FieldTypes = []FieldType{Int32Field, Int64Field, StringField, VarStringField, DecimalField, DateField}
+
+var fieldChecks = map[string]func(string) error{
+	"cpf": ValidateCPF,
+}
+
+var fieldNormalizers = map[string]func(string) (string, error){
+	"cpf": NormalizeCPF,
+}
+
+type Bound string
+
+func (b *Bound) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err == nil {
+		*b = Bound(text)
+		return nil
+	}
+	var number json.Number
+	if err := json.Unmarshal(data, &number); err != nil {
+		return fmt.Errorf("limite deve ser um número ou uma data entre aspas: %s", data)
+	}
+	*b = Bound(number)
+	return nil
+}
+
+func (b Bound) MarshalJSON() ([]byte, error) {
+	if _, err := strconv.ParseFloat(string(b), 64); err == nil {
+		return []byte(b), nil
+	}
+	return json.Marshal(string(b))
+}
+
+type Field struct {
+	Name      string    `json:"nome"`
+	Type      FieldType `json:"tipo"`
+	Length    int       `json:"tamanho,omitempty"`
+	Precision int       `json:"precisao,omitempty"`
+	Scale     int       `json:"escala,omitempty"`
+	Min       Bound     `json:"min,omitempty"`
+	Max       Bound     `json:"max,omitempty"`
+	Key       bool      `json:"chave,omitempty"`
+	Check     string    `json:"verificacao,omitempty"`
+
+	low, high float64
+}
+
+type Schema struct {
+	Name   string  `json:"nome"`
+	Fields []Field `json:"campos"`
+
+	key int
+}
+
+type Record []any
+
+func (s *Schema) Validate() error {
+	if strings.TrimSpace(s.Name) == "" {
+		return errors.New("esquema inválido: nome não informado")
+	}
+	if len(s.Fields) == 0 {
+		return fmt.Errorf("esquema %s inválido: nenhum campo informado", s.Name)
+	}
+
+	s.key = -1
+	names := make(map[string]bool, len(s.Fields))
+	for i := range s.Fields {
+		field := &s.Fields[i]
+		if strings.TrimSpace(field.Name) == "" {
+			return fmt.Errorf("esquema %s inválido: campo %d sem nome", s.Name, i+1)
+		}
+		if names[field.Name] {
+			return fmt.Errorf("esquema %s inválido: campo %s repetido", s.Name, field.Name)
+		}
+		names[field.Name] = true
+
+		if err := field.validate(); err != nil {
+			return fmt.Errorf("esquema %s inválido: campo %s: %w", s.Name, field.Name, err)
+		}
+		if field.Key {
+			if s.key >= 0 {
+				return fmt.Errorf("esquema %s inválido: mais de um campo chave (%s e %s)", s.Name, s.Fields[s.key].Name, field.Name)
+			}
+			s.key = i
+		}
+	}
+	if s.key < 0 {
+		return fmt.Errorf("esquema %s inválido: nenhum campo chave (marque um campo int32 ou int64 como chave)", s.Name)
+	}
+	return nil
+}
+
+func (f *Field) validate() error {
+	if !slices.Contains(FieldTypes, f.Type) {
+		return fmt.Errorf("tipo desconhecido %q", f.Type)
+	}
+	if f.Key && f.Type != Int32Field && f.Type != Int64Field {
+		return fmt.Errorf("a chave deve ser int32 ou int64, não %s", f.Type)
+	}
+	if f.Check != "" {
+		if _, ok := fieldChecks[f.Check]; !ok || !f.isText() {
+			return fmt.Errorf("verificação %q não existe para o tipo %s", f.Check, f.Type)
+		}
+	}
+
+	switch f.Type {
+	case Int32Field:
+		f.low, f.high = math.MinInt32, math.MaxInt32
+	case Int64Field:
+		f.low, f.high = math.MinInt64, math.MaxInt64
+	case StringField, VarStringField:
+		if f.Length <= 0 {
+			return errors.New("tamanho deve ser positivo")
+		}
+		if f.Min != "" || f.Max != "" {
+			return errors.New("campos de texto não aceitam min e max")
+		}
+		return nil
+	case DecimalField:
+		if f.Scale < 0 || f.Scale > MaxScale {
+			return fmt.Errorf("escala deve estar entre 0 e %d", MaxScale)
+		}
+		if f.Precision < 0 || (f.Precision > 0 && f.Precision < f.Scale) || f.Precision > 18 {
+			return errors.New("precisão deve estar entre a escala e 18")
+		}
+		f.high = math.MaxInt64 / math.Pow10(f.Scale)
+		if f.Precision > 0 {
+			f.high = math.Pow10(f.Precision-f.Scale) - math.Pow10(-f.Scale)
+		}
+		f.low = -f.high
+	case DateField:
+		f.low, f.high = dateNumber(time.Date(1, 1, 1, 0, 0, 0, 0, time.UTC)), dateNumber(time.Date(9999, 12, 31, 0, 0, 0, 0, time.UTC))
+	}
+
+	if f.Min != "" {
+		low, err := f.boundValue(f.Min)
+		if err != nil {
+			return fmt.Errorf("min: %w", err)
+		}
+		f.low = max(f.low, low)
+	}
+	if f.Max != "" {
+		high, err := f.boundValue(f.Max)
+		if err != nil {
+			return fmt.Errorf("max: %w", err)
+		}
+		f.high = min(f.high, high)
+	}
+	if f.low > f.high {
+		return fmt.Errorf("min (%s) maior que max (%s)", f.Min, f.Max)
+	}
+	return nil
+}
+
+func (f *Field) boundValue(bound Bound) (float64, error) {
+	value, err := f.Parse(string(bound))
+	if err != nil {
+		return 0, err
+	}
+	return f.number(value), nil
+}
+
+func (f *Field) isText() bool {
+	return f.Type == StringField || f.Type == VarStringField
+}
+
+func (f *Field) number(value any) float64 {
+	switch v := value.(type) {
+	case int64:
+		return float64(v)
+	case float64:
+		return v
+	case time.Time:
+		return dateNumber(v)
+	}
+	return 0
+}
+
+func dateNumber(date time.Time) float64 {
+	return float64(date.Year()*10000 + int(date.Month())*100 + date.Day())
+}
+
+func (f *Field) Range() (float64, float64) {
+	return f.low, f.high
+}
+
+func (f *Field) Limits() (any, any) {
+	return f.boundOf(f.low), f.boundOf(f.high)
+}
+
+func (f *Field) Parse(text string) (any, error) {
+	text = strings.TrimSpace(text)
+	switch f.Type {
+	case Int32Field, Int64Field:
+		bits := 64
+		if f.Type == Int32Field {
+			bits = 32
+		}
+		value, err := strconv.ParseInt(text, 10, bits)
+		if err != nil {
+			return nil, fmt.Errorf("%q não é um inteiro de %d bits", text, bits)
+		}
+		return value, nil
+	case DecimalField:
+		value, err := strconv.ParseFloat(strings.Replace(text, ",", ".", 1), 64)
+		if err != nil || math.IsNaN(value) || math.IsInf(value, 0) {
+			return nil, fmt.Errorf("%q não é um número decimal", text)
+		}
+		return value, nil
+	case DateField:
+		value, err := time.Parse(DateLayout, text)
+		if err != nil {
+			return nil, fmt.Errorf("%q não é uma data no formato AAAA-MM-DD", text)
+		}
+		return value, nil
+	default:
+		if normalize, ok := fieldNormalizers[f.Check]; ok {
+			return normalize(text)
+		}
+		return text, nil
+	}
+}
+
+func (f *Field) Format(value any) string {
+	switch v := value.(type) {
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'f', f.Scale, 64)
+	case time.Time:
+		return v.Format(DateLayout)
+	case string:
+		return v
+	}
+	return fmt.Sprint(value)
+}
+
+func (f *Field) ValidateValue(value any) error {
+	switch f.Type {
+	case Int32Field, Int64Field, DecimalField, DateField:
+		if !f.acceptsType(value) {
+			return fmt.Errorf("campo %s deve ser do tipo %s, recebido %T", f.Name, f.Type, value)
+		}
+		if number := f.number(value); number < f.low || number > f.high || math.IsNaN(number) {
+			return fmt.Errorf("campo %s deve estar entre %s e %s", f.Name, f.Format(f.boundOf(f.low)), f.Format(f.boundOf(f.high)))
+		}
+	case StringField, VarStringField:
+		text, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("campo %s deve ser texto, recebido %T", f.Name, value)
+		}
+		if f.Type == StringField && TextLength(text) != f.Length {
+			return fmt.Errorf("%s deve ter exatamente %d caracteres", f.Name, f.Length)
+		}
+		if err := validateText(f.Name, text, f.Length); err != nil {
+			return err
+		}
+		if f.Check != "" {
+			if err := fieldChecks[f.Check](text); err != nil {
+				return fmt.Errorf("campo %s: %w", f.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+func (f *Field) acceptsType(value any) bool {
+	switch value.(type) {
+	case int64:
+		return f.Type == Int32Field || f.Type == Int64Field
+	case float64:
+		return f.Type == DecimalField
+	case time.Time:
+		return f.Type == DateField
+	}
+	return false
+}
+
+func (f *Field) boundOf(number float64) any {
+	switch f.Type {
+	case DecimalField:
+		return number
+	case DateField:
+		n := int(number)
+		return time.Date(n/10000, time.Month(n/100%100), n%100, 0, 0, 0, 0, time.UTC)
+	}
+	if number >= math.MaxInt64 {
+		return int64(math.MaxInt64)
+	}
+	return int64(number)
+}
+
+func (s *Schema) KeyIndex() int {
+	return s.key
+}
+
+func (s *Schema) KeyField() *Field {
+	return &s.Fields[s.key]
+}
+
+func (s *Schema) Key(record Record) int64 {
+	key, _ := record[s.key].(int64)
+	return key
+}
+
+func (s *Schema) FieldIndex(name string) int {
+	return slices.IndexFunc(s.Fields, func(field Field) bool {
+		return field.Name == name
+	})
+}
+
+func (s *Schema) Equal(other *Schema) bool {
+	return s == other || other != nil && s.Name == other.Name && s.key == other.key && slices.Equal(s.Fields, other.Fields)
+}
+
+func (s *Schema) Fingerprint() uint64 {
+	hash := fnv.New64a()
+	json.NewEncoder(hash).Encode(s)
+	return hash.Sum64()
+}
+
+func (s *Schema) ValidateRecord(record Record) error {
+	if len(record) != len(s.Fields) {
+		return fmt.Errorf("registro com %d valores, o esquema %s tem %d campos", len(record), s.Name, len(s.Fields))
+	}
+	for i := range s.Fields {
+		if err := s.Fields[i].ValidateValue(record[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Schema) ParseRecord(values []string) (Record, error) {
+	if len(values) != len(s.Fields) {
+		return nil, fmt.Errorf("%d valores informados, o esquema %s tem %d campos", len(values), s.Name, len(s.Fields))
+	}
+	record := make(Record, len(s.Fields))
+	for i := range s.Fields {
+		value, err := s.Fields[i].Parse(values[i])
+		if err != nil {
+			return nil, fmt.Errorf("campo %s: %w", s.Fields[i].Name, err)
+		}
+		record[i] = value
+	}
+	if err := s.ValidateRecord(record); err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+func (s *Schema) FormatRecord(record Record) []string {
+	values := make([]string, len(record))
+	for i := range record {
+		values[i] = s.Fields[i].Format(record[i])
+	}
+	return values
+}
@@ -0,0 +1,343 @@
+package entity
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+const StudentSchemaName = "alunos"
+
+//go:embed schemas/*.sql
+var builtinSchemas embed.FS
+
+var StudentSchema = sync.OnceValue(func() *Schema {
+	schema, err := BuiltinSchema(StudentSchemaName)
+	if err != nil {
+		panic(err)
+	}
+	return schema
+})
+
+func BuiltinSchemaNames() []string {
+	entries, err := builtinSchemas.ReadDir("schemas")
+	if err != nil {
+		return nil
+	}
+
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		names = append(names, strings.TrimSuffix(entry.Name(), ".sql"))
+	}
+	return names
+}
+
+func BuiltinSchema(name string) (*Schema, error) {
+	data, err := builtinSchemas.ReadFile(path.Join("schemas", name+".sql"))
+	if err != nil {
+		return nil, fmt.Errorf("esquema %q não existe (disponíveis: %s)", name, strings.Join(BuiltinSchemaNames(), ", "))
+	}
+	return ParseDDL(string(data))
+}
+
+func LoadSchema(filename string) (*Schema, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao ler esquema: %w", err)
+	}
+	if strings.EqualFold(filepath.Ext(filename), ".json") {
+		return ParseSchemaJSON(data)
+	}
+	return ParseDDL(string(data))
+}
+
+func ResolveSchema(spec string) (*Schema, error) {
+	if spec == StudentSchemaName {
+		return StudentSchema(), nil
+	}
+	if slices.Contains(BuiltinSchemaNames(), spec) || filepath.Ext(spec) == "" {
+		return BuiltinSchema(spec)
+	}
+	return LoadSchema(spec)
+}
+
+func ParseSchemaJSON(data []byte) (*Schema, error) {
+	var schema Schema
+	if err := json.Unmarshal(data, &schema); err != nil {
+		return nil, fmt.Errorf("esquema inválido: %w", err)
+	}
+	if err := schema.Validate(); err != nil {
+		return nil, err
+	}
+	return &schema, nil
+}
+
+type ddlParser struct {
+	tokens []string
+	pos    int
+}
+
+func ParseDDL(text string) (*Schema, error) {
+	tokens, err := tokenizeDDL(text)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &ddlParser{tokens: tokens}
+	schema, err := p.parseTable()
+	if err != nil {
+		return nil, fmt.Errorf("DDL inválido: %w", err)
+	}
+	if err := schema.Validate(); err != nil {
+		return nil, err
+	}
+	return schema, nil
+}
+
+func tokenizeDDL(text string) ([]string, error) {
+	tokens := make([]string, 0)
+	runes := []rune(text)
+	for i := 0; i < len(runes); {
+		char := runes[i]
+		switch {
+		case unicode.IsSpace(char):
+			i++
+		case char == '-' && i+1 < len(runes) && runes[i+1] == '-':
+			for i < len(runes) && runes[i] != '\n' {
+				i++
+			}
+		case char == '\'':
+			end := i + 1
+			for end < len(runes) && runes[end] != '\'' {
+				end++
+			}
+			if end == len(runes) {
+				return nil, fmt.Errorf("DDL inválido: texto sem aspas de fechamento")
+			}
+			tokens = append(tokens, string(runes[i:end+1]))
+			i = end + 1
+		case char == '>' || char == '<':
+			if i+1 < len(runes) && runes[i+1] == '=' {
+				tokens = append(tokens, string(runes[i:i+2]))
+				i += 2
+				continue
+			}
+			return nil, fmt.Errorf("DDL inválido: use >= ou <= em vez de %c", char)
+		case strings.ContainsRune("(),;", char):
+			tokens = append(tokens, string(char))
+			i++
+		case unicode.IsLetter(char) || unicode.IsDigit(char) || char == '_' || char == '-' || char == '.':
+			end := i + 1
+			for end < len(runes) && (unicode.IsLetter(runes[end]) || unicode.IsDigit(runes[end]) || runes[end] == '_' || runes[end] == '.') {
+				end++
+			}
+			tokens = append(tokens, string(runes[i:end]))
+			i = end
+		default:
+			return nil, fmt.Errorf("DDL inválido: caractere inesperado %q", char)
+		}
+	}
+	return tokens, nil
+}
+
+func (p *ddlParser) next() string {
+	if p.pos >= len(p.tokens) {
+		return ""
+	}
+	token := p.tokens[p.pos]
+	p.pos++
+	return token
+}
+
+func (p *ddlParser) peek() string {
+	if p.pos >= len(p.tokens) {
+		return ""
+	}
+	return p.tokens[p.pos]
+}
+
+func (p *ddlParser) expect(words ...string) error {
+	for _, word := range words {
+		if token := p.next(); !strings.EqualFold(token, word) {
+			return fmt.Errorf("esperado %q, encontrado %q", word, token)
+		}
+	}
+	return nil
+}
+
+func (p *ddlParser) number() (int, error) {
+	token := p.next()
+	value, err := strconv.Atoi(token)
+	if err != nil {
+		return 0, fmt.Errorf("esperado um número, encontrado %q", token)
+	}
+	return value, nil
+}
+
+func (p *ddlParser) literal() (Bound, error) {
+	token := p.next()
+	if strings.HasPrefix(token, "'") {
+		return Bound(strings.Trim(token, "'")), nil
+	}
+	if _, err := strconv.ParseFloat(token, 64); err != nil {
+		return "", fmt.Errorf("esperado um número ou uma data entre aspas, encontrado %q", token)
+	}
+	return Bound(token), nil
+}
+
+func (p *ddlParser) parseTable() (*Schema, error) {
+	if err := p.expect("CREATE", "TABLE"); err != nil {
+		return nil, err
+	}
+	schema := &Schema{Name: p.next()}
+	if err := p.expect("("); err != nil {
+		return nil, err
+	}
+
+	for {
+		field, err := p.parseField()
+		if err != nil {
+			return nil, err
+		}
+		schema.Fields = append(schema.Fields, field)
+
+		token := p.next()
+		if token == ")" {
+			break
+		}
+		if token != "," {
+			return nil, fmt.Errorf("esperado \",\" ou \")\" após o campo %s, encontrado %q", field.Name, token)
+		}
+	}
+
+	if p.peek() == ";" {
+		p.next()
+	}
+	if token := p.next(); token != "" {
+		return nil, fmt.Errorf("conteúdo inesperado após a tabela: %q", token)
+	}
+	return schema, nil
+}
+
+func (p *ddlParser) parseField() (Field, error) {
+	field := Field{Name: p.next()}
+	typeName := strings.ToUpper(p.next())
+	switch typeName {
+	case "INT32", "INT", "INTEGER":
+		field.Type = Int32Field
+	case "INT64", "BIGINT":
+		field.Type = Int64Field
+	case "DATE":
+		field.Type = DateField
+	case "CHAR", "VARCHAR":
+		field.Type = StringField
+		if typeName == "VARCHAR" {
+			field.Type = VarStringField
+		}
+		length, err := p.arguments()
+		if err != nil || len(length) != 1 {
+			return field, fmt.Errorf("campo %s: %s exige o tamanho, como %s(30)", field.Name, typeName, typeName)
+		}
+		field.Length = length[0]
+	case "DECIMAL", "NUMERIC":
+		field.Type = DecimalField
+		arguments, err := p.arguments()
+		if err != nil || len(arguments) < 1 || len(arguments) > 2 {
+			return field, fmt.Errorf("campo %s: use DECIMAL(precisão, escala)", field.Name)
+		}
+		field.Precision = arguments[0]
+		if len(arguments) == 2 {
+			field.Scale = arguments[1]
+		}
+	default:
+		return field, fmt.Errorf("campo %s: tipo desconhecido %q", field.Name, typeName)
+	}
+
+	for {
+		switch strings.ToUpper(p.peek()) {
+		case "PRIMARY":
+			p.next()
+			if err := p.expect("KEY"); err != nil {
+				return field, err
+			}
+			field.Key = true
+		case "CHECK":
+			p.next()
+			if err := p.parseCheck(&field); err != nil {
+				return field, fmt.Errorf("campo %s: %w", field.Name, err)
+			}
+		default:
+			return field, nil
+		}
+	}
+}
+
+func (p *ddlParser) arguments() ([]int, error) {
+	if p.peek() != "(" {
+		return nil, fmt.Errorf("esperado \"(\"")
+	}
+	p.next()
+
+	values := make([]int, 0, 2)
+	for {
+		value, err := p.number()
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+		if token := p.next(); token == ")" {
+			return values, nil
+		} else if token != "," {
+			return nil, fmt.Errorf("esperado \",\" ou \")\", encontrado %q", token)
+		}
+	}
+}
+
+func (p *ddlParser) parseCheck(field *Field) error {
+	if err := p.expect("("); err != nil {
+		return err
+	}
+
+	name := p.next()
+	if p.peek() == "(" {
+		p.next()
+		if err := p.expect(field.Name, ")", ")"); err != nil {
+			return err
+		}
+		field.Check = strings.ToLower(name)
+		return nil
+	}
+	if name != field.Name {
+		return fmt.Errorf("CHECK deve se referir ao próprio campo, encontrado %q", name)
+	}
+
+	var err error
+	switch operator := strings.ToUpper(p.next()); operator {
+	case "BETWEEN":
+		if field.Min, err = p.literal(); err != nil {
+			return err
+		}
+		if err := p.expect("AND"); err != nil {
+			return err
+		}
+		field.Max, err = p.literal()
+	case ">=":
+		field.Min, err = p.literal()
+	case "<=":
+		field.Max, err = p.literal()
+	default:
+		return fmt.Errorf("use BETWEEN, >= ou <= no CHECK, encontrado %q", operator)
+	}
+	if err != nil {
+		return err
+	}
+	return p.expect(")")
+}
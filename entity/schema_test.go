@@ -0,0 +1,70 @@
+package entity
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseRecordNormalizesCPF(t *testing.T) {
+	schema := StudentSchema()
+	for _, cpf := range []string{"52998224725", "529.982.247-25", " 529.982.247-25 "} {
+		record, err := schema.ParseRecord([]string{"100000001", "Ana Silva", cpf, "Computação", "Maria Silva", "José Silva", "2020", "7.5"})
+		if err != nil {
+			t.Fatalf("ParseRecord com CPF %q: %v", cpf, err)
+		}
+		if got := record[2]; got != "52998224725" {
+			t.Fatalf("ParseRecord com CPF %q gravou %q, esperado 52998224725", cpf, got)
+		}
+	}
+}
+
+func TestParseRecordRejectsInvalidCPF(t *testing.T) {
+	schema := StudentSchema()
+	for _, cpf := range []string{"529.982.247-24", "111.111.111-11", "5299822472"} {
+		_, err := schema.ParseRecord([]string{"100000001", "Ana Silva", cpf, "Computação", "Maria Silva", "José Silva", "2020", "7.5"})
+		if err == nil || !strings.Contains(err.Error(), "cpf") {
+			t.Fatalf("ParseRecord aceitou o CPF %q (erro %v)", cpf, err)
+		}
+	}
+}
+
+func TestLoadedStudentSchemaEqualsBuiltin(t *testing.T) {
+	loaded, err := ResolveSchema("schemas/alunos.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded == StudentSchema() || !loaded.Equal(StudentSchema()) {
+		t.Fatal("esquema carregado de schemas/alunos.sql deveria ser igual, mas não idêntico, ao esquema de alunos")
+	}
+
+	other, err := BuiltinSchema("disciplinas")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if other.Equal(StudentSchema()) {
+		t.Fatal("esquema de disciplinas considerado igual ao de alunos")
+	}
+}
+
+func TestFingerprintIdentifiesSchema(t *testing.T) {
+	loaded, err := ResolveSchema("schemas/alunos.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Fingerprint() != StudentSchema().Fingerprint() {
+		t.Fatal("esquemas de alunos iguais com impressões digitais diferentes")
+	}
+
+	other, err := BuiltinSchema("disciplinas")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if other.Fingerprint() == StudentSchema().Fingerprint() {
+		t.Fatal("esquemas de disciplinas e de alunos com a mesma impressão digital")
+	}
+
+	loaded.Fields[1].Length++
+	if loaded.Fingerprint() == StudentSchema().Fingerprint() {
+		t.Fatal("alteração no tamanho de um campo não mudou a impressão digital")
+	}
+}
@@ -0,0 +1,11 @@
+-- Registro de aluno do trabalho prático: mesmo leiaute de entity.Student
+CREATE TABLE alunos (
+    matricula   INT32 PRIMARY KEY CHECK (matricula BETWEEN 1 AND 999999999),
+    nome        VARCHAR(50),
+    cpf         CHAR(11) CHECK (cpf(cpf)),
+    curso       VARCHAR(30),
+    filiacaoMae VARCHAR(30),
+    filiacaoPai VARCHAR(30),
+    anoIngresso INT32 CHECK (anoIngresso BETWEEN 1000 AND 9999),
+    ca          DECIMAL(4, 2) CHECK (ca BETWEEN 0 AND 10)
+);
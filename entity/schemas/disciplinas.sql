@@ -0,0 +1,12 @@
+-- Disciplinas oferecidas pelos departamentos
+CREATE TABLE disciplinas (
+    codigo       INT32 PRIMARY KEY CHECK (codigo BETWEEN 1 AND 999999),
+    sigla        CHAR(7),
+    nome         VARCHAR(60),
+    departamento VARCHAR(40),
+    ementa       VARCHAR(200),
+    creditos     INT32 CHECK (creditos BETWEEN 1 AND 12),
+    cargaHoraria INT32 CHECK (cargaHoraria BETWEEN 15 AND 180),
+    notaMinima   DECIMAL(4, 2) CHECK (notaMinima BETWEEN 0 AND 10),
+    criadaEm     DATE CHECK (criadaEm BETWEEN '1950-01-01' AND '2030-12-31')
+);
@@ -1,18 +1,17 @@
 package entity
 
 import (
-	"errors"
 	"fmt"
 	"math"
 	"strconv"
 	"strings"
-	"unicode"
 
 	"github.com/go-playground/validator/v10"
 )
 
 const (
 	MaxMatriculaDigits = 9
+	MaxMatricula       = 999999999
 	MaxNomeLength      = 50
 	CPFLength          = 11
 	MaxCursoLength     = 30
@@ -38,20 +37,11 @@ func validateMatriculaDigits(fl validator.FieldLevel) bool {
 }
 
 func validateCPFFormat(fl validator.FieldLevel) bool {
-	cpf := fl.Field().String()
-	if len(cpf) != CPFLength {
-		return false
-	}
-	for _, char := range cpf {
-		if !unicode.IsDigit(char) {
-			return false
-		}
-	}
-	return true
+	return ValidateCPF(fl.Field().String()) == nil
 }
 
 type Student struct {
-	Matricula   int     `validate:"required,min=1,matricula_digits"`
+	Matricula   int64   `validate:"required,min=1,matricula_digits"`
 	Nome        string  `validate:"required,min=1,max=50"`
 	CPF         string  `validate:"required,cpf_format"`
 	Curso       string  `validate:"required,min=1,max=30"`
@@ -62,81 +52,74 @@ type Student struct {
 }
 
 func (s *Student) Validate() error {
-	if s.Matricula < 1 || len(strconv.Itoa(s.Matricula)) > MaxMatriculaDigits {
-		return fmt.Errorf("matrícula deve ter no máximo %d dígitos", MaxMatriculaDigits)
-	}
-
-	if len(s.Nome) == 0 || len(s.Nome) > MaxNomeLength {
-		return fmt.Errorf("nome deve ter entre 1 e %d caracteres", MaxNomeLength)
-	}
-
-	if len(s.CPF) != CPFLength {
-		return fmt.Errorf("CPF deve ter exatamente %d caracteres", CPFLength)
-	}
-
-	for _, char := range s.CPF {
-		if char < '0' || char > '9' {
-			return errors.New("CPF deve conter apenas dígitos")
-		}
-	}
-
-	if len(s.Curso) == 0 || len(s.Curso) > MaxCursoLength {
-		return fmt.Errorf("curso deve ter entre 1 e %d caracteres", MaxCursoLength)
-	}
-
-	if len(s.FiliacaoMae) == 0 || len(s.FiliacaoMae) > MaxFiliacaoLength {
-		return fmt.Errorf("filiação mãe deve ter entre 1 e %d caracteres", MaxFiliacaoLength)
-	}
-
-	if len(s.FiliacaoPai) == 0 || len(s.FiliacaoPai) > MaxFiliacaoLength {
-		return fmt.Errorf("filiação pai deve ter entre 1 e %d caracteres", MaxFiliacaoLength)
-	}
-
-	if s.AnoIngresso < AnoIngressoMin || s.AnoIngresso > AnoIngressoMax {
-		return fmt.Errorf("ano de ingresso deve ter %d dígitos", 4)
-	}
-
-	if s.CA < CA_MIN || s.CA > CA_MAX {
-		return fmt.Errorf("CA deve estar entre %.2f e %.2f", CA_MIN, CA_MAX)
-	}
+	return StudentSchema().ValidateRecord(s.Record())
+}
 
-	return nil
+func studentField(name string) *Field {
+	schema := StudentSchema()
+	return &schema.Fields[schema.FieldIndex(name)]
 }
 
 func (s *Student) TruncateFields() {
-	if len(s.Nome) > MaxNomeLength {
-		s.Nome = s.Nome[:MaxNomeLength]
-	}
+	s.Nome = TruncateText(s.Nome, studentField("nome").Length)
 
-	if len(s.CPF) > CPFLength {
-		s.CPF = s.CPF[:CPFLength]
-	} else if len(s.CPF) < CPFLength {
-		s.CPF = strings.Repeat("0", CPFLength-len(s.CPF)) + s.CPF
+	cpfLength := studentField("cpf").Length
+	if cpf, err := NormalizeCPF(s.CPF); err == nil {
+		s.CPF = cpf
+	} else if len(s.CPF) > cpfLength {
+		s.CPF = s.CPF[:cpfLength]
+	} else if len(s.CPF) < cpfLength {
+		s.CPF = strings.Repeat("0", cpfLength-len(s.CPF)) + s.CPF
 	}
 
-	if len(s.Curso) > MaxCursoLength {
-		s.Curso = s.Curso[:MaxCursoLength]
-	}
+	s.Curso = TruncateText(s.Curso, studentField("curso").Length)
 
-	if len(s.FiliacaoMae) > MaxFiliacaoLength {
-		s.FiliacaoMae = s.FiliacaoMae[:MaxFiliacaoLength]
-	}
+	s.FiliacaoMae = TruncateText(s.FiliacaoMae, studentField("filiacaoMae").Length)
 
-	if len(s.FiliacaoPai) > MaxFiliacaoLength {
-		s.FiliacaoPai = s.FiliacaoPai[:MaxFiliacaoLength]
-	}
+	s.FiliacaoPai = TruncateText(s.FiliacaoPai, studentField("filiacaoPai").Length)
 
-	matriculaStr := strconv.Itoa(s.Matricula)
-	if len(matriculaStr) > MaxMatriculaDigits {
-		matriculaStr = matriculaStr[:MaxMatriculaDigits]
-		s.Matricula, _ = strconv.Atoi(matriculaStr)
+	_, maxMatricula := studentField("matricula").Range()
+	matriculaDigits := len(strconv.FormatFloat(maxMatricula, 'f', 0, 64))
+	matriculaStr := strconv.FormatInt(s.Matricula, 10)
+	if len(matriculaStr) > matriculaDigits {
+		matriculaStr = matriculaStr[:matriculaDigits]
+		s.Matricula, _ = strconv.ParseInt(matriculaStr, 10, 64)
 	}
 
+	caMin, caMax := studentField("ca").Range()
 	s.CA = math.Round(s.CA*100) / 100
-	if s.CA < CA_MIN {
-		s.CA = CA_MIN
+	if s.CA < caMin {
+		s.CA = caMin
+	}
+	if s.CA > caMax {
+		s.CA = caMax
 	}
-	if s.CA > CA_MAX {
-		s.CA = CA_MAX
+}
+
+func (s Student) Record() Record {
+	return Record{s.Matricula, s.Nome, s.CPF, s.Curso, s.FiliacaoMae, s.FiliacaoPai, int64(s.AnoIngresso), s.CA}
+}
+
+func StudentFromRecord(record Record) (*Student, error) {
+	if len(record) != len(StudentSchema().Fields) {
+		return nil, fmt.Errorf("registro com %d valores não é um aluno", len(record))
+	}
+
+	student := &Student{}
+	var ok [8]bool
+	student.Matricula, ok[0] = record[0].(int64)
+	student.Nome, ok[1] = record[1].(string)
+	student.CPF, ok[2] = record[2].(string)
+	student.Curso, ok[3] = record[3].(string)
+	student.FiliacaoMae, ok[4] = record[4].(string)
+	student.FiliacaoPai, ok[5] = record[5].(string)
+	anoIngresso, ok6 := record[6].(int64)
+	student.AnoIngresso, ok[6] = int(anoIngresso), ok6
+	student.CA, ok[7] = record[7].(float64)
+	for i, converted := range ok {
+		if !converted {
+			return nil, fmt.Errorf("campo %s do registro não é do tipo %s", StudentSchema().Fields[i].Name, StudentSchema().Fields[i].Type)
+		}
 	}
-}
\ No newline at end of file
+	return student, nil
+}
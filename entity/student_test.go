@@ -0,0 +1,100 @@
+package entity
+
+import (
+	"strings"
+	"testing"
+)
+
+func validStudent() Student {
+	return Student{
+		Matricula:   100000001,
+		Nome:        "Ana Silva",
+		CPF:         "52998224725",
+		Curso:       "Computação",
+		FiliacaoMae: "Maria Silva",
+		FiliacaoPai: "José Silva",
+		AnoIngresso: 2020,
+		CA:          7.5,
+	}
+}
+
+func TestStudentLimitsMatchSchema(t *testing.T) {
+	lengths := map[string]int{
+		"nome":        MaxNomeLength,
+		"cpf":         CPFLength,
+		"curso":       MaxCursoLength,
+		"filiacaoMae": MaxFiliacaoLength,
+		"filiacaoPai": MaxFiliacaoLength,
+	}
+	for name, length := range lengths {
+		if got := studentField(name).Length; got != length {
+			t.Errorf("campo %s com %d caracteres no esquema, constante %d", name, got, length)
+		}
+	}
+
+	ranges := map[string][2]float64{
+		"matricula":   {1, MaxMatricula},
+		"anoIngresso": {AnoIngressoMin, AnoIngressoMax},
+		"ca":          {CA_MIN, CA_MAX},
+	}
+	for name, want := range ranges {
+		if low, high := studentField(name).Range(); low != want[0] || high != want[1] {
+			t.Errorf("campo %s entre %g e %g no esquema, constantes %g e %g", name, low, high, want[0], want[1])
+		}
+	}
+}
+
+func TestStudentValidateUsesSchemaLimits(t *testing.T) {
+	student := validStudent()
+	if err := student.Validate(); err != nil {
+		t.Fatalf("aluno válido rejeitado: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		field  string
+		change func(*Student)
+	}{
+		{"matricula-zero", "matricula", func(s *Student) { s.Matricula = 0 }},
+		{"matricula-longa", "matricula", func(s *Student) { s.Matricula = MaxMatricula + 1 }},
+		{"nome-vazio", "nome", func(s *Student) { s.Nome = "" }},
+		{"nome-longo", "nome", func(s *Student) { s.Nome = strings.Repeat("a", MaxNomeLength+1) }},
+		{"cpf-invalido", "cpf", func(s *Student) { s.CPF = "52998224724" }},
+		{"curso-longo", "curso", func(s *Student) { s.Curso = strings.Repeat("c", MaxCursoLength+1) }},
+		{"mae-vazia", "filiacaoMae", func(s *Student) { s.FiliacaoMae = "" }},
+		{"pai-longo", "filiacaoPai", func(s *Student) { s.FiliacaoPai = strings.Repeat("p", MaxFiliacaoLength+1) }},
+		{"ano-baixo", "anoIngresso", func(s *Student) { s.AnoIngresso = AnoIngressoMin - 1 }},
+		{"ca-alto", "ca", func(s *Student) { s.CA = CA_MAX + 0.01 }},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			student := validStudent()
+			test.change(&student)
+			err := student.Validate()
+			if err == nil || !strings.Contains(err.Error(), test.field) {
+				t.Fatalf("Validate = %v, esperado erro no campo %s", err, test.field)
+			}
+		})
+	}
+
+	student = validStudent()
+	student.Nome = strings.Repeat("a", MaxNomeLength)
+	if err := student.Validate(); err != nil {
+		t.Fatalf("nome com %d caracteres rejeitado: %v", MaxNomeLength, err)
+	}
+}
+
+func TestTruncateFieldsProducesValidStudent(t *testing.T) {
+	student := validStudent()
+	student.Nome = strings.Repeat("n", MaxNomeLength+10)
+	student.Curso = strings.Repeat("c", MaxCursoLength+10)
+	student.Matricula = 12345678901
+	student.CA = 12.345
+	student.TruncateFields()
+	if err := student.Validate(); err != nil {
+		t.Fatalf("aluno truncado inválido: %v", err)
+	}
+	if student.Matricula != 123456789 || student.CA != CA_MAX {
+		t.Fatalf("truncado para matrícula %d e CA %g", student.Matricula, student.CA)
+	}
+}
@@ -0,0 +1,35 @@
+package entity
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+func TextLength(text string) int {
+	return utf8.RuneCountInString(text)
+}
+
+func TruncateText(text string, length int) string {
+	count := 0
+	for i := range text {
+		if count == length {
+			return text[:i]
+		}
+		count++
+	}
+	return text
+}
+
+func validateText(field, text string, maxLength int) error {
+	if !utf8.ValidString(text) {
+		return fmt.Errorf("%s não é texto UTF-8 válido", field)
+	}
+	if strings.ContainsRune(text, 0) {
+		return fmt.Errorf("%s não pode conter o caractere NUL", field)
+	}
+	if length := TextLength(text); length == 0 || length > maxLength {
+		return fmt.Errorf("%s deve ter entre 1 e %d caracteres", field, maxLength)
+	}
+	return nil
+}
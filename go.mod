@@ -2,13 +2,17 @@ module aeds2-tp1
 
 go 1.25.3
 
+require (
+	github.com/go-playground/validator/v10 v10.28.0
+	golang.org/x/sys v0.36.0
+	golang.org/x/term v0.35.0
+)
+
 require (
 	github.com/gabriel-vasile/mimetype v1.4.10 // indirect
 	github.com/go-playground/locales v0.14.1 // indirect
 	github.com/go-playground/universal-translator v0.18.1 // indirect
-	github.com/go-playground/validator/v10 v10.28.0 // indirect
 	github.com/leodido/go-urn v1.4.0 // indirect
 	golang.org/x/crypto v0.42.0 // indirect
-	golang.org/x/sys v0.36.0 // indirect
 	golang.org/x/text v0.29.0 // indirect
 )
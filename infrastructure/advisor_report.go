@@ -0,0 +1,63 @@
+package infrastructure
+
+import (
+	"aeds2-tp1/storage"
+	"fmt"
+	"io"
+	"os"
+)
+
+const advisorRankingSize = 5
+
+type AdviceReporter struct {
+	advice storage.BlockSizeAdvice
+	out    io.Writer
+}
+
+func NewAdviceReporter(advice storage.BlockSizeAdvice) *AdviceReporter {
+	return NewAdviceReporterTo(os.Stdout, advice)
+}
+
+func NewAdviceReporterTo(out io.Writer, advice storage.BlockSizeAdvice) *AdviceReporter {
+	return &AdviceReporter{
+		advice: advice,
+		out:    out,
+	}
+}
+
+func (ar *AdviceReporter) PrintAdvice(mode storage.Mode) {
+	fmt.Fprintln(ar.out, "\n=== RECOMENDAÇÃO DE TAMANHO DE BLOCO ===")
+	fmt.Fprintf(ar.out, "Objetivo: %s; candidatos: %s\n", ar.advice.Goal, ar.advice.Constraint)
+	fmt.Fprintf(ar.out, "Simulação com amostra de %d registros, estimada para %d registros\n\n", ar.advice.SampleSize, ar.advice.Records)
+
+	ar.printHeader("Modo")
+	for _, candidate := range ar.advice.Recommended {
+		ar.printCandidate(candidate.Mode.String(), candidate)
+	}
+
+	ranking := ar.advice.Ranking(mode)
+	if len(ranking) == 0 {
+		fmt.Fprintf(ar.out, "\nNenhum tamanho candidato é válido para o modo %s.\n", mode)
+		return
+	}
+
+	fmt.Fprintf(ar.out, "\nMelhores tamanhos para o modo %s:\n", mode)
+	ar.printHeader("Posição")
+	for i, candidate := range ranking[:min(advisorRankingSize, len(ranking))] {
+		ar.printCandidate(fmt.Sprintf("%dº", i+1), candidate)
+	}
+}
+
+func (ar *AdviceReporter) printHeader(label string) {
+	fmt.Fprintf(ar.out, "%-10s %8s %9s %15s %11s %15s\n", label, "Bloco", "Blocos", "Registros/bloco", "Eficiência", "Leituras/busca")
+}
+
+func (ar *AdviceReporter) printCandidate(label string, candidate storage.BlockSizeCandidate) {
+	fmt.Fprintf(ar.out, "%-10s %8d %9d %15.2f %10.2f%% %15.2f\n",
+		label,
+		candidate.BlockSize,
+		candidate.Blocks,
+		candidate.RecordsPerBlock,
+		candidate.EfficiencyRate,
+		candidate.AvgLookupReads)
+}
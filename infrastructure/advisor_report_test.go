@@ -0,0 +1,49 @@
+package infrastructure
+
+import (
+	"aeds2-tp1/storage"
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestPrintAdvice(t *testing.T) {
+	candidate := func(mode storage.Mode, blockSize int, efficiency float64) storage.BlockSizeCandidate {
+		return storage.BlockSizeCandidate{Mode: mode, BlockSize: blockSize, Blocks: 100, RecordsPerBlock: 3, EfficiencyRate: efficiency, AvgLookupReads: 50.5}
+	}
+	advice := storage.BlockSizeAdvice{
+		Goal:       storage.MaximizeEfficiency,
+		Constraint: storage.PowerOfTwoBlockSize,
+		SampleSize: 50,
+		Records:    300,
+		Candidates: []storage.BlockSizeCandidate{
+			candidate(storage.FixedMode, 256, 65.23),
+			candidate(storage.FixedMode, 512, 97.85),
+			candidate(storage.VariableMode, 512, 90),
+		},
+	}
+	advice.Recommended = []storage.BlockSizeCandidate{advice.Candidates[1], advice.Candidates[2]}
+
+	var out bytes.Buffer
+	NewAdviceReporterTo(&out, advice).PrintAdvice(storage.FixedMode)
+	report := out.String()
+	for _, want := range []string{
+		"Objetivo: maior eficiência; candidatos: potências de 2\n",
+		"Simulação com amostra de 50 registros, estimada para 300 registros\n",
+		"fixo            512       100            3.00      97.85%           50.50\n",
+		"variável        512       100            3.00      90.00%           50.50\n",
+		"Melhores tamanhos para o modo fixo:\n",
+		"1º              512",
+		"2º              256",
+	} {
+		if !strings.Contains(report, want) {
+			t.Errorf("recomendação sem %q:\n%s", want, report)
+		}
+	}
+
+	out.Reset()
+	NewAdviceReporterTo(&out, advice).PrintAdvice(storage.FragmentedMode)
+	if !strings.Contains(out.String(), "Nenhum tamanho candidato é válido para o modo espalhado.") {
+		t.Fatalf("modo sem candidatos:\n%s", out.String())
+	}
+}
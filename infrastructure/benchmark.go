@@ -0,0 +1,208 @@
+package infrastructure
+
+import (
+	"aeds2-tp1/entity"
+	"aeds2-tp1/storage"
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var BenchmarkModes = []storage.Mode{storage.FixedMode, storage.VariableMode, storage.FragmentedMode}
+
+type BenchmarkOptions struct {
+	BlockSizes []int
+	Modes      []storage.Mode
+	Lookups    int
+	Dir        string
+	Seed       uint64
+	Encoding   storage.Encoding
+	OnResult   func(BenchmarkResult)
+}
+
+type BenchmarkResult struct {
+	Mode           string
+	BlockSize      int
+	Records        int
+	TotalBlocks    int64
+	EfficiencyRate float64
+	PartialBlocks  int64
+	Lookups        int
+	AvgBlockReads  float64
+	WriteTime      time.Duration
+	ScanTime       time.Duration
+	Error          string `json:",omitempty"`
+}
+
+func RunBenchmark(ctx context.Context, students []entity.Student, options BenchmarkOptions) ([]BenchmarkResult, error) {
+	modes := options.Modes
+	if len(modes) == 0 {
+		modes = BenchmarkModes
+	}
+
+	dir, err := os.MkdirTemp(options.Dir, "tp1-compare-")
+	if err != nil {
+		return nil, fmt.Errorf("erro ao criar diretório temporário: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	keys := lookupKeys(students, options.Lookups, options.Seed)
+	results := make([]BenchmarkResult, 0, len(options.BlockSizes)*len(modes))
+	for _, blockSize := range options.BlockSizes {
+		for _, mode := range modes {
+			result, err := runBenchmarkCase(ctx, filepath.Join(dir, "alunos.dat"), mode, blockSize, options.Encoding, students, keys)
+			if err != nil {
+				return results, err
+			}
+			results = append(results, result)
+			if options.OnResult != nil {
+				options.OnResult(result)
+			}
+		}
+	}
+	return results, nil
+}
+
+func lookupKeys(students []entity.Student, count int, seed uint64) []int64 {
+	if len(students) == 0 {
+		return nil
+	}
+
+	random := rand.New(rand.NewPCG(seed, uint64(count)))
+	keys := make([]int64, count)
+	for i := range keys {
+		keys[i] = students[random.IntN(len(students))].Matricula
+	}
+	return keys
+}
+
+func runBenchmarkCase(ctx context.Context, path string, mode storage.Mode, blockSize int, encoding storage.Encoding, students []entity.Student, keys []int64) (BenchmarkResult, error) {
+	result := BenchmarkResult{
+		Mode:      mode.String(),
+		BlockSize: blockSize,
+		Records:   len(students),
+		Lookups:   len(keys),
+	}
+	defer os.Remove(path + ".stats")
+	defer os.Remove(path)
+
+	handle, err := storage.Open(path, storage.Options{Mode: mode, BlockSize: blockSize, Encoding: encoding})
+	if err != nil {
+		result.Error = err.Error()
+		return result, nil
+	}
+	defer handle.Close()
+
+	start := time.Now()
+	if err := handle.WriteStudents(ctx, students); err != nil {
+		return result, fmt.Errorf("erro ao gravar no modo %s com blocos de %d bytes: %w", mode, blockSize, err)
+	}
+	result.WriteTime = time.Since(start)
+
+	stats, err := handle.GetStats(ctx)
+	if err != nil {
+		return result, fmt.Errorf("erro ao calcular estatísticas no modo %s com blocos de %d bytes: %w", mode, blockSize, err)
+	}
+	result.TotalBlocks = stats.TotalBlocks
+	result.EfficiencyRate = stats.EfficiencyRate
+	result.PartialBlocks = stats.PartialBlocks
+
+	start = time.Now()
+	if _, err := handle.GetAllStudents(ctx); err != nil {
+		return result, fmt.Errorf("erro ao ler no modo %s com blocos de %d bytes: %w", mode, blockSize, err)
+	}
+	result.ScanTime = time.Since(start)
+
+	var blockReads int64
+	lookupCtx := storage.WithProgress(ctx, func(progress storage.Progress) {
+		if progress.Done {
+			blockReads += progress.BlocksDone
+		}
+	})
+	for _, key := range keys {
+		if _, err := handle.FindStudentByMatricula(lookupCtx, key); err != nil {
+			return result, fmt.Errorf("erro ao buscar no modo %s com blocos de %d bytes: %w", mode, blockSize, err)
+		}
+	}
+	if len(keys) > 0 {
+		result.AvgBlockReads = float64(blockReads) / float64(len(keys))
+	}
+	return result, nil
+}
+
+func ParseBlockSizes(spec string) ([]int, error) {
+	blockSizes := make([]int, 0)
+	for part := range strings.SplitSeq(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		sizes, err := parseBlockSizeRange(part)
+		if err != nil {
+			return nil, err
+		}
+		blockSizes = append(blockSizes, sizes...)
+	}
+	if len(blockSizes) == 0 {
+		return nil, fmt.Errorf("nenhum tamanho de bloco informado")
+	}
+
+	slices.Sort(blockSizes)
+	return slices.Compact(blockSizes), nil
+}
+
+func parseBlockSizeRange(part string) ([]int, error) {
+	bounds, stepText, hasStep := strings.Cut(part, ":")
+	firstText, lastText, isRange := strings.Cut(bounds, "-")
+	if !isRange {
+		if hasStep {
+			return nil, fmt.Errorf("passo sem intervalo em %q", part)
+		}
+		lastText = firstText
+	}
+
+	first, err := parseBlockSize(firstText)
+	if err != nil {
+		return nil, err
+	}
+	last, err := parseBlockSize(lastText)
+	if err != nil {
+		return nil, err
+	}
+	if last < first {
+		return nil, fmt.Errorf("intervalo de tamanhos de bloco invertido: %q", part)
+	}
+
+	step := 0
+	if hasStep {
+		if step, err = parseBlockSize(stepText); err != nil {
+			return nil, err
+		}
+	}
+
+	sizes := make([]int, 0)
+	for size := first; size <= last; {
+		sizes = append(sizes, size)
+		if step > 0 {
+			size += step
+		} else {
+			size *= 2
+		}
+	}
+	return sizes, nil
+}
+
+func parseBlockSize(text string) (int, error) {
+	size, err := strconv.Atoi(strings.TrimSpace(text))
+	if err != nil || size <= 0 {
+		return 0, fmt.Errorf("tamanho de bloco inválido: %q", text)
+	}
+	return size, nil
+}
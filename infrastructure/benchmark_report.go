@@ -0,0 +1,241 @@
+package infrastructure
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"time"
+)
+
+type BenchmarkReporter struct {
+	results []BenchmarkResult
+	out     io.Writer
+}
+
+type benchmarkMetric struct {
+	label  string
+	better func(a, b BenchmarkResult) bool
+	value  func(result BenchmarkResult) string
+}
+
+var benchmarkMetrics = []benchmarkMetric{
+	{
+		label:  "Maior eficiência",
+		better: func(a, b BenchmarkResult) bool { return a.EfficiencyRate > b.EfficiencyRate },
+		value:  func(result BenchmarkResult) string { return fmt.Sprintf("%.2f%%", result.EfficiencyRate) },
+	},
+	{
+		label:  "Menos blocos",
+		better: func(a, b BenchmarkResult) bool { return a.TotalBlocks < b.TotalBlocks },
+		value:  func(result BenchmarkResult) string { return fmt.Sprintf("%d blocos", result.TotalBlocks) },
+	},
+	{
+		label:  "Menos leituras por busca",
+		better: func(a, b BenchmarkResult) bool { return a.AvgBlockReads < b.AvgBlockReads },
+		value:  func(result BenchmarkResult) string { return fmt.Sprintf("%.2f blocos", result.AvgBlockReads) },
+	},
+	{
+		label:  "Gravação mais rápida",
+		better: func(a, b BenchmarkResult) bool { return a.WriteTime < b.WriteTime },
+		value:  func(result BenchmarkResult) string { return formatMillis(result.WriteTime) },
+	},
+	{
+		label:  "Varredura mais rápida",
+		better: func(a, b BenchmarkResult) bool { return a.ScanTime < b.ScanTime },
+		value:  func(result BenchmarkResult) string { return formatMillis(result.ScanTime) },
+	},
+}
+
+func NewBenchmarkReporter(results []BenchmarkResult) *BenchmarkReporter {
+	return NewBenchmarkReporterTo(os.Stdout, results)
+}
+
+func NewBenchmarkReporterTo(out io.Writer, results []BenchmarkResult) *BenchmarkReporter {
+	return &BenchmarkReporter{
+		results: results,
+		out:     out,
+	}
+}
+
+func (br *BenchmarkReporter) PrintSummary() {
+	fmt.Fprintln(br.out, "\n=== COMPARAÇÃO DOS MODOS DE ARMAZENAMENTO ===")
+	if len(br.results) == 0 {
+		fmt.Fprintln(br.out, "Nenhuma execução realizada.")
+		return
+	}
+	fmt.Fprintf(br.out, "%d registros, %d buscas por matrícula em cada execução\n\n", br.results[0].Records, br.results[0].Lookups)
+
+	fmt.Fprintf(br.out, "%-10s %7s %9s %11s %9s %15s %12s %12s\n",
+		"Modo", "Bloco", "Blocos", "Eficiência", "Parciais", "Leituras/busca", "Gravação", "Varredura")
+	failed := make([]BenchmarkResult, 0)
+	for _, result := range br.results {
+		if result.Error != "" {
+			failed = append(failed, result)
+			continue
+		}
+		fmt.Fprintf(br.out, "%-10s %7d %9d %10.2f%% %9d %15.2f %12s %12s\n",
+			result.Mode,
+			result.BlockSize,
+			result.TotalBlocks,
+			result.EfficiencyRate,
+			result.PartialBlocks,
+			result.AvgBlockReads,
+			formatMillis(result.WriteTime),
+			formatMillis(result.ScanTime))
+	}
+
+	if len(failed) > 0 {
+		fmt.Fprintln(br.out, "\nExecuções não realizadas:")
+		for _, result := range failed {
+			fmt.Fprintf(br.out, "Modo %s, bloco de %d bytes: %s\n", result.Mode, result.BlockSize, result.Error)
+		}
+	}
+
+	br.printBestResults()
+	br.printMostEfficientModes()
+}
+
+func (br *BenchmarkReporter) printBestResults() {
+	fmt.Fprintln(br.out, "\n=== MELHORES RESULTADOS ===")
+	for _, metric := range benchmarkMetrics {
+		best, found := br.best(metric, 0)
+		if !found {
+			continue
+		}
+		fmt.Fprintf(br.out, "%-26s modo %s, bloco de %d bytes (%s)\n", metric.label+":", best.Mode, best.BlockSize, metric.value(best))
+	}
+}
+
+func (br *BenchmarkReporter) printMostEfficientModes() {
+	fmt.Fprintln(br.out, "\n=== MODO MAIS EFICIENTE POR TAMANHO DE BLOCO ===")
+	efficiency := benchmarkMetrics[0]
+	for _, blockSize := range br.blockSizes() {
+		best, found := br.best(efficiency, blockSize)
+		if !found {
+			fmt.Fprintf(br.out, "Bloco de %d bytes: nenhum modo aceita este tamanho\n", blockSize)
+			continue
+		}
+		fmt.Fprintf(br.out, "Bloco de %d bytes: %s (%s)\n", blockSize, best.Mode, efficiency.value(best))
+	}
+}
+
+func (br *BenchmarkReporter) best(metric benchmarkMetric, blockSize int) (BenchmarkResult, bool) {
+	var best BenchmarkResult
+	found := false
+	for _, result := range br.results {
+		if result.Error != "" || (blockSize > 0 && result.BlockSize != blockSize) {
+			continue
+		}
+		if !found || metric.better(result, best) {
+			best = result
+			found = true
+		}
+	}
+	return best, found
+}
+
+func (br *BenchmarkReporter) blockSizes() []int {
+	blockSizes := make([]int, 0)
+	for _, result := range br.results {
+		if len(blockSizes) == 0 || blockSizes[len(blockSizes)-1] != result.BlockSize {
+			blockSizes = append(blockSizes, result.BlockSize)
+		}
+	}
+	return blockSizes
+}
+
+func (br *BenchmarkReporter) Export(format ReportFormat) error {
+	out := &errorWriter{out: br.out}
+	reporter := NewBenchmarkReporterTo(out, br.results)
+
+	var err error
+	switch format {
+	case FormatText:
+		reporter.PrintSummary()
+	case FormatJSON:
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(br.results)
+	case FormatCSV:
+		err = reporter.exportCSV()
+	case FormatMarkdown:
+		reporter.exportMarkdown()
+	default:
+		return fmt.Errorf("formato de relatório desconhecido: %q", format)
+	}
+
+	if out.err != nil {
+		err = out.err
+	}
+	if err != nil {
+		return fmt.Errorf("erro ao gravar comparação: %w", err)
+	}
+	return nil
+}
+
+func (br *BenchmarkReporter) exportCSV() error {
+	writer := csv.NewWriter(br.out)
+	writer.Write([]string{
+		"modo",
+		"bloco",
+		"registros",
+		"blocos",
+		"eficiencia",
+		"blocos_parciais",
+		"buscas",
+		"leituras_por_busca",
+		"gravacao_ms",
+		"varredura_ms",
+		"erro",
+	})
+	for _, result := range br.results {
+		writer.Write([]string{
+			result.Mode,
+			strconv.Itoa(result.BlockSize),
+			strconv.Itoa(result.Records),
+			strconv.FormatInt(result.TotalBlocks, 10),
+			strconv.FormatFloat(result.EfficiencyRate, 'f', 2, 64),
+			strconv.FormatInt(result.PartialBlocks, 10),
+			strconv.Itoa(result.Lookups),
+			strconv.FormatFloat(result.AvgBlockReads, 'f', 2, 64),
+			strconv.FormatFloat(millis(result.WriteTime), 'f', 3, 64),
+			strconv.FormatFloat(millis(result.ScanTime), 'f', 3, 64),
+			result.Error,
+		})
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+func (br *BenchmarkReporter) exportMarkdown() {
+	fmt.Fprintln(br.out, "## Comparação dos Modos de Armazenamento")
+	fmt.Fprintln(br.out)
+	fmt.Fprintln(br.out, "| Modo | Bloco | Blocos | Eficiência | Parciais | Leituras/busca | Gravação | Varredura |")
+	fmt.Fprintln(br.out, "|---|---:|---:|---:|---:|---:|---:|---:|")
+	for _, result := range br.results {
+		if result.Error != "" {
+			fmt.Fprintf(br.out, "| %s | %d | %s | | | | | |\n", result.Mode, result.BlockSize, result.Error)
+			continue
+		}
+		fmt.Fprintf(br.out, "| %s | %d | %d | %.2f%% | %d | %.2f | %s | %s |\n",
+			result.Mode,
+			result.BlockSize,
+			result.TotalBlocks,
+			result.EfficiencyRate,
+			result.PartialBlocks,
+			result.AvgBlockReads,
+			formatMillis(result.WriteTime),
+			formatMillis(result.ScanTime))
+	}
+}
+
+func millis(duration time.Duration) float64 {
+	return float64(duration) / float64(time.Millisecond)
+}
+
+func formatMillis(duration time.Duration) string {
+	return fmt.Sprintf("%.1f ms", millis(duration))
+}
@@ -0,0 +1,177 @@
+package infrastructure
+
+import (
+	"aeds2-tp1/domain"
+	"aeds2-tp1/entity"
+	"aeds2-tp1/storage"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseBlockSizes(t *testing.T) {
+	cases := []struct {
+		spec string
+		want []int
+	}{
+		{"512", []int{512}},
+		{"4096, 512,1024,512", []int{512, 1024, 4096}},
+		{"256-4096", []int{256, 512, 1024, 2048, 4096}},
+		{"200-500:100", []int{200, 300, 400, 500}},
+		{"300-1000", []int{300, 600}},
+		{"256-1024,300", []int{256, 300, 512, 1024}},
+	}
+	for _, tc := range cases {
+		got, err := ParseBlockSizes(tc.spec)
+		if err != nil || !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("ParseBlockSizes(%q) = %v (erro %v), esperado %v", tc.spec, got, err, tc.want)
+		}
+	}
+
+	for _, spec := range []string{"", " , ", "abc", "0", "-512", "1024-512", "512:64", "256-1024:0", "256-1024:x"} {
+		if sizes, err := ParseBlockSizes(spec); err == nil {
+			t.Errorf("ParseBlockSizes(%q) aceitou a especificação inválida: %v", spec, sizes)
+		}
+	}
+}
+
+func TestRunBenchmark(t *testing.T) {
+	ctx := context.Background()
+	students, err := domain.NewStudentGeneratorWithSeed(41).Generate(200)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	reported := make([]BenchmarkResult, 0)
+	results, err := RunBenchmark(ctx, students, BenchmarkOptions{
+		BlockSizes: []int{100, 512, 2048},
+		Lookups:    50,
+		Dir:        dir,
+		Seed:       41,
+		OnResult:   func(result BenchmarkResult) { reported = append(reported, result) },
+	})
+	if err != nil {
+		t.Fatalf("RunBenchmark: %v", err)
+	}
+	if len(results) != 9 || !reflect.DeepEqual(reported, results) {
+		t.Fatalf("%d resultados e %d notificados, esperado 9", len(results), len(reported))
+	}
+
+	for _, result := range results {
+		mode, err := storage.ParseMode(result.Mode)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.BlockSize == 100 {
+			if result.Error == "" || result.TotalBlocks != 0 {
+				t.Fatalf("modo %s aceitou blocos de 100 bytes: %+v", mode, result)
+			}
+			continue
+		}
+		if result.Error != "" {
+			t.Fatalf("modo %s, bloco de %d bytes: %s", mode, result.BlockSize, result.Error)
+		}
+
+		want := benchmarkStats(t, mode, result.BlockSize, students)
+		if result.TotalBlocks != want.TotalBlocks || result.EfficiencyRate != want.EfficiencyRate || result.PartialBlocks != want.PartialBlocks {
+			t.Fatalf("modo %s, bloco de %d bytes: %d blocos, %.2f%%, %d parciais, esperado %d, %.2f%%, %d",
+				mode, result.BlockSize, result.TotalBlocks, result.EfficiencyRate, result.PartialBlocks, want.TotalBlocks, want.EfficiencyRate, want.PartialBlocks)
+		}
+		if result.Records != len(students) || result.Lookups != 50 {
+			t.Fatalf("resultado com %d registros e %d buscas", result.Records, result.Lookups)
+		}
+		if result.AvgBlockReads < 1 || result.AvgBlockReads > float64(result.TotalBlocks) {
+			t.Fatalf("modo %s, bloco de %d bytes: %.2f leituras por busca em %d blocos", mode, result.BlockSize, result.AvgBlockReads, result.TotalBlocks)
+		}
+	}
+
+	if entries, err := os.ReadDir(dir); err != nil || len(entries) != 0 {
+		t.Fatalf("RunBenchmark deixou %d arquivos no diretório (erro %v)", len(entries), err)
+	}
+}
+
+func TestFixedModeLookupReadsMatchKeyPositions(t *testing.T) {
+	students, err := domain.NewStudentGeneratorWithSeed(42).Generate(100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	results, err := RunBenchmark(context.Background(), students, BenchmarkOptions{
+		BlockSizes: []int{512},
+		Modes:      []storage.Mode{storage.FixedMode},
+		Lookups:    30,
+		Dir:        t.TempDir(),
+		Seed:       42,
+	})
+	if err != nil {
+		t.Fatalf("RunBenchmark: %v", err)
+	}
+
+	recordsPerBlock := benchmarkStats(t, storage.FixedMode, 512, students).BlockStatsList[0].RecordsCount
+	positions := make(map[int64]int, len(students))
+	for i, student := range students {
+		positions[student.Matricula] = i
+	}
+	var blockReads int
+	for _, key := range lookupKeys(students, 30, 42) {
+		blockReads += positions[key]/recordsPerBlock + 1
+	}
+	if want := float64(blockReads) / 30; results[0].AvgBlockReads != want {
+		t.Fatalf("%.2f leituras por busca, esperado %.2f", results[0].AvgBlockReads, want)
+	}
+}
+
+func benchmarkStats(t *testing.T, mode storage.Mode, blockSize int, students []entity.Student) storage.StorageStats {
+	t.Helper()
+	ctx := context.Background()
+	handle, err := storage.OpenDevice(storage.NewMemoryDevice(blockSize), storage.Options{Mode: mode})
+	if err != nil {
+		t.Fatalf("OpenDevice: %v", err)
+	}
+	defer handle.Close()
+	if err := handle.WriteStudents(ctx, students); err != nil {
+		t.Fatalf("WriteStudents: %v", err)
+	}
+	stats, err := handle.GetBlockStats(ctx)
+	if err != nil {
+		t.Fatalf("GetBlockStats: %v", err)
+	}
+	return stats
+}
+
+func TestBenchmarkReporterExport(t *testing.T) {
+	results := []BenchmarkResult{
+		{Mode: "fixo", BlockSize: 100, Records: 10, Lookups: 5, Error: "bloco inválido"},
+		{Mode: "fixo", BlockSize: 512, Records: 10, Lookups: 5, TotalBlocks: 4, EfficiencyRate: 81.54, PartialBlocks: 1, AvgBlockReads: 2.5},
+		{Mode: "variável", BlockSize: 512, Records: 10, Lookups: 5, TotalBlocks: 3, EfficiencyRate: 90.1, PartialBlocks: 1, AvgBlockReads: 1.8},
+	}
+
+	var out bytes.Buffer
+	if err := NewBenchmarkReporterTo(&out, results).Export(FormatCSV); err != nil {
+		t.Fatalf("Export(csv): %v", err)
+	}
+	rows, err := csv.NewReader(&out).ReadAll()
+	if err != nil || len(rows) != 4 {
+		t.Fatalf("CSV com %d linhas (erro %v), esperado 4", len(rows), err)
+	}
+	if want := []string{"variável", "512", "10", "3", "90.10", "1", "5", "1.80", "0.000", "0.000", ""}; !reflect.DeepEqual(rows[3], want) {
+		t.Fatalf("linha do CSV %v, esperado %v", rows[3], want)
+	}
+
+	out.Reset()
+	NewBenchmarkReporterTo(&out, results).PrintSummary()
+	for _, want := range []string{
+		"Modo fixo, bloco de 100 bytes: bloco inválido",
+		"Maior eficiência:          modo variável, bloco de 512 bytes (90.10%)",
+		"Menos leituras por busca:  modo variável, bloco de 512 bytes (1.80 blocos)",
+		"Bloco de 100 bytes: nenhum modo aceita este tamanho",
+		"Bloco de 512 bytes: variável (90.10%)",
+	} {
+		if !strings.Contains(out.String(), want+"\n") {
+			t.Errorf("resumo sem %q:\n%s", want, out.String())
+		}
+	}
+}
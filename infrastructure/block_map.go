@@ -0,0 +1,228 @@
+package infrastructure
+
+import (
+	"aeds2-tp1/storage"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+const (
+	defaultTerminalWidth = 80
+	maxBlockRuns         = 40
+	initialRunTolerance  = 5.0
+	maxRunTolerance      = 20.0
+	maxHeatmapRows       = 50
+	emptyBlockCell       = '·'
+)
+
+type colorMode int
+
+const (
+	noColor colorMode = iota
+	color256
+	trueColor
+)
+
+var heatmapLevels = []rune{'░', '▒', '▓', '█'}
+
+type blockRun struct {
+	first        int64
+	last         int64
+	occupancy    float64
+	lowest       float64
+	highest      float64
+	bytesUsed    int64
+	records      int64
+	continuation int
+}
+
+func (br *blockRun) add(blockStat storage.BlockStats, count int) {
+	if count == 0 {
+		*br = blockRun{first: blockStat.BlockNumber, lowest: blockStat.OccupancyRate, highest: blockStat.OccupancyRate}
+	}
+	br.last = blockStat.BlockNumber
+	br.occupancy = (br.occupancy*float64(count) + blockStat.OccupancyRate) / float64(count+1)
+	br.lowest = min(br.lowest, blockStat.OccupancyRate)
+	br.highest = max(br.highest, blockStat.OccupancyRate)
+	br.bytesUsed += int64(blockStat.BytesUsed)
+	br.records += int64(blockStat.RecordsCount)
+	if blockStat.EndsWithContinuation {
+		br.continuation++
+	}
+}
+
+func terminalWidth(file *os.File) int {
+	if width, _, err := term.GetSize(int(file.Fd())); err == nil && width > 0 {
+		return width
+	}
+	if width, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && width > 0 {
+		return width
+	}
+	return defaultTerminalWidth
+}
+
+func detectColorMode(file *os.File) colorMode {
+	if !term.IsTerminal(int(file.Fd())) || os.Getenv("NO_COLOR") != "" {
+		return noColor
+	}
+
+	colorTerm := os.Getenv("COLORTERM")
+	if colorTerm == "truecolor" || colorTerm == "24bit" {
+		return trueColor
+	}
+	if strings.Contains(os.Getenv("TERM"), "256color") {
+		return color256
+	}
+	return noColor
+}
+
+func (r *Reporter) Zoom(first, last int64) *Reporter {
+	blockStatsList := r.stats.BlockStatsList
+	first = max(first, 0)
+	last = min(last, int64(len(blockStatsList))-1)
+
+	zoomed := *r
+	if first > last {
+		zoomed.stats.BlockStatsList = nil
+	} else {
+		zoomed.stats.BlockStatsList = blockStatsList[first : last+1]
+	}
+	return &zoomed
+}
+
+func (r *Reporter) PrintHeatmap() {
+	fmt.Fprintln(r.out, "\n=== MAPA DE CALOR DOS BLOCOS ===")
+	blockStatsList := r.stats.BlockStatsList
+	if len(blockStatsList) == 0 {
+		fmt.Fprintln(r.out, "Nenhum bloco no intervalo.")
+		return
+	}
+
+	lastBlock := blockStatsList[len(blockStatsList)-1].BlockNumber + 1
+	labelWidth := len(fmt.Sprint(lastBlock))
+	columns := max(r.width-labelWidth-3, 10)
+	cells := groupFixedWindows(blockStatsList, columns*maxHeatmapRows)
+	if blocksPerCell := (len(blockStatsList) + len(cells) - 1) / len(cells); blocksPerCell > 1 {
+		fmt.Fprintf(r.out, "Cada caractere representa até %d blocos (média de ocupação); amplie um intervalo para ver bloco a bloco\n", blocksPerCell)
+	}
+
+	var line strings.Builder
+	for rowStart := 0; rowStart < len(cells); rowStart += columns {
+		rowEnd := min(rowStart+columns, len(cells))
+		line.Reset()
+		fmt.Fprintf(&line, "%*d │", labelWidth, cells[rowStart].first+1)
+		for _, cell := range cells[rowStart:rowEnd] {
+			r.writeHeatmapCell(&line, cell)
+		}
+		if r.colors != noColor {
+			line.WriteString("\033[0m")
+		}
+		fmt.Fprintln(r.out, line.String())
+	}
+
+	fmt.Fprintf(r.out, "%*s  %c vazio  %c até 25%%  %c até 50%%  %c até 75%%  %c acima de 75%%\n",
+		labelWidth, "", emptyBlockCell, heatmapLevels[0], heatmapLevels[1], heatmapLevels[2], heatmapLevels[3])
+}
+
+func (r *Reporter) writeHeatmapCell(line *strings.Builder, cell blockRun) {
+	if cell.bytesUsed == 0 {
+		if r.colors != noColor {
+			line.WriteString("\033[0m")
+		}
+		line.WriteRune(emptyBlockCell)
+		return
+	}
+
+	level := min(int(math.Ceil(cell.occupancy/25))-1, len(heatmapLevels)-1)
+	level = max(level, 0)
+
+	rgb := occupancyRGB(cell.occupancy)
+	switch r.colors {
+	case trueColor:
+		fmt.Fprintf(line, "\033[38;2;%d;%d;%dm", rgb[0], rgb[1], rgb[2])
+	case color256:
+		fmt.Fprintf(line, "\033[38;5;%dm", 16+36*colorCubeIndex(rgb[0])+6*colorCubeIndex(rgb[1])+colorCubeIndex(rgb[2]))
+	}
+	line.WriteRune(heatmapLevels[level])
+}
+
+func colorCubeIndex(component int) int {
+	return (component*5 + 127) / 255
+}
+
+func (r *Reporter) PrintBlockRuns() {
+	fmt.Fprintln(r.out, "\n=== FAIXAS DE OCUPAÇÃO DOS BLOCOS ===")
+	blockStatsList := r.stats.BlockStatsList
+	if len(blockStatsList) == 0 {
+		fmt.Fprintln(r.out, "Nenhum bloco no intervalo.")
+		return
+	}
+
+	tolerance := initialRunTolerance
+	runs := groupSimilarBlocks(blockStatsList, tolerance)
+	for len(runs) > maxBlockRuns && tolerance < maxRunTolerance {
+		tolerance *= 2
+		runs = groupSimilarBlocks(blockStatsList, tolerance)
+	}
+
+	if len(runs) > maxBlockRuns {
+		runs = groupFixedWindows(blockStatsList, maxBlockRuns)
+		fmt.Fprintf(r.out, "Ocupação muito variável; blocos agrupados em %d intervalos de tamanho igual\n", len(runs))
+	} else {
+		fmt.Fprintf(r.out, "Blocos agrupados com variação de ocupação de até %.0f pontos percentuais\n", tolerance)
+	}
+
+	for _, run := range runs {
+		blocks := fmt.Sprintf("Bloco %d", run.first+1)
+		if run.last > run.first {
+			blocks = fmt.Sprintf("Blocos %d–%d", run.first+1, run.last+1)
+		}
+
+		line := fmt.Sprintf("%s: %.2f%%", blocks, run.occupancy)
+		if run.highest-run.lowest >= 0.01 {
+			line += fmt.Sprintf(" (mín %.2f%%, máx %.2f%%)", run.lowest, run.highest)
+		}
+		line += fmt.Sprintf(" - %d registros", run.records)
+		if run.continuation > 0 {
+			line += fmt.Sprintf(", %d com registro continuando no bloco seguinte", run.continuation)
+		}
+		fmt.Fprintln(r.out, line)
+	}
+}
+
+func groupSimilarBlocks(blockStatsList []storage.BlockStats, tolerance float64) []blockRun {
+	runs := make([]blockRun, 0)
+	var current blockRun
+	count := 0
+	for _, blockStat := range blockStatsList {
+		occupancy := blockStat.OccupancyRate
+		if count > 0 && max(current.highest, occupancy)-min(current.lowest, occupancy) > tolerance {
+			runs = append(runs, current)
+			count = 0
+		}
+		current.add(blockStat, count)
+		count++
+	}
+	if count > 0 {
+		runs = append(runs, current)
+	}
+	return runs
+}
+
+func groupFixedWindows(blockStatsList []storage.BlockStats, maxGroups int) []blockRun {
+	groupSize := (len(blockStatsList) + maxGroups - 1) / maxGroups
+	groups := make([]blockRun, 0, (len(blockStatsList)+groupSize-1)/groupSize)
+	for start := 0; start < len(blockStatsList); start += groupSize {
+		var group blockRun
+		for i, blockStat := range blockStatsList[start:min(start+groupSize, len(blockStatsList))] {
+			group.add(blockStat, i)
+		}
+		groups = append(groups, group)
+	}
+	return groups
+}
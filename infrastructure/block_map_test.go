@@ -0,0 +1,149 @@
+package infrastructure
+
+import (
+	"aeds2-tp1/storage"
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func blocksWithOccupancy(occupancies ...float64) []storage.BlockStats {
+	blockStatsList := make([]storage.BlockStats, len(occupancies))
+	for i, occupancy := range occupancies {
+		blockStatsList[i] = storage.BlockStats{
+			BlockNumber:   int64(i),
+			BytesUsed:     int(occupancy),
+			BytesTotal:    100,
+			OccupancyRate: occupancy,
+			RecordsCount:  1,
+		}
+	}
+	return blockStatsList
+}
+
+func repeatOccupancy(occupancy float64, count int) []float64 {
+	occupancies := make([]float64, count)
+	for i := range occupancies {
+		occupancies[i] = occupancy
+	}
+	return occupancies
+}
+
+func TestGroupSimilarBlocks(t *testing.T) {
+	occupancies := append(repeatOccupancy(98, 840), 96, 99, 50, 52, 10)
+	runs := groupSimilarBlocks(blocksWithOccupancy(occupancies...), 5)
+	want := []blockRun{
+		{first: 0, last: 841, lowest: 96, highest: 99, records: 842},
+		{first: 842, last: 843, lowest: 50, highest: 52, records: 2},
+		{first: 844, last: 844, lowest: 10, highest: 10, records: 1},
+	}
+	if len(runs) != len(want) {
+		t.Fatalf("%d faixas, esperado %d: %+v", len(runs), len(want), runs)
+	}
+	for i, run := range runs {
+		if run.first != want[i].first || run.last != want[i].last || run.lowest != want[i].lowest || run.highest != want[i].highest || run.records != want[i].records {
+			t.Fatalf("faixa %d: %+v, esperado %+v", i, run, want[i])
+		}
+	}
+	if occupancy := runs[1].occupancy; occupancy != 51 {
+		t.Fatalf("ocupação média da faixa 2: %.2f, esperado 51", occupancy)
+	}
+}
+
+func TestGroupFixedWindows(t *testing.T) {
+	groups := groupFixedWindows(blocksWithOccupancy(repeatOccupancy(40, 101)...), 10)
+	if len(groups) != 10 {
+		t.Fatalf("%d grupos, esperado 10", len(groups))
+	}
+	var blocks int64
+	for i, group := range groups {
+		if i > 0 && group.first != groups[i-1].last+1 {
+			t.Fatalf("grupo %d começa no bloco %d, depois do %d", i, group.first, groups[i-1].last)
+		}
+		blocks += group.last - group.first + 1
+	}
+	if blocks != 101 || groups[0].last != 10 || groups[9].last != 100 {
+		t.Fatalf("grupos cobrem %d blocos, primeiro termina em %d e último em %d", blocks, groups[0].last, groups[9].last)
+	}
+}
+
+func TestPrintBlockRunsAggregatesLongFiles(t *testing.T) {
+	stats := storage.StorageStats{BlockStatsList: blocksWithOccupancy(append(repeatOccupancy(98, 840), 30)...)}
+	var out bytes.Buffer
+	NewReporterTo(&out, stats).PrintBlockRuns()
+	for _, want := range []string{"Blocos 1–840: 98.00% - 840 registros\n", "Bloco 841: 30.00% - 1 registros\n"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("faixas sem %q:\n%s", want, out.String())
+		}
+	}
+
+	alternating := make([]float64, 200)
+	for i := range alternating {
+		alternating[i] = float64(i%2) * 100
+	}
+	out.Reset()
+	NewReporterTo(&out, storage.StorageStats{BlockStatsList: blocksWithOccupancy(alternating...)}).PrintBlockRuns()
+	if lines := strings.Count(out.String(), "\nBloco"); lines != maxBlockRuns {
+		t.Fatalf("ocupação alternada gerou %d faixas, esperado %d intervalos fixos:\n%s", lines, maxBlockRuns, out.String())
+	}
+}
+
+func TestPrintHeatmapFitsTerminalWidth(t *testing.T) {
+	stats := storage.StorageStats{BlockStatsList: blocksWithOccupancy(append(repeatOccupancy(0, 5), repeatOccupancy(100, 20000)...)...)}
+	var out bytes.Buffer
+	reporter := NewReporterTo(&out, stats)
+	reporter.width = 60
+	reporter.PrintHeatmap()
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if !strings.Contains(lines[1], "até 8 blocos") {
+		t.Fatalf("mapa de calor não informa a agregação: %q", lines[1])
+	}
+	rows := lines[2 : len(lines)-1]
+	if len(rows) > maxHeatmapRows {
+		t.Fatalf("mapa de calor com %d linhas, limite %d", len(rows), maxHeatmapRows)
+	}
+	for _, row := range rows {
+		if width := len([]rune(row)); width > reporter.width {
+			t.Fatalf("linha com %d colunas em um terminal de %d: %q", width, reporter.width, row)
+		}
+	}
+	if first := []rune(strings.SplitN(rows[0], "│", 2)[1]); first[0] != heatmapLevels[1] {
+		t.Fatalf("primeira célula %q, esperado %q para a média de 5 blocos vazios e 3 cheios", first[0], heatmapLevels[1])
+	}
+
+	out.Reset()
+	reporter.Zoom(0, 4).PrintHeatmap()
+	if !strings.Contains(out.String(), "1 │·····\n") {
+		t.Fatalf("ampliação dos blocos vazios:\n%s", out.String())
+	}
+}
+
+func TestZoomClampsRange(t *testing.T) {
+	reporter := NewReporterTo(&bytes.Buffer{}, storage.StorageStats{BlockStatsList: blocksWithOccupancy(repeatOccupancy(50, 10)...)})
+	cases := []struct {
+		first, last int64
+		want        int
+	}{
+		{2, 4, 3},
+		{-5, 3, 4},
+		{8, 100, 2},
+		{7, 3, 0},
+	}
+	for _, tc := range cases {
+		if got := len(reporter.Zoom(tc.first, tc.last).stats.BlockStatsList); got != tc.want {
+			t.Errorf("Zoom(%d, %d) com %d blocos, esperado %d", tc.first, tc.last, got, tc.want)
+		}
+	}
+	if len(reporter.stats.BlockStatsList) != 10 {
+		t.Error("Zoom alterou o relatório original")
+	}
+}
+
+func TestColorCubeIndexEndpoints(t *testing.T) {
+	for component, want := range map[int]int{0: 0, 25: 0, 26: 1, 128: 3, 255: 5} {
+		if got := colorCubeIndex(component); got != want {
+			t.Errorf("colorCubeIndex(%d) = %d, esperado %d", component, got, want)
+		}
+	}
+}
@@ -0,0 +1,200 @@
+package infrastructure
+
+import (
+	"aeds2-tp1/storage"
+	"fmt"
+	"math"
+)
+
+const (
+	fragmentHeaderBytes = 5
+	deviationThreshold  = 5.0
+)
+
+type costModel struct {
+	formula        string
+	blockingFactor float64
+	blocks         int64
+	freePerBlock   float64
+	avgReads       float64
+	worstReads     int64
+}
+
+type costComparison struct {
+	label     string
+	predicted float64
+	measured  float64
+	decimals  int
+}
+
+func predictCost(stats storage.StorageStats) (costModel, bool) {
+	n := stats.TotalRecords
+	blockSize := stats.BlockSize
+	if n == 0 || blockSize <= 0 || len(stats.RecordSizes) == 0 {
+		return costModel{}, false
+	}
+
+	avgSize := averageRecordSize(stats.RecordSizes)
+	var model costModel
+	usedBytes := float64(n) * avgSize
+	switch stats.Mode {
+	case storage.FixedMode:
+		recordSize := stats.RecordSizes[0].Size
+		bfr := blockSize / recordSize
+		if bfr == 0 {
+			return costModel{}, false
+		}
+		model.formula = fmt.Sprintf("bfr = ⌊B/R⌋ = ⌊%d/%d⌋ = %d", blockSize, recordSize, bfr)
+		model.blockingFactor = float64(bfr)
+		model.blocks = (n + int64(bfr) - 1) / int64(bfr)
+	case storage.VariableMode:
+		bfr := unspannedBlockingFactor(stats.RecordSizes, blockSize)
+		if bfr == 0 {
+			return costModel{}, false
+		}
+		model.formula = fmt.Sprintf("bfr = %.2f registros por bloco, pela distribuição de tamanhos (R̄ = %.2f bytes) sem espalhamento", bfr, avgSize)
+		model.blockingFactor = bfr
+		model.blocks = int64(math.Ceil(float64(n) / bfr))
+	case storage.FragmentedMode:
+		if blockSize <= fragmentHeaderBytes {
+			return costModel{}, false
+		}
+		stored := float64(n) * (avgSize + fragmentHeaderBytes)
+		noSplit := min(float64(fragmentHeaderBytes+1)/(avgSize+fragmentHeaderBytes), 1)
+		tailWaste := float64(fragmentHeaderBytes*(fragmentHeaderBytes+1)/2) / (avgSize + fragmentHeaderBytes)
+		capacity := float64(blockSize) - (1-noSplit)*fragmentHeaderBytes - tailWaste
+		model.formula = fmt.Sprintf("bfr ≈ (B - h)/(R̄ + h) = (%d - %d)/(%.2f + %d) ≈ %.2f, com h = %d bytes de cabeçalho por fragmento", blockSize, fragmentHeaderBytes, avgSize, fragmentHeaderBytes,
+			capacity/(avgSize+fragmentHeaderBytes), fragmentHeaderBytes)
+		model.blockingFactor = capacity / (avgSize + fragmentHeaderBytes)
+		model.blocks = max(int64(math.Ceil(stored/capacity)), 1)
+		usedBytes = stored + (1-noSplit)*fragmentHeaderBytes*float64(model.blocks-1)
+	default:
+		return costModel{}, false
+	}
+
+	model.freePerBlock = max(float64(model.blocks)*float64(blockSize)-usedBytes, 0) / float64(model.blocks)
+	model.avgReads = averageSequentialReads(n, model.blockingFactor)
+	model.worstReads = model.blocks
+	return model, true
+}
+
+func averageRecordSize(recordSizes []storage.RecordSizeCount) float64 {
+	var total, count float64
+	for _, recordSize := range recordSizes {
+		total += float64(recordSize.Size) * float64(recordSize.Count)
+		count += float64(recordSize.Count)
+	}
+	if count == 0 {
+		return 0
+	}
+	return total / count
+}
+
+func unspannedBlockingFactor(recordSizes []storage.RecordSizeCount, blockSize int) float64 {
+	var totalCount float64
+	for _, recordSize := range recordSizes {
+		totalCount += float64(recordSize.Count)
+	}
+
+	records := make([]float64, blockSize+1)
+	for free := 1; free <= blockSize; free++ {
+		for _, recordSize := range recordSizes {
+			if recordSize.Size <= free {
+				records[free] += float64(recordSize.Count) / totalCount * (1 + records[free-recordSize.Size])
+			}
+		}
+	}
+
+	var perBlock float64
+	for _, recordSize := range recordSizes {
+		if recordSize.Size <= blockSize {
+			perBlock += float64(recordSize.Count) / totalCount * (1 + records[blockSize-recordSize.Size])
+		}
+	}
+	return perBlock
+}
+
+func averageSequentialReads(n int64, blockingFactor float64) float64 {
+	var total float64
+	for i := int64(1); i <= n; i++ {
+		total += math.Ceil(float64(i)/blockingFactor - 1e-9)
+	}
+	return total / float64(n)
+}
+
+func (r *Reporter) measuredReads() (float64, int64) {
+	var total float64
+	var worst int64
+	for _, blockStat := range r.stats.BlockStatsList {
+		if blockStat.RecordsCount == 0 {
+			continue
+		}
+		total += float64(blockStat.BlockNumber+1) * float64(blockStat.RecordsCount)
+		worst = blockStat.BlockNumber + 1
+	}
+	if r.stats.TotalRecords == 0 {
+		return 0, 0
+	}
+	return total / float64(r.stats.TotalRecords), worst
+}
+
+func (r *Reporter) costComparisons(model costModel) []costComparison {
+	avgReads, worstReads := r.measuredReads()
+	freePerBlock := 0.0
+	recordsPerBlock := 0.0
+	if r.stats.TotalBlocks > 0 {
+		freePerBlock = float64(r.stats.TotalBytesTotal-r.stats.TotalBytesUsed) / float64(r.stats.TotalBlocks)
+		recordsPerBlock = float64(r.stats.TotalRecords) / float64(r.stats.TotalBlocks)
+	}
+
+	return []costComparison{
+		{"Blocos", float64(model.blocks), float64(r.stats.TotalBlocks), 0},
+		{"Registros por bloco", model.blockingFactor, recordsPerBlock, 2},
+		{"Bytes livres por bloco", model.freePerBlock, freePerBlock, 2},
+		{"Leituras por busca (média)", model.avgReads, avgReads, 2},
+		{"Leituras por busca (pior caso)", float64(model.worstReads), float64(worstReads), 0},
+		{"Leituras por busca sem sucesso", float64(model.blocks), float64(r.stats.TotalBlocks), 0},
+	}
+}
+
+func (comparison costComparison) deviation() float64 {
+	if comparison.predicted == 0 {
+		if comparison.measured == 0 {
+			return 0
+		}
+		return math.Inf(1)
+	}
+	return (comparison.measured - comparison.predicted) / comparison.predicted * 100
+}
+
+func (comparison costComparison) format(value float64) string {
+	return fmt.Sprintf("%.*f", comparison.decimals, value)
+}
+
+func (r *Reporter) PrintCostModel() {
+	fmt.Fprintln(r.out, "\n=== MODELO ANALÍTICO DE CUSTO ===")
+	model, ok := predictCost(r.stats)
+	if !ok {
+		fmt.Fprintln(r.out, "Sem registros para estimar.")
+		return
+	}
+
+	fmt.Fprintf(r.out, "Modo %s, bloco de %d bytes, %d registros\n", r.stats.Mode, r.stats.BlockSize, r.stats.TotalRecords)
+	fmt.Fprintln(r.out, model.formula)
+	fmt.Fprint(r.out, "Blocos = ⌈n/bfr⌉; busca sequencial até o bloco onde o registro termina\n\n")
+
+	fmt.Fprintf(r.out, "%-32s %12s %12s %10s\n", "", "Previsto", "Medido", "Desvio")
+	for _, comparison := range r.costComparisons(model) {
+		deviation := comparison.deviation()
+		line := fmt.Sprintf("%-32s %12s %12s %9.2f%%",
+			comparison.label, comparison.format(comparison.predicted), comparison.format(comparison.measured), deviation)
+		if math.Abs(deviation) > deviationThreshold {
+			line += " ◀"
+			if r.colors != noColor {
+				line = "\033[1;33m" + line + "\033[0m"
+			}
+		}
+		fmt.Fprintln(r.out, line)
+	}
+	fmt.Fprintf(r.out, "◀ desvio acima de %.0f%%\n", deviationThreshold)
+}
@@ -0,0 +1,109 @@
+package infrastructure
+
+import (
+	"aeds2-tp1/storage"
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestFixedCostModelMatchesMeasuredStats(t *testing.T) {
+	for _, blockSize := range []int{512, 1000, 4096} {
+		stats := measuredStats(t, storage.FixedMode, blockSize, 600)
+		model, ok := predictCost(stats)
+		if !ok {
+			t.Fatalf("bloco de %d bytes: modelo não calculado", blockSize)
+		}
+		for _, comparison := range NewReporterTo(&bytes.Buffer{}, stats).costComparisons(model) {
+			if math.Abs(comparison.deviation()) > 1e-9 {
+				t.Errorf("bloco de %d bytes, %s: previsto %.4f, medido %.4f", blockSize, comparison.label, comparison.predicted, comparison.measured)
+			}
+		}
+	}
+}
+
+func TestVariableCostModelsTrackMeasuredStats(t *testing.T) {
+	for _, mode := range []storage.Mode{storage.VariableMode, storage.FragmentedMode} {
+		for _, blockSize := range []int{512, 4096} {
+			stats := measuredStats(t, mode, blockSize, 2000)
+			model, ok := predictCost(stats)
+			if !ok {
+				t.Fatalf("modo %s, bloco de %d bytes: modelo não calculado", mode, blockSize)
+			}
+			for _, comparison := range NewReporterTo(&bytes.Buffer{}, stats).costComparisons(model) {
+				if comparison.label == "Bytes livres por bloco" {
+					continue
+				}
+				if deviation := comparison.deviation(); math.Abs(deviation) > 2 {
+					t.Errorf("modo %s, bloco de %d bytes, %s: previsto %.2f, medido %.2f (desvio %.2f%%)",
+						mode, blockSize, comparison.label, comparison.predicted, comparison.measured, deviation)
+				}
+			}
+		}
+	}
+}
+
+func TestCostModelFormulas(t *testing.T) {
+	if got := averageSequentialReads(10, 3); math.Abs(got-2.2) > 1e-9 {
+		t.Errorf("averageSequentialReads(10, 3) = %v, esperado 2.2", got)
+	}
+	if got := averageSequentialReads(6, 2); math.Abs(got-2) > 1e-9 {
+		t.Errorf("averageSequentialReads(6, 2) = %v, esperado 2", got)
+	}
+	if got := unspannedBlockingFactor([]storage.RecordSizeCount{{Size: 100, Count: 7}}, 512); math.Abs(got-5) > 1e-9 {
+		t.Errorf("fator de bloco com registros de 100 bytes em blocos de 512: %v, esperado 5", got)
+	}
+	if got := unspannedBlockingFactor([]storage.RecordSizeCount{{Size: 100, Count: 1}, {Size: 200, Count: 1}}, 300); math.Abs(got-1.875) > 1e-9 {
+		t.Errorf("fator de bloco com registros de 100 e 200 bytes em blocos de 300: %v, esperado 1.875", got)
+	}
+	if got := averageRecordSize([]storage.RecordSizeCount{{Size: 100, Count: 3}, {Size: 200, Count: 1}}); got != 125 {
+		t.Errorf("tamanho médio %v, esperado 125", got)
+	}
+}
+
+func TestCostModelWithoutRecords(t *testing.T) {
+	if _, ok := predictCost(storage.StorageStats{Mode: storage.FixedMode, BlockSize: 512}); ok {
+		t.Fatal("modelo calculado sem registros")
+	}
+	var out bytes.Buffer
+	NewReporterTo(&out, storage.StorageStats{}).PrintCostModel()
+	if !strings.Contains(out.String(), "Sem registros para estimar.") {
+		t.Fatalf("relatório sem registros:\n%s", out.String())
+	}
+}
+
+func TestCostDeviation(t *testing.T) {
+	cases := []struct {
+		predicted, measured, want float64
+	}{
+		{100, 110, 10},
+		{100, 90, -10},
+		{0, 0, 0},
+		{0, 1, math.Inf(1)},
+	}
+	for _, tc := range cases {
+		if got := (costComparison{predicted: tc.predicted, measured: tc.measured}).deviation(); got != tc.want {
+			t.Errorf("desvio de %v para %v: %v, esperado %v", tc.predicted, tc.measured, got, tc.want)
+		}
+	}
+}
+
+func TestPrintCostModelHighlightsDeviation(t *testing.T) {
+	stats := measuredStats(t, storage.FixedMode, 512, 100)
+	stats.TotalBlocks += 10
+	var out bytes.Buffer
+	NewReporterTo(&out, stats).PrintCostModel()
+	highlighted := map[string]bool{}
+	for _, line := range strings.Split(out.String(), "\n") {
+		if label, _, found := strings.Cut(line, "  "); found && strings.Contains(line, "%") {
+			highlighted[label] = strings.HasSuffix(line, " ◀")
+		}
+	}
+	if highlighted, found := highlighted["Blocos"]; !found || !highlighted {
+		t.Fatalf("desvio nos blocos não destacado:\n%s", out.String())
+	}
+	if highlighted, found := highlighted["Leituras por busca (média)"]; !found || highlighted {
+		t.Fatalf("medida sem desvio destacada ou ausente:\n%s", out.String())
+	}
+}
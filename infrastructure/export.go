@@ -0,0 +1,214 @@
+package infrastructure
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+)
+
+type ReportFormat string
+
+const (
+	FormatText     ReportFormat = "texto"
+	FormatJSON     ReportFormat = "json"
+	FormatCSV      ReportFormat = "csv"
+	FormatMarkdown ReportFormat = "markdown"
+)
+
+var ReportFormats = []ReportFormat{FormatText, FormatJSON, FormatCSV, FormatMarkdown}
+
+func (f ReportFormat) Extension() string {
+	switch f {
+	case FormatJSON:
+		return ".json"
+	case FormatCSV:
+		return ".csv"
+	case FormatMarkdown:
+		return ".md"
+	default:
+		return ".txt"
+	}
+}
+
+type errorWriter struct {
+	out io.Writer
+	err error
+}
+
+func (ew *errorWriter) Write(p []byte) (int, error) {
+	if ew.err != nil {
+		return 0, ew.err
+	}
+	n, err := ew.out.Write(p)
+	ew.err = err
+	return n, err
+}
+
+func (r *Reporter) Export(format ReportFormat) error {
+	out := &errorWriter{out: r.out}
+	reporter := NewReporterTo(out, r.stats)
+
+	var err error
+	switch format {
+	case FormatText:
+		reporter.PrintReport()
+	case FormatJSON:
+		err = reporter.exportJSON()
+	case FormatCSV:
+		err = reporter.exportCSV()
+	case FormatMarkdown:
+		reporter.exportMarkdown()
+	default:
+		return fmt.Errorf("formato de relatório desconhecido: %q", format)
+	}
+
+	if out.err != nil {
+		err = out.err
+	}
+	if err != nil {
+		return fmt.Errorf("erro ao gravar relatório: %w", err)
+	}
+	return nil
+}
+
+func (r *Reporter) exportJSON() error {
+	encoder := json.NewEncoder(r.out)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(r.stats)
+}
+
+func (r *Reporter) exportCSV() error {
+	writer := csv.NewWriter(r.out)
+	writer.Write([]string{
+		"bloco",
+		"bytes_usados",
+		"bytes_totais",
+		"ocupacao",
+		"registros",
+		"preenchimento",
+		"prefixos",
+		"cabecalhos",
+		"sobra",
+		"fragmentos",
+		"comeca_com_continuacao",
+		"termina_com_continuacao",
+	})
+	for _, blockStat := range r.stats.BlockStatsList {
+		writer.Write([]string{
+			strconv.FormatInt(blockStat.BlockNumber+1, 10),
+			strconv.Itoa(blockStat.BytesUsed),
+			strconv.Itoa(blockStat.BytesTotal),
+			strconv.FormatFloat(blockStat.OccupancyRate, 'f', 2, 64),
+			strconv.Itoa(blockStat.RecordsCount),
+			strconv.Itoa(blockStat.PaddingBytes),
+			strconv.Itoa(blockStat.LengthPrefixBytes),
+			strconv.Itoa(blockStat.HeaderBytes),
+			strconv.Itoa(blockStat.TailBytes),
+			strconv.Itoa(blockStat.FragmentsCount),
+			strconv.FormatBool(blockStat.StartsWithContinuation),
+			strconv.FormatBool(blockStat.EndsWithContinuation),
+		})
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+func (r *Reporter) exportMarkdown() {
+	fmt.Fprintln(r.out, "## Estatísticas de Armazenamento")
+	fmt.Fprintln(r.out)
+	fmt.Fprintln(r.out, "| Métrica | Valor |")
+	fmt.Fprintln(r.out, "|---|---:|")
+	fmt.Fprintf(r.out, "| Blocos utilizados | %d |\n", r.stats.TotalBlocks)
+	fmt.Fprintf(r.out, "| Eficiência de armazenamento | %.2f%% |\n", r.stats.EfficiencyRate)
+	fmt.Fprintf(r.out, "| Blocos parcialmente utilizados | %d |\n", r.stats.PartialBlocks)
+	fmt.Fprintf(r.out, "| Bytes utilizados | %d |\n", r.stats.TotalBytesUsed)
+	fmt.Fprintf(r.out, "| Bytes disponíveis | %d |\n", r.stats.TotalBytesTotal)
+	fmt.Fprintf(r.out, "| Ocupação média | %.2f%% |\n", r.averageOccupancy())
+	fmt.Fprintf(r.out, "| Registros | %d |\n", r.stats.TotalRecords)
+
+	fmt.Fprintln(r.out)
+	fmt.Fprintln(r.out, "## Desperdício de Espaço")
+	fmt.Fprintln(r.out)
+	fmt.Fprintln(r.out, "| Parcela | Bytes | % do total |")
+	fmt.Fprintln(r.out, "|---|---:|---:|")
+	r.markdownWasteRow("Dados dos registros", r.payloadBytes())
+	r.markdownWasteRow("Preenchimento com `#` nos campos fixos", r.stats.TotalPaddingBytes)
+	r.markdownWasteRow("Prefixos de tamanho (4 bytes)", r.stats.TotalLengthPrefixBytes)
+	r.markdownWasteRow("Cabeçalhos de fragmento (5 bytes)", r.stats.TotalHeaderBytes)
+	r.markdownWasteRow("Sobra no fim dos blocos", r.stats.TotalTailBytes)
+
+	fmt.Fprintln(r.out)
+	fmt.Fprintln(r.out, "## Espalhamento de Registros")
+	fmt.Fprintln(r.out)
+	fmt.Fprintln(r.out, "| Métrica | Valor |")
+	fmt.Fprintln(r.out, "|---|---:|")
+	fmt.Fprintf(r.out, "| Registros divididos em dois ou mais blocos | %d (%.2f%%) |\n", r.stats.SpanningRecords, r.spanningRate())
+	fmt.Fprintf(r.out, "| Fragmentos por registro (média) | %.2f |\n", r.stats.AvgFragmentsPerRecord)
+	fmt.Fprintf(r.out, "| Fragmentos por registro (máximo) | %d |\n", r.stats.MaxFragmentsPerRecord)
+	fmt.Fprintf(r.out, "| Leituras extras de bloco | %d |\n", r.stats.ExtraBlockReads)
+	fmt.Fprintf(r.out, "| Leituras extras por registro dividido | %.2f |\n", r.extraReadsPerSpanningRecord())
+
+	if model, ok := predictCost(r.stats); ok {
+		fmt.Fprintln(r.out)
+		fmt.Fprintln(r.out, "## Modelo Analítico de Custo")
+		fmt.Fprintln(r.out)
+		fmt.Fprintf(r.out, "%s\n\n", model.formula)
+		fmt.Fprintln(r.out, "| Medida | Previsto | Medido | Desvio |")
+		fmt.Fprintln(r.out, "|---|---:|---:|---:|")
+		for _, comparison := range r.costComparisons(model) {
+			deviation := fmt.Sprintf("%.2f%%", comparison.deviation())
+			if math.Abs(comparison.deviation()) > deviationThreshold {
+				deviation = "**" + deviation + "**"
+			}
+			fmt.Fprintf(r.out, "| %s | %s | %s | %s |\n",
+				comparison.label, comparison.format(comparison.predicted), comparison.format(comparison.measured), deviation)
+		}
+	}
+
+	fmt.Fprintln(r.out)
+	fmt.Fprintln(r.out, "## Tamanho dos Registros")
+	fmt.Fprintln(r.out)
+	fmt.Fprintln(r.out, "| Faixa (bytes) | Registros |")
+	fmt.Fprintln(r.out, "|---|---:|")
+	for _, bucket := range r.recordSizeBuckets() {
+		fmt.Fprintf(r.out, "| %d-%d | %d |\n", bucket.start, bucket.end, bucket.count)
+	}
+
+	fmt.Fprintln(r.out)
+	fmt.Fprintln(r.out, "## Mapa de Ocupação dos Blocos")
+	fmt.Fprintln(r.out)
+	fmt.Fprintln(r.out, "| Bloco | Bytes usados | Ocupação | Registros | Preenchimento | Prefixos | Cabeçalhos | Sobra | Continuação |")
+	fmt.Fprintln(r.out, "|---:|---:|---:|---:|---:|---:|---:|---:|---|")
+	for _, blockStat := range r.stats.BlockStatsList {
+		fmt.Fprintf(r.out, "| %d | %d | %.2f%% | %d | %d | %d | %d | %d | %s |\n",
+			blockStat.BlockNumber+1,
+			blockStat.BytesUsed,
+			blockStat.OccupancyRate,
+			blockStat.RecordsCount,
+			blockStat.PaddingBytes,
+			blockStat.LengthPrefixBytes,
+			blockStat.HeaderBytes,
+			blockStat.TailBytes,
+			markdownContinuation(blockStat.StartsWithContinuation, blockStat.EndsWithContinuation))
+	}
+}
+
+func (r *Reporter) markdownWasteRow(label string, bytes int64) {
+	fmt.Fprintf(r.out, "| %s | %d | %.2f%% |\n", label, bytes, r.shareOfTotal(bytes))
+}
+
+func markdownContinuation(starts, ends bool) string {
+	switch {
+	case starts && ends:
+		return "começa e termina"
+	case starts:
+		return "começa"
+	case ends:
+		return "termina"
+	default:
+		return ""
+	}
+}
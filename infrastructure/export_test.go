@@ -0,0 +1,140 @@
+package infrastructure
+
+import (
+	"aeds2-tp1/storage"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func exportReport(t *testing.T, stats storage.StorageStats, format ReportFormat) string {
+	t.Helper()
+	var out bytes.Buffer
+	if err := NewReporterTo(&out, stats).Export(format); err != nil {
+		t.Fatalf("Export(%s): %v", format, err)
+	}
+	return out.String()
+}
+
+func TestExportJSONRoundTrip(t *testing.T) {
+	for _, mode := range allModes {
+		t.Run(mode.String(), func(t *testing.T) {
+			stats := measuredStats(t, mode, 512, 120)
+			var decoded storage.StorageStats
+			if err := json.Unmarshal([]byte(exportReport(t, stats, FormatJSON)), &decoded); err != nil {
+				t.Fatalf("JSON exportado inválido: %v", err)
+			}
+			if !reflect.DeepEqual(decoded, stats) {
+				t.Fatalf("JSON exportado não reproduz as estatísticas:\n lido     %+v\n esperado %+v", decoded, stats)
+			}
+		})
+	}
+}
+
+func TestExportCSVHasOneRowPerBlock(t *testing.T) {
+	for _, mode := range allModes {
+		t.Run(mode.String(), func(t *testing.T) {
+			stats := measuredStats(t, mode, 512, 120)
+			rows, err := csv.NewReader(strings.NewReader(exportReport(t, stats, FormatCSV))).ReadAll()
+			if err != nil {
+				t.Fatalf("CSV exportado inválido: %v", err)
+			}
+			if len(rows) != len(stats.BlockStatsList)+1 {
+				t.Fatalf("CSV com %d linhas, esperado cabeçalho e %d blocos", len(rows), len(stats.BlockStatsList))
+			}
+			if rows[0][0] != "bloco" || rows[0][len(rows[0])-1] != "termina_com_continuacao" {
+				t.Fatalf("cabeçalho do CSV inesperado: %v", rows[0])
+			}
+			for i, blockStat := range stats.BlockStatsList {
+				row := rows[i+1]
+				want := []string{
+					strconv.FormatInt(blockStat.BlockNumber+1, 10),
+					strconv.Itoa(blockStat.BytesUsed),
+					strconv.Itoa(blockStat.BytesTotal),
+					strconv.FormatFloat(blockStat.OccupancyRate, 'f', 2, 64),
+					strconv.Itoa(blockStat.RecordsCount),
+					strconv.Itoa(blockStat.PaddingBytes),
+					strconv.Itoa(blockStat.LengthPrefixBytes),
+					strconv.Itoa(blockStat.HeaderBytes),
+					strconv.Itoa(blockStat.TailBytes),
+					strconv.Itoa(blockStat.FragmentsCount),
+					strconv.FormatBool(blockStat.StartsWithContinuation),
+					strconv.FormatBool(blockStat.EndsWithContinuation),
+				}
+				if !reflect.DeepEqual(row, want) {
+					t.Fatalf("linha do bloco %d: %v, esperado %v", i+1, row, want)
+				}
+			}
+		})
+	}
+}
+
+func TestExportMarkdownGolden(t *testing.T) {
+	stats := storage.StorageStats{
+		Mode:                   storage.FragmentedMode,
+		BlockSize:              100,
+		TotalBlocks:            2,
+		TotalBytesUsed:         150,
+		TotalBytesTotal:        200,
+		EfficiencyRate:         75,
+		PartialBlocks:          1,
+		TotalLengthPrefixBytes: 12,
+		TotalHeaderBytes:       15,
+		TotalTailBytes:         50,
+		TotalRecords:           3,
+		TotalFragments:         4,
+		SpanningRecords:        1,
+		MaxFragmentsPerRecord:  2,
+		AvgFragmentsPerRecord:  4.0 / 3,
+		ExtraBlockReads:        1,
+		BlockStatsList: []storage.BlockStats{
+			{BlockNumber: 0, BytesUsed: 100, BytesTotal: 100, OccupancyRate: 100, RecordsCount: 1, LengthPrefixBytes: 8, HeaderBytes: 10, FragmentsCount: 2, EndsWithContinuation: true},
+			{BlockNumber: 1, BytesUsed: 50, BytesTotal: 100, OccupancyRate: 50, RecordsCount: 2, LengthPrefixBytes: 4, HeaderBytes: 5, TailBytes: 50, FragmentsCount: 2, StartsWithContinuation: true},
+		},
+		RecordSizes: []storage.RecordSizeCount{{Size: 40, Count: 2}, {Size: 43, Count: 1}},
+	}
+	markdown := exportReport(t, stats, FormatMarkdown)
+	for _, line := range []string{
+		"| Blocos utilizados | 2 |",
+		"| Eficiência de armazenamento | 75.00% |",
+		"| Bytes utilizados | 150 |",
+		"| Ocupação média | 75.00% |",
+		"| Registros | 3 |",
+		"| Dados dos registros | 123 | 61.50% |",
+		"| Prefixos de tamanho (4 bytes) | 12 | 6.00% |",
+		"| Cabeçalhos de fragmento (5 bytes) | 15 | 7.50% |",
+		"| Sobra no fim dos blocos | 50 | 25.00% |",
+		"| Registros divididos em dois ou mais blocos | 1 (33.33%) |",
+		"| Fragmentos por registro (média) | 1.33 |",
+		"| Fragmentos por registro (máximo) | 2 |",
+		"| 1 | 100 | 100.00% | 1 | 0 | 8 | 10 | 0 | termina |",
+		"| 2 | 50 | 50.00% | 2 | 0 | 4 | 5 | 50 | começa |",
+	} {
+		if !strings.Contains(markdown, line+"\n") {
+			t.Errorf("Markdown sem a linha %q:\n%s", line, markdown)
+		}
+	}
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("disco cheio")
+}
+
+func TestExportReportsWriteErrors(t *testing.T) {
+	stats := measuredStats(t, storage.VariableMode, 512, 20)
+	for _, format := range ReportFormats {
+		if err := NewReporterTo(failingWriter{}, stats).Export(format); err == nil || !strings.Contains(err.Error(), "disco cheio") {
+			t.Errorf("Export(%s) em um destino com falha retornou %v", format, err)
+		}
+	}
+	if err := NewReporterTo(&bytes.Buffer{}, stats).Export("xml"); err == nil {
+		t.Error("Export aceitou um formato desconhecido")
+	}
+}
@@ -0,0 +1,32 @@
+package infrastructure
+
+import (
+	"aeds2-tp1/domain"
+	"aeds2-tp1/storage"
+	"context"
+	"testing"
+)
+
+var allModes = []storage.Mode{storage.FixedMode, storage.VariableMode, storage.FragmentedMode}
+
+func measuredStats(t testing.TB, mode storage.Mode, blockSize int, count int) storage.StorageStats {
+	t.Helper()
+	ctx := context.Background()
+	handle, err := storage.OpenDevice(storage.NewMemoryDevice(blockSize), storage.Options{Mode: mode})
+	if err != nil {
+		t.Fatalf("OpenDevice: %v", err)
+	}
+	defer handle.Close()
+	students, err := domain.NewStudentGeneratorWithSeed(38).Generate(count)
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	if err := handle.WriteStudents(ctx, students); err != nil {
+		t.Fatalf("WriteStudents: %v", err)
+	}
+	stats, err := handle.GetBlockStats(ctx)
+	if err != nil {
+		t.Fatalf("GetBlockStats: %v", err)
+	}
+	return stats
+}
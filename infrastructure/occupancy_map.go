@@ -0,0 +1,204 @@
+package infrastructure
+
+import (
+	"aeds2-tp1/storage"
+	"fmt"
+	"html"
+	"io"
+	"strings"
+)
+
+const (
+	mapColumns      = 50
+	mapCellSize     = 10
+	mapCellGap      = 1
+	mapPanelGap     = 40
+	mapHeaderHeight = 50
+	mapLegendHeight = 40
+	mapMargin       = 20
+	mapMinWidth     = 720
+	emptyBlockColor = "#d0d0d0"
+)
+
+type MapFormat string
+
+const (
+	MapFormatHTML MapFormat = "html"
+	MapFormatSVG  MapFormat = "svg"
+)
+
+var MapFormats = []MapFormat{MapFormatHTML, MapFormatSVG}
+
+func (f MapFormat) Extension() string {
+	return "." + string(f)
+}
+
+type OccupancyMap struct {
+	Title string
+	Stats storage.StorageStats
+}
+
+func WriteOccupancyMap(out io.Writer, format MapFormat, maps ...OccupancyMap) error {
+	writer := &errorWriter{out: out}
+	switch format {
+	case MapFormatSVG:
+		writeOccupancySVG(writer, maps)
+	case MapFormatHTML:
+		writeOccupancyHTML(writer, maps)
+	default:
+		return fmt.Errorf("formato de mapa desconhecido: %q", format)
+	}
+
+	if writer.err != nil {
+		return fmt.Errorf("erro ao gravar mapa de ocupação: %w", writer.err)
+	}
+	return nil
+}
+
+func writeOccupancyHTML(out io.Writer, maps []OccupancyMap) {
+	titles := make([]string, 0, len(maps))
+	for _, occupancyMap := range maps {
+		titles = append(titles, occupancyMap.Title)
+	}
+
+	fmt.Fprintln(out, "<!DOCTYPE html>")
+	fmt.Fprintln(out, `<html lang="pt-BR">`)
+	fmt.Fprintln(out, "<head>")
+	fmt.Fprintln(out, `<meta charset="utf-8">`)
+	fmt.Fprintf(out, "<title>Mapa de ocupação - %s</title>\n", html.EscapeString(strings.Join(titles, " x ")))
+	fmt.Fprintln(out, "<style>body{font-family:sans-serif;margin:20px}table{border-collapse:collapse;margin-bottom:20px}td,th{border:1px solid #ccc;padding:4px 8px;text-align:right}th{text-align:left}</style>")
+	fmt.Fprintln(out, "</head>")
+	fmt.Fprintln(out, "<body>")
+	fmt.Fprintln(out, "<h1>Mapa de ocupação dos blocos</h1>")
+
+	fmt.Fprintln(out, "<table>")
+	fmt.Fprint(out, "<tr><th></th>")
+	for _, occupancyMap := range maps {
+		fmt.Fprintf(out, "<th>%s</th>", html.EscapeString(occupancyMap.Title))
+	}
+	fmt.Fprintln(out, "</tr>")
+	summaryRow(out, "Blocos", maps, func(stats storage.StorageStats) string { return fmt.Sprint(stats.TotalBlocks) })
+	summaryRow(out, "Registros", maps, func(stats storage.StorageStats) string { return fmt.Sprint(stats.TotalRecords) })
+	summaryRow(out, "Eficiência", maps, func(stats storage.StorageStats) string { return fmt.Sprintf("%.2f%%", stats.EfficiencyRate) })
+	summaryRow(out, "Blocos parciais", maps, func(stats storage.StorageStats) string { return fmt.Sprint(stats.PartialBlocks) })
+	summaryRow(out, "Registros divididos", maps, func(stats storage.StorageStats) string { return fmt.Sprint(stats.SpanningRecords) })
+	fmt.Fprintln(out, "</table>")
+
+	writeOccupancySVG(out, maps)
+	fmt.Fprintln(out, "</body>")
+	fmt.Fprintln(out, "</html>")
+}
+
+func summaryRow(out io.Writer, label string, maps []OccupancyMap, value func(storage.StorageStats) string) {
+	fmt.Fprintf(out, "<tr><th>%s</th>", label)
+	for _, occupancyMap := range maps {
+		fmt.Fprintf(out, "<td>%s</td>", value(occupancyMap.Stats))
+	}
+	fmt.Fprintln(out, "</tr>")
+}
+
+func writeOccupancySVG(out io.Writer, maps []OccupancyMap) {
+	panelWidth := mapColumns * (mapCellSize + mapCellGap)
+	maxRows := int64(1)
+	for _, occupancyMap := range maps {
+		maxRows = max(maxRows, (int64(len(occupancyMap.Stats.BlockStatsList))+mapColumns-1)/mapColumns)
+	}
+
+	width := max(mapMinWidth, 2*mapMargin+len(maps)*panelWidth+(len(maps)-1)*mapPanelGap)
+	height := int64(2*mapMargin+mapHeaderHeight+mapLegendHeight) + maxRows*(mapCellSize+mapCellGap)
+
+	fmt.Fprintf(out, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" font-family="sans-serif" font-size="12">`+"\n", width, height, width, height)
+	fmt.Fprintf(out, `<rect width="%d" height="%d" fill="#ffffff"/>`+"\n", width, height)
+	writeMapLegend(out, mapMargin, mapMargin)
+
+	for i, occupancyMap := range maps {
+		x := mapMargin + i*(panelWidth+mapPanelGap)
+		writeMapPanel(out, occupancyMap, x, mapMargin+mapLegendHeight)
+	}
+	fmt.Fprintln(out, "</svg>")
+}
+
+func writeMapLegend(out io.Writer, x, y int) {
+	fmt.Fprintln(out, `<defs><linearGradient id="ocupacao">`)
+	for step := 0; step <= 4; step++ {
+		occupancy := float64(step) * 25
+		fmt.Fprintf(out, `<stop offset="%d%%" stop-color="%s"/>`+"\n", step*25, occupancyColor(occupancy))
+	}
+	fmt.Fprintln(out, `</linearGradient></defs>`)
+	fmt.Fprintf(out, `<text x="%d" y="%d">0%%</text>`+"\n", x, y+12)
+	fmt.Fprintf(out, `<rect x="%d" y="%d" width="200" height="12" fill="url(#ocupacao)"/>`+"\n", x+30, y+2)
+	fmt.Fprintf(out, `<text x="%d" y="%d">100%% de ocupação</text>`+"\n", x+236, y+12)
+	fmt.Fprintf(out, `<rect x="%d" y="%d" width="%d" height="%d" fill="%s"/>`+"\n", x+380, y+2, mapCellSize, mapCellSize, emptyBlockColor)
+	fmt.Fprintf(out, `<text x="%d" y="%d">bloco vazio</text>`+"\n", x+396, y+12)
+	fmt.Fprintf(out, `<rect x="%d" y="%d" width="3" height="%d" fill="#000000"/>`+"\n", x+480, y+2, mapCellSize)
+	fmt.Fprintf(out, `<text x="%d" y="%d">registro continua entre blocos</text>`+"\n", x+490, y+12)
+}
+
+func writeMapPanel(out io.Writer, occupancyMap OccupancyMap, x, y int) {
+	stats := occupancyMap.Stats
+	fmt.Fprintf(out, `<g transform="translate(%d,%d)">`+"\n", x, y)
+	fmt.Fprintf(out, `<text x="0" y="16" font-size="14" font-weight="bold">%s</text>`+"\n", html.EscapeString(occupancyMap.Title))
+	fmt.Fprintf(out, `<text x="0" y="34">%d blocos, %d registros, eficiência %.2f%%</text>`+"\n", stats.TotalBlocks, stats.TotalRecords, stats.EfficiencyRate)
+
+	for i, blockStat := range stats.BlockStatsList {
+		cellX := (i % mapColumns) * (mapCellSize + mapCellGap)
+		cellY := mapHeaderHeight + (i/mapColumns)*(mapCellSize+mapCellGap)
+		color := occupancyColor(blockStat.OccupancyRate)
+		if blockStat.BytesUsed == 0 {
+			color = emptyBlockColor
+		}
+
+		fmt.Fprintf(out, `<rect x="%d" y="%d" width="%d" height="%d" fill="%s"><title>%s</title></rect>`+"\n",
+			cellX, cellY, mapCellSize, mapCellSize, color, blockTooltip(blockStat))
+		if blockStat.StartsWithContinuation {
+			fmt.Fprintf(out, `<rect x="%d" y="%d" width="2" height="%d" fill="#000000" pointer-events="none"/>`+"\n", cellX, cellY, mapCellSize)
+		}
+		if blockStat.EndsWithContinuation {
+			fmt.Fprintf(out, `<rect x="%d" y="%d" width="2" height="%d" fill="#000000" pointer-events="none"/>`+"\n", cellX+mapCellSize-2, cellY, mapCellSize)
+		}
+	}
+	fmt.Fprintln(out, "</g>")
+}
+
+var occupancyScale = [3][3]float64{
+	{215, 48, 39},
+	{254, 224, 139},
+	{26, 152, 80},
+}
+
+func occupancyColor(occupancy float64) string {
+	rgb := occupancyRGB(occupancy)
+	return fmt.Sprintf("#%02x%02x%02x", rgb[0], rgb[1], rgb[2])
+}
+
+func occupancyRGB(occupancy float64) [3]int {
+	position := min(max(occupancy, 0), 100) / 50
+	from := min(int(position), 1)
+	fraction := position - float64(from)
+
+	var rgb [3]int
+	for i := range rgb {
+		start := occupancyScale[from][i]
+		end := occupancyScale[from+1][i]
+		rgb[i] = int(start + (end-start)*fraction + 0.5)
+	}
+	return rgb
+}
+
+func blockTooltip(blockStat storage.BlockStats) string {
+	lines := []string{
+		fmt.Sprintf("Bloco %d", blockStat.BlockNumber+1),
+		fmt.Sprintf("Ocupação: %.2f%% (%d de %d bytes)", blockStat.OccupancyRate, blockStat.BytesUsed, blockStat.BytesTotal),
+		fmt.Sprintf("Registros: %d", blockStat.RecordsCount),
+	}
+	if blockStat.FragmentsCount > 0 {
+		lines = append(lines, fmt.Sprintf("Fragmentos: %d", blockStat.FragmentsCount))
+	}
+	if blockStat.StartsWithContinuation {
+		lines = append(lines, "Começa com a continuação de um registro")
+	}
+	if blockStat.EndsWithContinuation {
+		lines = append(lines, "Termina com um registro que continua no próximo bloco")
+	}
+	return html.EscapeString(strings.Join(lines, "\n"))
+}
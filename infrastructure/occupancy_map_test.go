@@ -0,0 +1,134 @@
+package infrastructure
+
+import (
+	"aeds2-tp1/storage"
+	"bytes"
+	"encoding/xml"
+	"strings"
+	"testing"
+)
+
+func TestOccupancyRGBEndpoints(t *testing.T) {
+	cases := []struct {
+		occupancy float64
+		want      [3]int
+	}{
+		{-10, [3]int{215, 48, 39}},
+		{0, [3]int{215, 48, 39}},
+		{25, [3]int{235, 136, 89}},
+		{50, [3]int{254, 224, 139}},
+		{100, [3]int{26, 152, 80}},
+		{150, [3]int{26, 152, 80}},
+	}
+	for _, tc := range cases {
+		if got := occupancyRGB(tc.occupancy); got != tc.want {
+			t.Errorf("occupancyRGB(%v) = %v, esperado %v", tc.occupancy, got, tc.want)
+		}
+	}
+	if got := occupancyColor(100); got != "#1a9850" {
+		t.Errorf("occupancyColor(100) = %s, esperado #1a9850", got)
+	}
+}
+
+type svgRect struct {
+	Fill  string `xml:"fill,attr"`
+	Title string `xml:"title"`
+}
+
+type svgGroup struct {
+	Rects []svgRect `xml:"rect"`
+}
+
+type svgDocument struct {
+	XMLName xml.Name   `xml:"svg"`
+	Groups  []svgGroup `xml:"g"`
+}
+
+func TestOccupancySVGDrawsOneCellPerBlock(t *testing.T) {
+	fixed := measuredStats(t, storage.FixedMode, 512, 150)
+	fragmented := measuredStats(t, storage.FragmentedMode, 512, 150)
+	fragmented.BlockStatsList = append(fragmented.BlockStatsList, storage.BlockStats{BlockNumber: int64(len(fragmented.BlockStatsList)), BytesTotal: 512})
+
+	var out bytes.Buffer
+	err := WriteOccupancyMap(&out, MapFormatSVG, OccupancyMap{Title: "fixo", Stats: fixed}, OccupancyMap{Title: "espalhado", Stats: fragmented})
+	if err != nil {
+		t.Fatalf("WriteOccupancyMap: %v", err)
+	}
+	var document svgDocument
+	if err := xml.Unmarshal(out.Bytes(), &document); err != nil {
+		t.Fatalf("SVG inválido: %v", err)
+	}
+	if len(document.Groups) != 2 {
+		t.Fatalf("SVG com %d painéis, esperado 2", len(document.Groups))
+	}
+
+	for i, stats := range []storage.StorageStats{fixed, fragmented} {
+		cells, markers := 0, 0
+		for _, rect := range document.Groups[i].Rects {
+			if rect.Title == "" {
+				markers++
+				continue
+			}
+			blockStat := stats.BlockStatsList[cells]
+			wantFill := occupancyColor(blockStat.OccupancyRate)
+			if blockStat.BytesUsed == 0 {
+				wantFill = emptyBlockColor
+			}
+			if rect.Fill != wantFill {
+				t.Fatalf("painel %d, bloco %d: cor %s, esperado %s", i, cells+1, rect.Fill, wantFill)
+			}
+			cells++
+		}
+		wantMarkers := 0
+		for _, blockStat := range stats.BlockStatsList {
+			if blockStat.StartsWithContinuation {
+				wantMarkers++
+			}
+			if blockStat.EndsWithContinuation {
+				wantMarkers++
+			}
+		}
+		if cells != len(stats.BlockStatsList) || markers != wantMarkers {
+			t.Fatalf("painel %d: %d células e %d marcas de continuação, esperado %d e %d", i, cells, markers, len(stats.BlockStatsList), wantMarkers)
+		}
+	}
+	if fragmented.SpanningRecords == 0 {
+		t.Fatal("o arquivo espalhado não tem registros divididos para marcar")
+	}
+}
+
+func TestOccupancyTooltip(t *testing.T) {
+	tooltip := blockTooltip(storage.BlockStats{BlockNumber: 4, BytesUsed: 256, BytesTotal: 512, OccupancyRate: 50, RecordsCount: 3, FragmentsCount: 4, StartsWithContinuation: true})
+	want := "Bloco 5\nOcupação: 50.00% (256 de 512 bytes)\nRegistros: 3\nFragmentos: 4\nComeça com a continuação de um registro"
+	if tooltip != want {
+		t.Fatalf("dica do bloco:\n%s\nesperado:\n%s", tooltip, want)
+	}
+}
+
+func TestOccupancyHTMLComparesFiles(t *testing.T) {
+	var out bytes.Buffer
+	maps := []OccupancyMap{
+		{Title: "fixo <512>", Stats: measuredStats(t, storage.FixedMode, 512, 60)},
+		{Title: "variável", Stats: measuredStats(t, storage.VariableMode, 512, 60)},
+	}
+	if err := WriteOccupancyMap(&out, MapFormatHTML, maps...); err != nil {
+		t.Fatalf("WriteOccupancyMap: %v", err)
+	}
+	page := out.String()
+	for _, want := range []string{
+		"<title>Mapa de ocupação - fixo &lt;512&gt; x variável</title>",
+		"<tr><th></th><th>fixo &lt;512&gt;</th><th>variável</th></tr>",
+		"<svg ",
+		"</html>",
+	} {
+		if !strings.Contains(page, want) {
+			t.Errorf("HTML sem %q", want)
+		}
+	}
+	if strings.Contains(page, "<512>") {
+		t.Error("título não escapado no HTML")
+	}
+	if err := WriteOccupancyMap(&out, "png", maps...); err == nil {
+		t.Error("WriteOccupancyMap aceitou um formato desconhecido")
+	}
+}
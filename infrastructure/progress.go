@@ -0,0 +1,105 @@
+package infrastructure
+
+import (
+	"aeds2-tp1/storage"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/term"
+)
+
+const progressBarWidth = 30
+
+type ProgressBar struct {
+	mu       sync.Mutex
+	out      io.Writer
+	enabled  bool
+	rendered bool
+}
+
+func NewProgressBar() *ProgressBar {
+	return &ProgressBar{
+		out:     os.Stdout,
+		enabled: term.IsTerminal(int(os.Stdout.Fd())),
+	}
+}
+
+func (pb *ProgressBar) Update(progress storage.Progress) {
+	if !pb.enabled {
+		return
+	}
+
+	pb.mu.Lock()
+	defer pb.mu.Unlock()
+
+	if progress.Done {
+		if pb.rendered {
+			fmt.Fprintf(pb.out, "\r%s\033[K\n", pb.render(progress))
+		}
+		pb.rendered = false
+		return
+	}
+
+	fmt.Fprintf(pb.out, "\r%s\033[K", pb.render(progress))
+	pb.rendered = true
+}
+
+func (pb *ProgressBar) render(progress storage.Progress) string {
+	var line strings.Builder
+	fmt.Fprintf(&line, "%-12s ", progress.Operation)
+
+	fraction, known := progressFraction(progress)
+	if known {
+		filled := int(fraction * progressBarWidth)
+		line.WriteString("[")
+		line.WriteString(strings.Repeat("█", filled))
+		line.WriteString(strings.Repeat("░", progressBarWidth-filled))
+		fmt.Fprintf(&line, "] %5.1f%% ", fraction*100)
+	}
+
+	fmt.Fprintf(&line, "| %d blocos | %d registros", progress.BlocksDone, progress.RecordsDone)
+
+	seconds := progress.Elapsed.Seconds()
+	if seconds > 0 {
+		fmt.Fprintf(&line, " | %.1f MB/s | %.0f reg/s",
+			float64(progress.BytesDone)/seconds/(1024*1024),
+			float64(progress.RecordsDone)/seconds)
+	}
+
+	if progress.Done {
+		fmt.Fprintf(&line, " | %s", formatDuration(progress.Elapsed))
+	} else if progress.ETA > 0 {
+		fmt.Fprintf(&line, " | ETA %s", formatDuration(progress.ETA))
+	}
+	return line.String()
+}
+
+func progressFraction(progress storage.Progress) (float64, bool) {
+	var fraction float64
+	switch {
+	case progress.Done:
+		return 1, true
+	case progress.TotalBlocks > 0:
+		fraction = float64(progress.BlocksDone) / float64(progress.TotalBlocks)
+	case progress.TotalRecords > 0:
+		fraction = float64(progress.RecordsDone) / float64(progress.TotalRecords)
+	default:
+		return 0, false
+	}
+
+	if fraction > 1 {
+		fraction = 1
+	}
+	return fraction, true
+}
+
+func formatDuration(d time.Duration) string {
+	d = d.Round(time.Second)
+	minutes := int(d / time.Minute)
+	seconds := int((d % time.Minute) / time.Second)
+	return fmt.Sprintf("%02d:%02d", minutes, seconds)
+}
@@ -3,26 +3,62 @@ package infrastructure
 import (
 	"aeds2-tp1/storage"
 	"fmt"
+	"io"
+	"os"
+	"strings"
 )
 
+const histogramBuckets = 10
+
 type Reporter struct {
-	stats storage.StorageStats
+	stats  storage.StorageStats
+	out    io.Writer
+	width  int
+	colors colorMode
+}
+
+type sizeBucket struct {
+	start int
+	end   int
+	count int64
 }
 
 func NewReporter(stats storage.StorageStats) *Reporter {
+	reporter := NewReporterTo(os.Stdout, stats)
+	reporter.width = terminalWidth(os.Stdout)
+	reporter.colors = detectColorMode(os.Stdout)
+	return reporter
+}
+
+func NewReporterTo(out io.Writer, stats storage.StorageStats) *Reporter {
 	return &Reporter{
 		stats: stats,
+		out:   out,
+		width: defaultTerminalWidth,
 	}
 }
 
+func (r *Reporter) PrintReport() {
+	r.PrintStats()
+	r.PrintWasteBreakdown()
+	r.PrintRecordSizeHistogram()
+	r.PrintSpanningStats()
+	r.PrintCostModel()
+	r.PrintBlockRuns()
+	r.PrintHeatmap()
+}
+
 func (r *Reporter) PrintStats() {
-	fmt.Println("\n=== ESTATÍSTICAS DE ARMAZENAMENTO ===")
-	fmt.Printf("Número total de blocos utilizados: %d\n", r.stats.TotalBlocks)
-	fmt.Printf("Eficiência total de armazenamento: %.2f%%\n", r.stats.EfficiencyRate)
-	fmt.Printf("Número de blocos parcialmente utilizados: %d\n", r.stats.PartialBlocks)
-	fmt.Printf("Total de bytes utilizados: %d\n", r.stats.TotalBytesUsed)
-	fmt.Printf("Total de bytes disponíveis: %d\n", r.stats.TotalBytesTotal)
-	
+	fmt.Fprintln(r.out, "\n=== ESTATÍSTICAS DE ARMAZENAMENTO ===")
+	fmt.Fprintf(r.out, "Número total de blocos utilizados: %d\n", r.stats.TotalBlocks)
+	fmt.Fprintf(r.out, "Eficiência total de armazenamento: %.2f%%\n", r.stats.EfficiencyRate)
+	fmt.Fprintf(r.out, "Número de blocos parcialmente utilizados: %d\n", r.stats.PartialBlocks)
+	fmt.Fprintf(r.out, "Total de bytes utilizados: %d\n", r.stats.TotalBytesUsed)
+	fmt.Fprintf(r.out, "Total de bytes disponíveis: %d\n", r.stats.TotalBytesTotal)
+	fmt.Fprintf(r.out, "Percentual médio de ocupação: %.2f%%\n", r.averageOccupancy())
+}
+
+func (r *Reporter) averageOccupancy() float64 {
 	avgOccupancy := 0.0
 	for _, blockStat := range r.stats.BlockStatsList {
 		avgOccupancy += blockStat.OccupancyRate
@@ -30,31 +66,140 @@ func (r *Reporter) PrintStats() {
 	if len(r.stats.BlockStatsList) > 0 {
 		avgOccupancy /= float64(len(r.stats.BlockStatsList))
 	}
-	fmt.Printf("Percentual médio de ocupação: %.2f%%\n", avgOccupancy)
+	return avgOccupancy
+}
+
+func (r *Reporter) PrintWasteBreakdown() {
+	fmt.Fprintln(r.out, "\n=== DESPERDÍCIO DE ESPAÇO ===")
+	r.printWasteLine("Dados dos registros", r.payloadBytes())
+	r.printWasteLine("Preenchimento com '#' nos campos fixos", r.stats.TotalPaddingBytes)
+	r.printWasteLine("Prefixos de tamanho (4 bytes)", r.stats.TotalLengthPrefixBytes)
+	r.printWasteLine("Cabeçalhos de fragmento (5 bytes)", r.stats.TotalHeaderBytes)
+	r.printWasteLine("Sobra no fim dos blocos", r.stats.TotalTailBytes)
+}
+
+func (r *Reporter) printWasteLine(label string, bytes int64) {
+	fmt.Fprintf(r.out, "%-40s %10d bytes (%.2f%%)\n", label+":", bytes, r.shareOfTotal(bytes))
+}
+
+func (r *Reporter) payloadBytes() int64 {
+	return r.stats.TotalBytesUsed - r.stats.TotalPaddingBytes - r.stats.TotalLengthPrefixBytes - r.stats.TotalHeaderBytes
+}
+
+func (r *Reporter) shareOfTotal(bytes int64) float64 {
+	if r.stats.TotalBytesTotal == 0 {
+		return 0
+	}
+	return float64(bytes) / float64(r.stats.TotalBytesTotal) * 100
 }
 
 func (r *Reporter) PrintBlockMap() {
-	fmt.Println("\n=== MAPA DE OCUPAÇÃO DOS BLOCOS ===")
+	fmt.Fprintln(r.out, "\n=== MAPA DE OCUPAÇÃO DOS BLOCOS ===")
 	for _, blockStat := range r.stats.BlockStatsList {
-		fmt.Printf("Bloco %d: %d bytes (%.2f%% cheio) - %d registros\n",
+		fmt.Fprintf(r.out, "Bloco %d: %d bytes (%.2f%% cheio) - %d registros - preenchimento %d, prefixos %d, cabeçalhos %d, sobra %d%s\n",
 			blockStat.BlockNumber+1,
 			blockStat.BytesUsed,
 			blockStat.OccupancyRate,
-			blockStat.RecordsCount)
+			blockStat.RecordsCount,
+			blockStat.PaddingBytes,
+			blockStat.LengthPrefixBytes,
+			blockStat.HeaderBytes,
+			blockStat.TailBytes,
+			continuationMarks(blockStat))
 	}
 }
 
+func continuationMarks(blockStat storage.BlockStats) string {
+	marks := ""
+	if blockStat.StartsWithContinuation {
+		marks += " [começa com continuação]"
+	}
+	if blockStat.EndsWithContinuation {
+		marks += " [termina com continuação]"
+	}
+	return marks
+}
+
+func (r *Reporter) PrintSpanningStats() {
+	fmt.Fprintln(r.out, "\n=== ESPALHAMENTO DE REGISTROS ===")
+	if r.stats.TotalFragments == 0 {
+		fmt.Fprintln(r.out, "Nenhum registro fragmentado entre blocos.")
+		return
+	}
+
+	fmt.Fprintf(r.out, "Registros divididos em dois ou mais blocos: %d de %d (%.2f%%)\n", r.stats.SpanningRecords, r.stats.TotalRecords, r.spanningRate())
+	fmt.Fprintf(r.out, "Fragmentos por registro: média %.2f, máximo %d\n", r.stats.AvgFragmentsPerRecord, r.stats.MaxFragmentsPerRecord)
+	fmt.Fprintf(r.out, "Leituras extras de bloco para remontar registros: %d no total\n", r.stats.ExtraBlockReads)
+	fmt.Fprintf(r.out, "Leituras extras por consulta: %.2f em média, %.2f por registro dividido\n", r.stats.AvgFragmentsPerRecord-1, r.extraReadsPerSpanningRecord())
+}
+
+func (r *Reporter) spanningRate() float64 {
+	if r.stats.TotalRecords == 0 {
+		return 0
+	}
+	return float64(r.stats.SpanningRecords) / float64(r.stats.TotalRecords) * 100
+}
+
+func (r *Reporter) extraReadsPerSpanningRecord() float64 {
+	if r.stats.SpanningRecords == 0 {
+		return 0
+	}
+	return float64(r.stats.ExtraBlockReads) / float64(r.stats.SpanningRecords)
+}
+
+func (r *Reporter) PrintRecordSizeHistogram() {
+	fmt.Fprintln(r.out, "\n=== HISTOGRAMA DE TAMANHO DOS REGISTROS ===")
+	buckets := r.recordSizeBuckets()
+	if len(buckets) == 0 {
+		fmt.Fprintln(r.out, "Nenhum registro encontrado.")
+		return
+	}
+
+	maxCount := int64(0)
+	for _, bucket := range buckets {
+		maxCount = max(maxCount, bucket.count)
+	}
+
+	for _, bucket := range buckets {
+		barLength := int(bucket.count * 50 / maxCount)
+		fmt.Fprintf(r.out, "%4d-%4d bytes: [%s%s] %d\n",
+			bucket.start,
+			bucket.end,
+			strings.Repeat("█", barLength),
+			strings.Repeat("░", 50-barLength),
+			bucket.count)
+	}
+}
+
+func (r *Reporter) recordSizeBuckets() []sizeBucket {
+	recordSizes := r.stats.RecordSizes
+	if len(recordSizes) == 0 {
+		return nil
+	}
+
+	minSize := recordSizes[0].Size
+	maxSize := recordSizes[len(recordSizes)-1].Size
+	width := (maxSize - minSize + histogramBuckets) / histogramBuckets
+
+	buckets := make([]sizeBucket, (maxSize-minSize)/width+1)
+	for i := range buckets {
+		buckets[i].start = minSize + i*width
+		buckets[i].end = buckets[i].start + width - 1
+	}
+	for _, recordSize := range recordSizes {
+		buckets[(recordSize.Size-minSize)/width].count += recordSize.Count
+	}
+	return buckets
+}
+
 func (r *Reporter) PrintBlockVisualization() {
-	fmt.Println("\n=== VISUALIZAÇÃO VISUAL DOS BLOCOS ===")
+	fmt.Fprintln(r.out, "\n=== VISUALIZAÇÃO VISUAL DOS BLOCOS ===")
 	for _, blockStat := range r.stats.BlockStatsList {
 		barLength := int(blockStat.OccupancyRate / 2)
-		bar := ""
-		for i := 0; i < barLength; i++ {
-			bar += "█"
-		}
-		for i := barLength; i < 50; i++ {
-			bar += "░"
-		}
-		fmt.Printf("Bloco %3d: [%s] %.2f%%\n", blockStat.BlockNumber+1, bar, blockStat.OccupancyRate)
+		fmt.Fprintf(r.out, "Bloco %3d: [%s%s] %.2f%%\n",
+			blockStat.BlockNumber+1,
+			strings.Repeat("█", barLength),
+			strings.Repeat("░", 50-barLength),
+			blockStat.OccupancyRate)
 	}
 }
@@ -0,0 +1,163 @@
+package infrastructure
+
+import (
+	"aeds2-tp1/entity"
+	"aeds2-tp1/storage"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+type RoundTripOptions struct {
+	BlockSizes []int
+	Modes      []storage.Mode
+	Dir        string
+	Encoding   storage.Encoding
+}
+
+type RoundTripResult struct {
+	Mode      string
+	BlockSize int
+	Records   int
+	Error     string
+	Rejected  string
+	Diffs     []RecordDiff
+}
+
+type RecordDiff struct {
+	Index     int
+	Matricula int64
+	Field     string
+	Written   string
+	Read      string
+}
+
+type studentField struct {
+	name  string
+	value string
+}
+
+func (r RoundTripResult) Status() string {
+	switch {
+	case r.Error != "":
+		return "bloco inválido"
+	case r.Rejected != "":
+		return "rejeitado"
+	case len(r.Diffs) > 0:
+		return "divergente"
+	default:
+		return "ok"
+	}
+}
+
+func RunRoundTrip(ctx context.Context, students []entity.Student, options RoundTripOptions) ([]RoundTripResult, error) {
+	modes := options.Modes
+	if len(modes) == 0 {
+		modes = BenchmarkModes
+	}
+
+	dir, err := os.MkdirTemp(options.Dir, "tp1-roundtrip-")
+	if err != nil {
+		return nil, fmt.Errorf("erro ao criar diretório temporário: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	results := make([]RoundTripResult, 0, len(options.BlockSizes)*len(modes))
+	for _, blockSize := range options.BlockSizes {
+		for _, mode := range modes {
+			result, err := runRoundTripCase(ctx, filepath.Join(dir, "alunos.dat"), mode, blockSize, options.Encoding, students)
+			if err != nil {
+				return results, err
+			}
+			results = append(results, result)
+		}
+	}
+	return results, nil
+}
+
+func runRoundTripCase(ctx context.Context, path string, mode storage.Mode, blockSize int, encoding storage.Encoding, students []entity.Student) (RoundTripResult, error) {
+	result := RoundTripResult{
+		Mode:      mode.String(),
+		BlockSize: blockSize,
+		Records:   len(students),
+	}
+	defer os.Remove(path + ".stats")
+	defer os.Remove(path)
+
+	handle, err := storage.Open(path, storage.Options{Mode: mode, BlockSize: blockSize, Encoding: encoding})
+	if err != nil {
+		result.Error = err.Error()
+		return result, nil
+	}
+	defer handle.Close()
+
+	if err := handle.WriteStudents(ctx, students); err != nil {
+		if ctx.Err() != nil {
+			return result, ctx.Err()
+		}
+		result.Rejected = err.Error()
+		return result, nil
+	}
+
+	read, err := handle.GetAllStudents(ctx)
+	if ctx.Err() != nil {
+		return result, ctx.Err()
+	}
+	if err != nil {
+		result.Diffs = append(result.Diffs, RecordDiff{Field: "leitura", Written: "todos os registros", Read: err.Error()})
+	} else if len(read) != len(students) {
+		result.Diffs = append(result.Diffs, RecordDiff{
+			Field:   "quantidade de registros",
+			Written: strconv.Itoa(len(students)),
+			Read:    strconv.Itoa(len(read)),
+		})
+	}
+	for i := range min(len(read), len(students)) {
+		result.Diffs = append(result.Diffs, diffStudents(i, "", students[i], *read[i])...)
+	}
+
+	for i, student := range students {
+		found, err := handle.FindStudentByMatricula(ctx, student.Matricula)
+		if ctx.Err() != nil {
+			return result, ctx.Err()
+		}
+		if err != nil {
+			result.Diffs = append(result.Diffs, RecordDiff{Index: i, Matricula: student.Matricula, Field: "busca", Written: "encontrado", Read: err.Error()})
+			continue
+		}
+		result.Diffs = append(result.Diffs, diffStudents(i, "busca: ", student, *found)...)
+	}
+	return result, nil
+}
+
+func diffStudents(index int, prefix string, written, read entity.Student) []RecordDiff {
+	diffs := make([]RecordDiff, 0)
+	readFields := studentFields(read)
+	for i, field := range studentFields(written) {
+		if field.value != readFields[i].value {
+			diffs = append(diffs, RecordDiff{
+				Index:     index,
+				Matricula: written.Matricula,
+				Field:     prefix + field.name,
+				Written:   field.value,
+				Read:      readFields[i].value,
+			})
+		}
+	}
+	return diffs
+}
+
+func studentFields(student entity.Student) []studentField {
+	return []studentField{
+		{"matrícula", strconv.FormatInt(student.Matricula, 10)},
+		{"nome", student.Nome},
+		{"CPF", student.CPF},
+		{"curso", student.Curso},
+		{"filiação mãe", student.FiliacaoMae},
+		{"filiação pai", student.FiliacaoPai},
+		{"ano de ingresso", strconv.Itoa(student.AnoIngresso)},
+		{"CA", strconv.FormatFloat(student.CA, 'g', -1, 64)},
+	}
+}
@@ -0,0 +1,79 @@
+package infrastructure
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+const maxReportedDiffs = 5
+
+type RoundTripReporter struct {
+	results []RoundTripResult
+	out     io.Writer
+}
+
+func NewRoundTripReporter(results []RoundTripResult) *RoundTripReporter {
+	return NewRoundTripReporterTo(os.Stdout, results)
+}
+
+func NewRoundTripReporterTo(out io.Writer, results []RoundTripResult) *RoundTripReporter {
+	return &RoundTripReporter{
+		results: results,
+		out:     out,
+	}
+}
+
+func (rr *RoundTripReporter) PrintSummary(title string) {
+	fmt.Fprintf(rr.out, "\n=== IDA E VOLTA: %s ===\n", title)
+	if len(rr.results) == 0 {
+		fmt.Fprintln(rr.out, "Nenhuma execução realizada.")
+		return
+	}
+
+	fmt.Fprintf(rr.out, "%-10s %7s %10s %14s %12s\n", "Modo", "Bloco", "Registros", "Situação", "Diferenças")
+	for _, result := range rr.results {
+		fmt.Fprintf(rr.out, "%-10s %7d %10d %14s %12d\n", result.Mode, result.BlockSize, result.Records, result.Status(), len(result.Diffs))
+	}
+
+	for _, result := range rr.results {
+		switch {
+		case result.Error != "":
+			fmt.Fprintf(rr.out, "\nModo %s, bloco de %d bytes: %s\n", result.Mode, result.BlockSize, result.Error)
+		case result.Rejected != "":
+			fmt.Fprintf(rr.out, "\nModo %s, bloco de %d bytes: gravação rejeitada: %s\n", result.Mode, result.BlockSize, result.Rejected)
+		case len(result.Diffs) > 0:
+			fmt.Fprintf(rr.out, "\nModo %s, bloco de %d bytes: %d diferenças\n", result.Mode, result.BlockSize, len(result.Diffs))
+			for _, diff := range result.Diffs[:min(len(result.Diffs), maxReportedDiffs)] {
+				if diff.Matricula == 0 {
+					fmt.Fprintf(rr.out, "  %s: gravado %q, lido %q\n", diff.Field, diff.Written, diff.Read)
+					continue
+				}
+				fmt.Fprintf(rr.out, "  registro %d (matrícula %d), %s: gravado %q, lido %q\n", diff.Index+1, diff.Matricula, diff.Field, diff.Written, diff.Read)
+			}
+			if len(result.Diffs) > maxReportedDiffs {
+				fmt.Fprintf(rr.out, "  ... e mais %d\n", len(result.Diffs)-maxReportedDiffs)
+			}
+		}
+	}
+}
+
+func (rr *RoundTripReporter) Divergent() int {
+	divergent := 0
+	for _, result := range rr.results {
+		if len(result.Diffs) > 0 {
+			divergent++
+		}
+	}
+	return divergent
+}
+
+func (rr *RoundTripReporter) Rejected() int {
+	rejected := 0
+	for _, result := range rr.results {
+		if result.Rejected != "" {
+			rejected++
+		}
+	}
+	return rejected
+}
@@ -6,16 +6,76 @@ import (
 	"aeds2-tp1/infrastructure"
 	"aeds2-tp1/storage"
 	"bufio"
+	"context"
+	"errors"
+	"flag"
 	"fmt"
+	"math/rand/v2"
 	"os"
+	"os/signal"
+	"reflect"
 	"strconv"
 	"strings"
+	"time"
 )
 
-const filename = "alunos.dat"
+const (
+	filename          = "alunos.dat"
+	lockTimeout       = 5 * time.Second
+	zoomDetailLimit   = 100
+	advisorSampleSize = 2000
+)
+
+var (
+	recompute = flag.Bool("recompute", false, "recalcula as estatísticas varrendo o arquivo inteiro e confere com as persistidas")
+	seed      = flag.Uint64("seed", 0, "semente do gerador de dados (sem a opção, uma semente aleatória é sorteada e exibida)")
+	start     = flag.Int64("start-matricula", domain.FirstMatricula, "primeira matrícula gerada")
+	profile   = flag.String("profile", domain.DefaultProfileName, "perfil do gerador: "+strings.Join(domain.BuiltinProfileNames(), ", ")+" ou caminho de um arquivo JSON")
+	encoding  = flag.String("encoding", "utf-8", "codificação dos textos no arquivo: utf-8 ou latin1 (ISO-8859-1)")
+)
 
 func main() {
+	flag.Parse()
+
+	if flag.Arg(0) == "compare" {
+		if err := runCompare(flag.Args()[1:]); err != nil {
+			fmt.Printf("Erro: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if flag.Arg(0) == "create" {
+		if err := runCreate(flag.Args()[1:]); err != nil {
+			fmt.Printf("Erro: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if flag.Arg(0) == "roundtrip" {
+		if err := runRoundTrip(flag.Args()[1:]); err != nil {
+			fmt.Printf("Erro: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	fmt.Println("=== Sistema de Armazenamento de Registros de Alunos ===")
+	textEncoding, err := storage.ParseEncoding(*encoding)
+	if err != nil {
+		fmt.Printf("Erro: %v\n", err)
+		os.Exit(1)
+	}
+	generator, err := newStudentGenerator(flag.CommandLine, *seed, *profile)
+	if err == nil {
+		err = generator.StartAt(*start)
+	}
+	if err != nil {
+		fmt.Printf("Erro: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Codificação do arquivo: %s\n", textEncoding)
 	fmt.Println()
 
 	if _, err := os.Stat(filename); err == nil {
@@ -26,109 +86,114 @@ func main() {
 			fmt.Printf("Arquivo %s existente foi deletado. Criando novo arquivo.\n", filename)
 		}
 	}
+	if err := os.Remove(storage.StatsPath(filename)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		fmt.Printf("Aviso: não foi possível deletar o arquivo %s existente: %v\n", storage.StatsPath(filename), err)
+	}
 
 	reader := bufio.NewReader(os.Stdin)
 
 	numRecords := readInt(reader, "Digite o número de registros a serem gerados: ")
-	blockSize := readInt(reader, "Digite o tamanho máximo do bloco (em bytes): ")
-
-	fmt.Println("\nModo de armazenamento:")
-	fmt.Println("1 - Registros de tamanho fixo")
-	fmt.Println("2 - Registros de tamanho variável")
-	storageMode := readInt(reader, "Escolha o modo (1 ou 2): ")
+	blockSize := readOptionalInt(reader, "Digite o tamanho máximo do bloco (em bytes, vazio para recomendar): ")
 
-	var storageImpl storage.Storage
-	var err error
-
-	if storageMode == 1 {
-		storageImpl, err = storage.NewFixedStorage(blockSize)
-		if err != nil {
-			fmt.Printf("Erro: %v\n", err)
-			return
-		}
-	} else if storageMode == 2 {
-		fmt.Println("\nTipo de armazenamento variável:")
-		fmt.Println("1 - Contíguo (sem espalhamento)")
-		fmt.Println("2 - Espalhado (com fragmentação entre blocos)")
-		fragmentedMode := readInt(reader, "Escolha o tipo (1 ou 2): ")
+	mode := readMode(reader)
 
-		if fragmentedMode == 1 {
-			storageImpl, err = storage.NewVariableStorage(blockSize)
-			if err != nil {
-				fmt.Printf("Erro: %v\n", err)
-				return
-			}
-		} else if fragmentedMode == 2 {
-			storageImpl, err = storage.NewVariableFragmentedStorage(blockSize)
-			if err != nil {
-				fmt.Printf("Erro: %v\n", err)
-				return
-			}
-		} else {
-			fmt.Println("Tipo inválido, usando contíguo por padrão")
-			storageImpl, err = storage.NewVariableStorage(blockSize)
-			if err != nil {
-				fmt.Printf("Erro: %v\n", err)
-				return
-			}
-		}
-	} else {
-		fmt.Println("Modo inválido, usando tamanho variável contíguo por padrão")
-		storageImpl, err = storage.NewVariableStorage(blockSize)
-		if err != nil {
-			fmt.Printf("Erro: %v\n", err)
+	if blockSize == 0 {
+		var ok bool
+		blockSize, ok = adviseBlockSize(reader, mode, textEncoding, numRecords, generator)
+		if !ok {
 			return
 		}
 	}
 
-	fmt.Println("\nGerando registros de alunos...")
-	generator := domain.NewStudentGenerator()
-	students := generator.Generate(numRecords)
-	fmt.Printf("Gerados %d registros de alunos\n", len(students))
-
-	fmt.Println("\nGravando registros no arquivo alunos.dat...")
-	err = storageImpl.WriteStudents(filename, students)
+	handle, err := storage.Open(filename, storage.Options{
+		Mode:        mode,
+		BlockSize:   blockSize,
+		LockTimeout: lockTimeout,
+		Encoding:    textEncoding,
+	})
 	if err != nil {
+		fmt.Printf("Erro: %v\n", err)
+		return
+	}
+	defer handle.Close()
+
+	fmt.Println("\nGerando e gravando registros no arquivo alunos.dat... (Ctrl+C para cancelar)")
+	err = runOperation(func(ctx context.Context) error {
+		return handle.WriteStudentsFrom(ctx, generator.Stream(numRecords), int64(numRecords))
+	})
+	if errors.Is(err, context.Canceled) {
+		fmt.Println("Gravação cancelada. O arquivo não foi alterado.")
+	} else if err != nil {
 		fmt.Printf("Erro ao gravar arquivo: %v\n", err)
 		return
+	} else {
+		fmt.Printf("Gravados %d registros de alunos\n", numRecords)
+		fmt.Println("Arquivo gravado com sucesso!")
+		showStorageReport(handle)
 	}
-	fmt.Println("Arquivo gravado com sucesso!")
 
-	stats := storageImpl.GetStats(filename)
-	reporter := infrastructure.NewReporter(stats)
-	reporter.PrintStats()
-	reporter.PrintBlockMap()
-	reporter.PrintBlockVisualization()
+	runQueryMode(reader, handle, generator)
+}
+
+func newStudentGenerator(flags *flag.FlagSet, seed uint64, profileSpec string) (*domain.StudentGenerator, error) {
+	profile, err := domain.ResolveProfile(profileSpec)
+	if err != nil {
+		return nil, err
+	}
 
-	runQueryMode(reader, storageImpl)
+	generator := domain.NewStudentGeneratorWithProfile(rand.Uint64(), profile)
+	flags.Visit(func(f *flag.Flag) {
+		if f.Name == "seed" {
+			generator = domain.NewStudentGeneratorWithProfile(seed, profile)
+		}
+	})
+	fmt.Printf("Perfil do gerador: %s\n", profile.Name)
+	fmt.Printf("Semente do gerador: %d (use -seed %d para repetir os mesmos dados)\n", generator.Seed(), generator.Seed())
+	return generator, nil
 }
 
-func runQueryMode(reader *bufio.Reader, storageImpl storage.Storage) {
+func runQueryMode(reader *bufio.Reader, handle *storage.Handle, generator *domain.StudentGenerator) {
 	for {
 		fmt.Println("\n=== MENU PRINCIPAL ===")
 		fmt.Println("1 - Consultar aluno por matrícula")
 		fmt.Println("2 - Consultar todos os alunos")
 		fmt.Println("3 - Registrar novos alunos")
 		fmt.Println("4 - Ver relatório de armazenamento")
-		fmt.Println("5 - Sair")
+		fmt.Println("5 - Exportar relatório para arquivo")
+		fmt.Println("6 - Exportar mapa de ocupação (HTML/SVG)")
+		fmt.Println("7 - Ampliar intervalo de blocos")
+		fmt.Println("8 - Sair")
 		option := readInt(reader, "Escolha uma opção: ")
 
 		switch option {
 		case 1:
-			matricula := readInt(reader, "Digite a matrícula do aluno: ")
-			student, err := storageImpl.FindStudentByMatricula(filename, matricula)
-			if err != nil {
+			matricula := int64(readInt(reader, "Digite a matrícula do aluno: "))
+			var student *entity.Student
+			err := runOperation(func(ctx context.Context) error {
+				var err error
+				student, err = handle.FindStudentByMatricula(ctx, matricula)
+				return err
+			})
+			if errors.Is(err, context.Canceled) {
+				fmt.Println("Consulta cancelada.")
+			} else if err != nil {
 				fmt.Printf("Erro: %v\n", err)
 			} else {
 				printStudent(student)
 			}
 		case 2:
-			listAllStudents(storageImpl)
+			listAllStudents(handle)
 		case 3:
-			registerNewStudents(reader, storageImpl)
+			registerNewStudents(reader, handle, generator)
 		case 4:
-			showStorageReport(storageImpl)
+			showStorageReport(handle)
 		case 5:
+			exportStorageReport(reader, handle)
+		case 6:
+			exportOccupancyMap(reader, handle)
+		case 7:
+			zoomBlockMap(reader, handle)
+		case 8:
 			return
 		default:
 			fmt.Println("Opção inválida!")
@@ -136,9 +201,18 @@ func runQueryMode(reader *bufio.Reader, storageImpl storage.Storage) {
 	}
 }
 
-func listAllStudents(storageImpl storage.Storage) {
+func listAllStudents(handle *storage.Handle) {
 	fmt.Println("\n=== TODOS OS ALUNOS ===")
-	students, err := storageImpl.GetAllStudents(filename)
+	var students []*entity.Student
+	err := runOperation(func(ctx context.Context) error {
+		var err error
+		students, err = handle.GetAllStudents(ctx)
+		return err
+	})
+	if errors.Is(err, context.Canceled) {
+		fmt.Println("Listagem cancelada.")
+		return
+	}
 	if err != nil {
 		fmt.Printf("Erro ao listar alunos: %v\n", err)
 		return
@@ -156,32 +230,291 @@ func listAllStudents(storageImpl storage.Storage) {
 	}
 }
 
-func registerNewStudents(reader *bufio.Reader, storageImpl storage.Storage) {
+func registerNewStudents(reader *bufio.Reader, handle *storage.Handle, generator *domain.StudentGenerator) {
 	fmt.Println("\n=== REGISTRAR NOVOS ALUNOS ===")
 	numRecords := readInt(reader, "Digite o número de alunos a serem gerados: ")
 	
-	fmt.Println("\nGerando novos alunos...")
-	generator := domain.NewStudentGenerator()
-	students := generator.Generate(numRecords)
-	fmt.Printf("Gerados %d novos alunos\n", len(students))
-
-	fmt.Println("\nAdicionando alunos ao arquivo...")
-	err := storageImpl.AddStudents(filename, students)
+	fmt.Println("\nGerando e adicionando alunos ao arquivo...")
+	var first int64
+	err := runOperation(func(ctx context.Context) error {
+		highest, err := handle.MaxMatricula(ctx)
+		if err != nil {
+			return err
+		}
+		if highest >= generator.NextMatricula() {
+			if err := generator.StartAt(highest + 1); err != nil {
+				return err
+			}
+		}
+		first = generator.NextMatricula()
+		return handle.AddStudentsFrom(ctx, generator.Stream(numRecords))
+	})
+	if errors.Is(err, context.Canceled) {
+		fmt.Println("Registro cancelado. O arquivo não foi alterado.")
+		return
+	}
 	if err != nil {
 		fmt.Printf("Erro ao adicionar alunos: %v\n", err)
 		return
 	}
+	fmt.Printf("Adicionados %d novos alunos (matrículas %d a %d)\n", numRecords, first, generator.NextMatricula()-1)
 	fmt.Println("Alunos adicionados com sucesso!")
 }
 
-func showStorageReport(storageImpl storage.Storage) {
-	stats := storageImpl.GetStats(filename)
-	reporter := infrastructure.NewReporter(stats)
-	reporter.PrintStats()
-	reporter.PrintBlockMap()
-	reporter.PrintBlockVisualization()
+func adviseBlockSize(reader *bufio.Reader, mode storage.Mode, textEncoding storage.Encoding, numRecords int, generator *domain.StudentGenerator) (int, bool) {
+	fmt.Println("\nObjetivo da recomendação:")
+	fmt.Println("1 - Maior eficiência de armazenamento")
+	fmt.Println("2 - Menos leituras de bloco por busca")
+	goal := storage.MaximizeEfficiency
+	if readInt(reader, "Escolha o objetivo (1 ou 2): ") == 2 {
+		goal = storage.MinimizeLookupReads
+	}
+
+	constraints := []storage.BlockSizeConstraint{storage.AnyBlockSize, storage.PowerOfTwoBlockSize, storage.PageMultipleBlockSize}
+	fmt.Println("\nTamanhos candidatos:")
+	for i, constraint := range constraints {
+		fmt.Printf("%d - %s\n", i+1, constraint)
+	}
+	option := readInt(reader, "Escolha os candidatos (1 a 3): ")
+	constraint := storage.AnyBlockSize
+	if option >= 1 && option <= len(constraints) {
+		constraint = constraints[option-1]
+	}
+
+	maxBlockSize := storage.DefaultMaxAdvisedBlockSize
+	if input := readLine(reader, fmt.Sprintf("Maior tamanho de bloco a considerar [%d]: ", maxBlockSize)); input != "" {
+		value, err := strconv.Atoi(input)
+		if err != nil || value <= 0 {
+			fmt.Println("Valor inválido!")
+			return 0, false
+		}
+		maxBlockSize = value
+	}
+
+	fmt.Println("\nSimulando o empacotamento dos registros...")
+	sample, err := domain.NewStudentGeneratorWithProfile(generator.Seed(), generator.Profile()).Generate(min(numRecords, advisorSampleSize))
+	if err != nil {
+		fmt.Printf("Erro ao gerar a amostra: %v\n", err)
+		return 0, false
+	}
+	advice, err := storage.AdviseBlockSize(sample, storage.AdvisorOptions{
+		Goal:         goal,
+		Constraint:   constraint,
+		MaxBlockSize: maxBlockSize,
+		Records:      int64(numRecords),
+		Encoding:     textEncoding,
+	})
+	if err != nil {
+		fmt.Printf("Erro: %v\n", err)
+		return 0, false
+	}
+
+	infrastructure.NewAdviceReporter(advice).PrintAdvice(mode)
+	recommended, found := advice.RecommendationFor(mode)
+	if !found {
+		return readInt(reader, "\nDigite o tamanho máximo do bloco (em bytes): "), true
+	}
+
+	answer := readLine(reader, fmt.Sprintf("\nUsar blocos de %d bytes? (S/n): ", recommended.BlockSize))
+	if strings.EqualFold(answer, "n") {
+		return readInt(reader, "Digite o tamanho máximo do bloco (em bytes): "), true
+	}
+	return recommended.BlockSize, true
+}
+
+func showStorageReport(handle *storage.Handle) {
+	stats, ok := loadStorageStats(handle)
+	if !ok {
+		return
+	}
+
+	infrastructure.NewReporter(stats).PrintReport()
+}
+
+func zoomBlockMap(reader *bufio.Reader, handle *storage.Handle) {
+	stats, ok := loadStorageStats(handle)
+	if !ok {
+		return
+	}
+	if stats.TotalBlocks == 0 {
+		fmt.Println("O arquivo não possui blocos.")
+		return
+	}
+
+	first := int64(readInt(reader, fmt.Sprintf("Bloco inicial (1 a %d): ", stats.TotalBlocks)))
+	last := int64(readInt(reader, fmt.Sprintf("Bloco final (%d a %d): ", first, stats.TotalBlocks)))
+	if first < 1 || first > stats.TotalBlocks || last < first {
+		fmt.Println("Intervalo inválido!")
+		return
+	}
+
+	reporter := infrastructure.NewReporter(stats).Zoom(first-1, last-1)
+	reporter.PrintBlockRuns()
+	reporter.PrintHeatmap()
+	if last-first < zoomDetailLimit {
+		reporter.PrintBlockMap()
+		reporter.PrintBlockVisualization()
+	}
+}
+
+func exportStorageReport(reader *bufio.Reader, handle *storage.Handle) {
+	fmt.Println("\n=== EXPORTAR RELATÓRIO ===")
+	for i, format := range infrastructure.ReportFormats {
+		fmt.Printf("%d - %s\n", i+1, format)
+	}
+	option := readInt(reader, "Escolha o formato: ")
+	if option > len(infrastructure.ReportFormats) {
+		fmt.Println("Formato inválido!")
+		return
+	}
+	format := infrastructure.ReportFormats[option-1]
+
+	defaultPath := "relatorio" + format.Extension()
+	path := readLine(reader, fmt.Sprintf("Nome do arquivo [%s]: ", defaultPath))
+	if path == "" {
+		path = defaultPath
+	}
+
+	stats, ok := loadStorageStats(handle)
+	if !ok {
+		return
+	}
+
+	if err := writeReport(path, format, stats); err != nil {
+		fmt.Printf("Erro ao exportar relatório: %v\n", err)
+		return
+	}
+	fmt.Printf("Relatório exportado para %s\n", path)
+}
+
+func writeReport(path string, format infrastructure.ReportFormat, stats storage.StorageStats) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if err := infrastructure.NewReporterTo(file, stats).Export(format); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+func exportOccupancyMap(reader *bufio.Reader, handle *storage.Handle) {
+	fmt.Println("\n=== EXPORTAR MAPA DE OCUPAÇÃO ===")
+	for i, format := range infrastructure.MapFormats {
+		fmt.Printf("%d - %s\n", i+1, format)
+	}
+	option := readInt(reader, "Escolha o formato: ")
+	if option > len(infrastructure.MapFormats) {
+		fmt.Println("Formato inválido!")
+		return
+	}
+	format := infrastructure.MapFormats[option-1]
+
+	stats, ok := loadStorageStats(handle)
+	if !ok {
+		return
+	}
+	maps := []infrastructure.OccupancyMap{{Title: fmt.Sprintf("%s (%s)", filename, handle.Mode()), Stats: stats}}
+
+	comparePath := readLine(reader, "Arquivo para comparação lado a lado (vazio para nenhum): ")
+	if comparePath != "" {
+		compareMode := readMode(reader)
+		compareBlockSize := readInt(reader, "Tamanho do bloco do arquivo de comparação (em bytes): ")
+		compareEncoding, err := storage.ParseEncoding(readLine(reader, "Codificação do arquivo de comparação (utf-8 ou latin1) [utf-8]: "))
+		if err != nil {
+			fmt.Printf("Erro: %v\n", err)
+			return
+		}
+		compareStats, err := loadFileStats(comparePath, compareMode, compareBlockSize, compareEncoding)
+		if err != nil {
+			fmt.Printf("Erro ao abrir arquivo de comparação: %v\n", err)
+			return
+		}
+		maps = append(maps, infrastructure.OccupancyMap{Title: fmt.Sprintf("%s (%s)", comparePath, compareMode), Stats: compareStats})
+	}
+
+	defaultPath := "mapa" + format.Extension()
+	path := readLine(reader, fmt.Sprintf("Nome do arquivo [%s]: ", defaultPath))
+	if path == "" {
+		path = defaultPath
+	}
+
+	file, err := os.Create(path)
+	if err == nil {
+		err = infrastructure.WriteOccupancyMap(file, format, maps...)
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	if err != nil {
+		fmt.Printf("Erro ao exportar mapa: %v\n", err)
+		return
+	}
+	fmt.Printf("Mapa de ocupação exportado para %s\n", path)
+}
+
+func loadFileStats(path string, mode storage.Mode, blockSize int, textEncoding storage.Encoding) (storage.StorageStats, error) {
+	if _, err := os.Stat(path); err != nil {
+		return storage.StorageStats{}, err
+	}
+
+	handle, err := storage.Open(path, storage.Options{
+		Mode:        mode,
+		BlockSize:   blockSize,
+		LockTimeout: lockTimeout,
+		ReadOnly:    true,
+		Encoding:    textEncoding,
+	})
+	if err != nil {
+		return storage.StorageStats{}, err
+	}
+	defer handle.Close()
+
+	var stats storage.StorageStats
+	err = runOperation(func(ctx context.Context) error {
+		var err error
+		stats, err = handle.GetBlockStats(ctx)
+		return err
+	})
+	return stats, err
+}
+
+func loadStorageStats(handle *storage.Handle) (storage.StorageStats, bool) {
+	var stats storage.StorageStats
+	err := runOperation(func(ctx context.Context) error {
+		var err error
+		stats, err = handle.GetBlockStats(ctx)
+		if err != nil || !*recompute {
+			return err
+		}
+
+		persisted := stats
+		stats, err = handle.RecomputeStats(ctx)
+		if err == nil && !reflect.DeepEqual(persisted, stats) {
+			fmt.Println("Aviso: estatísticas persistidas divergiam da varredura completa e foram regravadas.")
+		}
+		return err
+	})
+	if errors.Is(err, context.Canceled) {
+		fmt.Println("Relatório cancelado.")
+		return stats, false
+	}
+	if err != nil {
+		fmt.Printf("Erro ao calcular estatísticas: %v\n", err)
+		return stats, false
+	}
+	return stats, true
 }
 
+func runOperation(operation func(ctx context.Context) error) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	progressBar := infrastructure.NewProgressBar()
+	return operation(storage.WithProgress(ctx, progressBar.Update))
+}
 
 func printStudent(student *entity.Student) {
 	fmt.Println("\n=== DADOS DO ALUNO ===")
@@ -195,6 +528,55 @@ func printStudent(student *entity.Student) {
 	fmt.Printf("CA:             %.2f\n", student.CA)
 }
 
+func readMode(reader *bufio.Reader) storage.Mode {
+	fmt.Println("\nModo de armazenamento:")
+	fmt.Println("1 - Registros de tamanho fixo")
+	fmt.Println("2 - Registros de tamanho variável")
+	storageMode := readInt(reader, "Escolha o modo (1 ou 2): ")
+
+	if storageMode == 1 {
+		return storage.FixedMode
+	}
+	if storageMode != 2 {
+		fmt.Println("Modo inválido, usando tamanho variável contíguo por padrão")
+		return storage.VariableMode
+	}
+
+	fmt.Println("\nTipo de armazenamento variável:")
+	fmt.Println("1 - Contíguo (sem espalhamento)")
+	fmt.Println("2 - Espalhado (com fragmentação entre blocos)")
+	fragmentedMode := readInt(reader, "Escolha o tipo (1 ou 2): ")
+
+	if fragmentedMode == 1 {
+		return storage.VariableMode
+	}
+	if fragmentedMode == 2 {
+		return storage.FragmentedMode
+	}
+	fmt.Println("Tipo inválido, usando contíguo por padrão")
+	return storage.VariableMode
+}
+
+func readLine(reader *bufio.Reader, prompt string) string {
+	fmt.Print(prompt)
+	input, _ := reader.ReadString('\n')
+	return strings.TrimSpace(input)
+}
+
+func readOptionalInt(reader *bufio.Reader, prompt string) int {
+	for {
+		input := readLine(reader, prompt)
+		if input == "" {
+			return 0
+		}
+		value, err := strconv.Atoi(input)
+		if err == nil && value > 0 {
+			return value
+		}
+		fmt.Println("Valor inválido. Digite um número inteiro positivo.")
+	}
+}
+
 func readInt(reader *bufio.Reader, prompt string) int {
 	for {
 		fmt.Print(prompt)
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"aeds2-tp1/domain"
+	"aeds2-tp1/entity"
+	"aeds2-tp1/infrastructure"
+	"aeds2-tp1/storage"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+)
+
+func runRoundTrip(args []string) error {
+	flags := flag.NewFlagSet("roundtrip", flag.ContinueOnError)
+	caseNames := make([]string, 0, len(domain.EdgeCases))
+	for _, edgeCase := range domain.EdgeCases {
+		caseNames = append(caseNames, string(edgeCase))
+	}
+	caseName := flags.String("case", "todos", "caso de borda: "+strings.Join(caseNames, ", ")+" ou todos")
+	numRecords := flags.Int("records", 200, "número de registros gerados em cada caso")
+	blockSizesSpec := flags.String("block-sizes", "167,183,200,256,512,4096", "tamanhos de bloco: lista (256,512), intervalo dobrando (256-4096) ou intervalo com passo (256-4096:256)")
+	dir := flags.String("dir", "", "diretório dos arquivos temporários (padrão o diretório temporário do sistema)")
+	seed := flags.Uint64("seed", 0, "semente do gerador de dados (sem a opção, uma semente aleatória é sorteada e exibida)")
+	encodingName := flags.String("encoding", "utf-8", "codificação dos textos nos arquivos: utf-8 ou latin1 (ISO-8859-1)")
+	profile := flags.String("profile", domain.DefaultProfileName, "perfil do gerador para os campos não forçados pelo caso")
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return err
+	}
+
+	if *numRecords <= 0 {
+		return fmt.Errorf("número de registros deve ser positivo")
+	}
+	edgeCases := domain.EdgeCases
+	if *caseName != "todos" {
+		if !slices.Contains(caseNames, *caseName) {
+			return fmt.Errorf("caso de borda desconhecido: %q", *caseName)
+		}
+		edgeCases = []domain.EdgeCase{domain.EdgeCase(*caseName)}
+	}
+
+	blockSizes, err := infrastructure.ParseBlockSizes(*blockSizesSpec)
+	if err != nil {
+		return err
+	}
+
+	textEncoding, err := storage.ParseEncoding(*encodingName)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	generator, err := newStudentGenerator(flags, *seed, *profile)
+	if err != nil {
+		return err
+	}
+
+	divergent, rejected := 0, 0
+	for _, edgeCase := range edgeCases {
+		datasetSizes := [][]int{blockSizes}
+		if edgeCase == domain.BlockBoundaryEdgeCase {
+			datasetSizes = make([][]int, 0, len(blockSizes))
+			for _, blockSize := range blockSizes {
+				datasetSizes = append(datasetSizes, []int{blockSize})
+			}
+		}
+
+		results := make([]infrastructure.RoundTripResult, 0)
+		for _, sizes := range datasetSizes {
+			students, err := domain.NewStudentGeneratorWithProfile(generator.Seed(), generator.Profile()).GenerateEdgeCase(edgeCase, *numRecords, domain.EdgeCaseOptions{
+				BlockSize:  sizes[0],
+				RecordSize: variableRecordSize(textEncoding),
+			})
+			if err != nil {
+				fmt.Printf("Caso %s, bloco de %d bytes: %v\n", edgeCase, sizes[0], err)
+				continue
+			}
+
+			caseResults, err := infrastructure.RunRoundTrip(ctx, students, infrastructure.RoundTripOptions{
+				BlockSizes: sizes,
+				Dir:        *dir,
+				Encoding:   textEncoding,
+			})
+			if errors.Is(err, context.Canceled) {
+				return fmt.Errorf("verificação cancelada")
+			}
+			if err != nil {
+				return err
+			}
+			results = append(results, caseResults...)
+		}
+
+		reporter := infrastructure.NewRoundTripReporter(results)
+		reporter.PrintSummary(string(edgeCase))
+		divergent += reporter.Divergent()
+		rejected += reporter.Rejected()
+	}
+
+	if divergent > 0 || rejected > 0 {
+		return fmt.Errorf("%d execuções leram dados diferentes dos gravados e %d recusaram a gravação de registros válidos", divergent, rejected)
+	}
+	fmt.Println("\nTodos os registros gravados foram lidos de volta sem diferenças.")
+	return nil
+}
+
+func variableRecordSize(textEncoding storage.Encoding) func(entity.Student) int {
+	return func(student entity.Student) int {
+		size, _ := storage.RecordSize(storage.VariableMode, textEncoding, student)
+		return size
+	}
+}
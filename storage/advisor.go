@@ -0,0 +1,261 @@
+package storage
+
+import (
+	"aeds2-tp1/entity"
+	"cmp"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"slices"
+)
+
+const (
+	DefaultMaxAdvisedBlockSize = 16384
+	advisorSampleBlockSize     = 1 << 20
+	minSimulatedBlocks         = 32
+)
+
+type BlockSizeGoal int
+
+const (
+	MaximizeEfficiency BlockSizeGoal = iota + 1
+	MinimizeLookupReads
+)
+
+func (g BlockSizeGoal) String() string {
+	switch g {
+	case MaximizeEfficiency:
+		return "maior eficiência"
+	case MinimizeLookupReads:
+		return "menos leituras por busca"
+	default:
+		return fmt.Sprintf("objetivo %d", int(g))
+	}
+}
+
+type BlockSizeConstraint int
+
+const (
+	AnyBlockSize BlockSizeConstraint = iota
+	PowerOfTwoBlockSize
+	PageMultipleBlockSize
+)
+
+func (c BlockSizeConstraint) String() string {
+	switch c {
+	case AnyBlockSize:
+		return "qualquer tamanho"
+	case PowerOfTwoBlockSize:
+		return "potências de 2"
+	case PageMultipleBlockSize:
+		return fmt.Sprintf("múltiplos da página do sistema (%d bytes)", os.Getpagesize())
+	default:
+		return fmt.Sprintf("restrição %d", int(c))
+	}
+}
+
+type AdvisorOptions struct {
+	Modes        []Mode
+	Goal         BlockSizeGoal
+	Constraint   BlockSizeConstraint
+	MinBlockSize int
+	MaxBlockSize int
+	Records      int64
+	Encoding     Encoding
+}
+
+type BlockSizeCandidate struct {
+	Mode            Mode
+	BlockSize       int
+	Blocks          int64
+	RecordsPerBlock float64
+	EfficiencyRate  float64
+	AvgLookupReads  float64
+}
+
+type BlockSizeAdvice struct {
+	Goal        BlockSizeGoal
+	Constraint  BlockSizeConstraint
+	SampleSize  int
+	Records     int64
+	Candidates  []BlockSizeCandidate
+	Recommended []BlockSizeCandidate
+}
+
+var ErrNoBlockSizeCandidates = errors.New("nenhum tamanho de bloco candidato atende às restrições")
+
+func AdviseBlockSize(sample []entity.Student, options AdvisorOptions) (BlockSizeAdvice, error) {
+	if len(sample) == 0 {
+		return BlockSizeAdvice{}, errors.New("amostra vazia: não há registros para simular")
+	}
+
+	modes := options.Modes
+	if len(modes) == 0 {
+		modes = []Mode{FixedMode, VariableMode, FragmentedMode}
+	}
+	if options.Goal == 0 {
+		options.Goal = MaximizeEfficiency
+	}
+	if options.MaxBlockSize == 0 {
+		options.MaxBlockSize = DefaultMaxAdvisedBlockSize
+	}
+	if options.Records <= 0 {
+		options.Records = int64(len(sample))
+	}
+
+	blockSizes := candidateBlockSizes(options.Constraint, max(options.MinBlockSize, 1), options.MaxBlockSize)
+	if len(blockSizes) == 0 {
+		return BlockSizeAdvice{}, ErrNoBlockSizeCandidates
+	}
+
+	advice := BlockSizeAdvice{
+		Goal:       options.Goal,
+		Constraint: options.Constraint,
+		SampleSize: len(sample),
+		Records:    options.Records,
+	}
+	for _, mode := range modes {
+		l, err := newLayout(Options{Mode: mode, BlockSize: advisorSampleBlockSize, Encoding: options.Encoding})
+		if err != nil {
+			return BlockSizeAdvice{}, err
+		}
+
+		sizes := make([]int, len(sample))
+		for i, student := range sample {
+			sizes[i] = l.recordSize(student.Record())
+		}
+		largestRecord := slices.Max(sizes)
+
+		modeCandidates := make([]BlockSizeCandidate, 0, len(blockSizes))
+		for _, blockSize := range blockSizes {
+			if l.ValidateBlockSize(blockSize) != nil || (mode != FragmentedMode && largestRecord > blockSize) {
+				continue
+			}
+			modeCandidates = append(modeCandidates, simulatePacking(mode, blockSize, sizes, options.Records))
+		}
+		if len(modeCandidates) == 0 {
+			continue
+		}
+
+		advice.Candidates = append(advice.Candidates, modeCandidates...)
+		advice.Recommended = append(advice.Recommended, slices.MinFunc(modeCandidates, func(a, b BlockSizeCandidate) int {
+			return compareCandidates(options.Goal, a, b)
+		}))
+	}
+
+	if len(advice.Recommended) == 0 {
+		return BlockSizeAdvice{}, ErrNoBlockSizeCandidates
+	}
+	return advice, nil
+}
+
+func (a BlockSizeAdvice) RecommendationFor(mode Mode) (BlockSizeCandidate, bool) {
+	for _, candidate := range a.Recommended {
+		if candidate.Mode == mode {
+			return candidate, true
+		}
+	}
+	return BlockSizeCandidate{}, false
+}
+
+func (a BlockSizeAdvice) Ranking(mode Mode) []BlockSizeCandidate {
+	ranking := make([]BlockSizeCandidate, 0)
+	for _, candidate := range a.Candidates {
+		if candidate.Mode == mode {
+			ranking = append(ranking, candidate)
+		}
+	}
+	slices.SortStableFunc(ranking, func(x, y BlockSizeCandidate) int {
+		return compareCandidates(a.Goal, x, y)
+	})
+	return ranking
+}
+
+func compareCandidates(goal BlockSizeGoal, a, b BlockSizeCandidate) int {
+	efficiency := cmp.Compare(math.Round(b.EfficiencyRate*100), math.Round(a.EfficiencyRate*100))
+	reads := cmp.Compare(math.Round(a.AvgLookupReads*100), math.Round(b.AvgLookupReads*100))
+	if goal == MinimizeLookupReads {
+		return cmp.Or(reads, efficiency, cmp.Compare(a.BlockSize, b.BlockSize))
+	}
+	return cmp.Or(efficiency, reads, cmp.Compare(a.BlockSize, b.BlockSize))
+}
+
+func candidateBlockSizes(constraint BlockSizeConstraint, minBlockSize, maxBlockSize int) []int {
+	blockSizes := make([]int, 0)
+	switch constraint {
+	case PowerOfTwoBlockSize:
+		for size := 1; size <= maxBlockSize; size *= 2 {
+			if size >= minBlockSize {
+				blockSizes = append(blockSizes, size)
+			}
+		}
+	case PageMultipleBlockSize:
+		pageSize := os.Getpagesize()
+		for size := pageSize; size <= maxBlockSize; size += pageSize {
+			if size >= minBlockSize {
+				blockSizes = append(blockSizes, size)
+			}
+		}
+	default:
+		for size := minBlockSize; size <= maxBlockSize; size++ {
+			blockSizes = append(blockSizes, size)
+		}
+	}
+	return blockSizes
+}
+
+func simulatePacking(mode Mode, blockSize int, sizes []int, records int64) BlockSizeCandidate {
+	simulated := max(len(sizes), minSimulatedBlocks*blockSize/slices.Min(sizes))
+
+	var blocks, recordsBeforeLastBlock, recordsInBlock, bytesUsed int64
+	used := 0
+	closeBlock := func() {
+		blocks++
+		recordsBeforeLastBlock += recordsInBlock
+		recordsInBlock = 0
+		used = 0
+	}
+
+	for i := 0; i < simulated; i++ {
+		size := sizes[i%len(sizes)]
+		if mode != FragmentedMode {
+			if used > 0 && used+size > blockSize {
+				closeBlock()
+			}
+			used += size
+			bytesUsed += int64(size)
+			recordsInBlock++
+			continue
+		}
+
+		for remaining := size; remaining > 0; {
+			spaceAvailable := blockSize - used - fragmentHeaderSize
+			if spaceAvailable < 1 {
+				closeBlock()
+				continue
+			}
+			chunkSize := min(remaining, spaceAvailable)
+			used += fragmentHeaderSize + chunkSize
+			bytesUsed += int64(fragmentHeaderSize + chunkSize)
+			remaining -= chunkSize
+		}
+		recordsInBlock++
+	}
+
+	recordsPerBlock := float64(simulated)
+	if blocks > 0 {
+		recordsPerBlock = float64(recordsBeforeLastBlock) / float64(blocks)
+	}
+	bytesPerRecord := float64(bytesUsed) / float64(simulated)
+
+	candidate := BlockSizeCandidate{
+		Mode:            mode,
+		BlockSize:       blockSize,
+		Blocks:          int64(math.Ceil(float64(records) / recordsPerBlock)),
+		RecordsPerBlock: recordsPerBlock,
+	}
+	candidate.EfficiencyRate = min(float64(records)*bytesPerRecord/(float64(candidate.Blocks)*float64(blockSize))*100, 100)
+	candidate.AvgLookupReads = (float64(records)/recordsPerBlock + 1) / 2
+	return candidate
+}
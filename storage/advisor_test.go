@@ -0,0 +1,134 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"math"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestAdviseFixedBlockSize(t *testing.T) {
+	sample := testStudents(t, 43, 50)
+	cases := []struct {
+		name    string
+		options AdvisorOptions
+		want    int
+	}{
+		{"quatro-registros-exatos", AdvisorOptions{Modes: []Mode{FixedMode}, MinBlockSize: 400, MaxBlockSize: 700, Records: 1000}, 668},
+		{"potencias-de-2-eficiencia", AdvisorOptions{Modes: []Mode{FixedMode}, Constraint: PowerOfTwoBlockSize, MaxBlockSize: 4096, Records: 1000}, 1024},
+		{"potencias-de-2-leituras", AdvisorOptions{Modes: []Mode{FixedMode}, Goal: MinimizeLookupReads, Constraint: PowerOfTwoBlockSize, MaxBlockSize: 4096, Records: 1000}, 4096},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			advice, err := AdviseBlockSize(sample, tc.options)
+			if err != nil {
+				t.Fatalf("AdviseBlockSize: %v", err)
+			}
+			recommended, found := advice.RecommendationFor(FixedMode)
+			if !found || recommended.BlockSize != tc.want {
+				t.Fatalf("recomendado %+v, esperado bloco de %d bytes", recommended, tc.want)
+			}
+			if ranking := advice.Ranking(FixedMode); ranking[0] != recommended {
+				t.Fatalf("primeiro da classificação %+v difere do recomendado %+v", ranking[0], recommended)
+			}
+		})
+	}
+
+	advice, err := AdviseBlockSize(sample, AdvisorOptions{Modes: []Mode{FixedMode}, Constraint: PowerOfTwoBlockSize, MaxBlockSize: 4096, Records: 1000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[int]BlockSizeCandidate{
+		256:  {Mode: FixedMode, BlockSize: 256, Blocks: 1000, RecordsPerBlock: 1, AvgLookupReads: 500.5},
+		1024: {Mode: FixedMode, BlockSize: 1024, Blocks: 167, RecordsPerBlock: 6, AvgLookupReads: (1000.0/6 + 1) / 2},
+	}
+	for _, candidate := range advice.Candidates {
+		expected, ok := want[candidate.BlockSize]
+		if !ok {
+			continue
+		}
+		expected.EfficiencyRate = 1000 * 167 / (float64(expected.Blocks) * float64(expected.BlockSize)) * 100
+		if candidate.Blocks != expected.Blocks || candidate.RecordsPerBlock != expected.RecordsPerBlock ||
+			math.Abs(candidate.EfficiencyRate-expected.EfficiencyRate) > 1e-9 || math.Abs(candidate.AvgLookupReads-expected.AvgLookupReads) > 1e-9 {
+			t.Fatalf("candidato de %d bytes: %+v, esperado %+v", candidate.BlockSize, candidate, expected)
+		}
+		delete(want, candidate.BlockSize)
+	}
+	if len(want) > 0 {
+		t.Fatalf("candidatos ausentes: %v", want)
+	}
+}
+
+func TestAdvisedBlockCountsMatchWrites(t *testing.T) {
+	ctx := context.Background()
+	sample := testStudents(t, 44, 2000)
+	advice, err := AdviseBlockSize(sample, AdvisorOptions{Constraint: PowerOfTwoBlockSize, MaxBlockSize: 4096})
+	if err != nil {
+		t.Fatalf("AdviseBlockSize: %v", err)
+	}
+	if len(advice.Recommended) != len(allModes) {
+		t.Fatalf("%d recomendações, esperado uma por modo", len(advice.Recommended))
+	}
+	for _, candidate := range advice.Candidates {
+		handle, err := OpenDevice(NewMemoryDevice(candidate.BlockSize), Options{Mode: candidate.Mode})
+		if err != nil {
+			t.Fatalf("OpenDevice(%s, %d): %v", candidate.Mode, candidate.BlockSize, err)
+		}
+		if err := handle.WriteStudents(ctx, sample); err != nil {
+			t.Fatalf("WriteStudents(%s, %d): %v", candidate.Mode, candidate.BlockSize, err)
+		}
+		stats, err := handle.GetStats(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		handle.Close()
+		if deviation := math.Abs(float64(candidate.Blocks-stats.TotalBlocks)) / float64(stats.TotalBlocks); deviation > 0.02 {
+			t.Errorf("modo %s, bloco de %d bytes: %d blocos simulados, %d gravados", candidate.Mode, candidate.BlockSize, candidate.Blocks, stats.TotalBlocks)
+		}
+		if math.Abs(candidate.EfficiencyRate-stats.EfficiencyRate) > 2 {
+			t.Errorf("modo %s, bloco de %d bytes: eficiência simulada %.2f%%, gravada %.2f%%", candidate.Mode, candidate.BlockSize, candidate.EfficiencyRate, stats.EfficiencyRate)
+		}
+	}
+}
+
+func TestCandidateBlockSizes(t *testing.T) {
+	page := os.Getpagesize()
+	cases := []struct {
+		constraint BlockSizeConstraint
+		min, max   int
+		want       []int
+	}{
+		{AnyBlockSize, 10, 13, []int{10, 11, 12, 13}},
+		{PowerOfTwoBlockSize, 100, 1024, []int{128, 256, 512, 1024}},
+		{PowerOfTwoBlockSize, 1, 4, []int{1, 2, 4}},
+		{PageMultipleBlockSize, page + 1, 3 * page, []int{2 * page, 3 * page}},
+		{AnyBlockSize, 20, 10, []int{}},
+	}
+	for _, tc := range cases {
+		if got := candidateBlockSizes(tc.constraint, tc.min, tc.max); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("candidateBlockSizes(%s, %d, %d) = %v, esperado %v", tc.constraint, tc.min, tc.max, got, tc.want)
+		}
+	}
+}
+
+func TestAdviseBlockSizeErrors(t *testing.T) {
+	if _, err := AdviseBlockSize(nil, AdvisorOptions{}); err == nil {
+		t.Fatal("AdviseBlockSize aceitou uma amostra vazia")
+	}
+	sample := testStudents(t, 45, 10)
+	if _, err := AdviseBlockSize(sample, AdvisorOptions{MinBlockSize: 1, MaxBlockSize: 150}); !errors.Is(err, ErrNoBlockSizeCandidates) {
+		t.Fatalf("blocos menores que qualquer registro: erro %v, esperado ErrNoBlockSizeCandidates", err)
+	}
+	advice, err := AdviseBlockSize(sample, AdvisorOptions{MinBlockSize: 150, MaxBlockSize: 170})
+	if err != nil {
+		t.Fatalf("AdviseBlockSize: %v", err)
+	}
+	if _, found := advice.RecommendationFor(VariableMode); found {
+		t.Fatal("recomendado bloco abaixo do mínimo do modo variável")
+	}
+	if recommended, found := advice.RecommendationFor(FixedMode); !found || recommended.BlockSize != 167 {
+		t.Fatalf("modo fixo entre 150 e 170 bytes: %+v, esperado 167", recommended)
+	}
+}
@@ -0,0 +1,124 @@
+package storage
+
+import (
+	"sync"
+)
+
+const defaultWriteBatchBlocks = 64
+
+var blockBuffers sync.Pool
+
+func getBlockBuffer(size int) []byte {
+	if buf, ok := blockBuffers.Get().(*[]byte); ok && cap(*buf) >= size {
+		block := (*buf)[:size]
+		clear(block)
+		return block
+	}
+	return make([]byte, size)
+}
+
+func putBlockBuffer(block []byte) {
+	blockBuffers.Put(&block)
+}
+
+type multiBlockWriter interface {
+	writeBlocks(start int64, data []byte) error
+}
+
+type writeBuffer struct {
+	BlockDevice
+	buffer   []byte
+	start    int64
+	count    int
+	capacity int
+}
+
+func newWriteBuffer(device BlockDevice, blocks int) *writeBuffer {
+	if blocks < 1 {
+		blocks = defaultWriteBatchBlocks
+	}
+
+	return &writeBuffer{
+		BlockDevice: device,
+		buffer:      make([]byte, blocks*device.BlockSize()),
+		capacity:    blocks,
+	}
+}
+
+func (wb *writeBuffer) WriteBlock(blockNum int64, data []byte) error {
+	if wb.count > 0 && blockNum != wb.start+int64(wb.count) {
+		if err := wb.flush(); err != nil {
+			return err
+		}
+	}
+	if wb.count == 0 {
+		wb.start = blockNum
+	}
+
+	blockSize := wb.BlockSize()
+	slot := wb.buffer[wb.count*blockSize : (wb.count+1)*blockSize]
+	n := copy(slot, data)
+	clear(slot[n:])
+	wb.count++
+
+	if wb.count == wb.capacity {
+		return wb.flush()
+	}
+	return nil
+}
+
+func (wb *writeBuffer) ReadBlock(blockNum int64, buf []byte) error {
+	if err := wb.flush(); err != nil {
+		return err
+	}
+	return wb.BlockDevice.ReadBlock(blockNum, buf)
+}
+
+func (wb *writeBuffer) BlockCount() (int64, error) {
+	if err := wb.flush(); err != nil {
+		return 0, err
+	}
+	return wb.BlockDevice.BlockCount()
+}
+
+func (wb *writeBuffer) Truncate(blocks int64) error {
+	if err := wb.flush(); err != nil {
+		return err
+	}
+	return wb.BlockDevice.Truncate(blocks)
+}
+
+func (wb *writeBuffer) Sync() error {
+	if err := wb.flush(); err != nil {
+		return err
+	}
+	return wb.BlockDevice.Sync()
+}
+
+func (wb *writeBuffer) Close() error {
+	if err := wb.flush(); err != nil {
+		return err
+	}
+	return wb.BlockDevice.Close()
+}
+
+func (wb *writeBuffer) flush() error {
+	if wb.count == 0 {
+		return nil
+	}
+
+	blockSize := wb.BlockSize()
+	data := wb.buffer[:wb.count*blockSize]
+	start := wb.start
+	wb.count = 0
+
+	if writer, ok := wb.BlockDevice.(multiBlockWriter); ok {
+		return writer.writeBlocks(start, data)
+	}
+	for offset := 0; offset < len(data); offset += blockSize {
+		if err := wb.BlockDevice.WriteBlock(start+int64(offset/blockSize), data[offset:offset+blockSize]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+)
+
+func BenchmarkWrite(b *testing.B) {
+	ctx := context.Background()
+	students := testStudents(b, 41, 20000)
+	for _, mode := range allModes {
+		for _, blockSize := range []int{200, 512, 1024, 4096} {
+			for _, batch := range []int{1, defaultWriteBatchBlocks} {
+				b.Run(fmt.Sprintf("%s/block-%d/batch-%d", mode, blockSize, batch), func(b *testing.B) {
+					path := tempPath(b, "alunos.dat")
+					handle, err := Open(path, Options{Mode: mode, BlockSize: blockSize, WriteBatchBlocks: batch})
+					if err != nil {
+						b.Skipf("tamanho de bloco não suportado: %v", err)
+					}
+					defer handle.Close()
+					if err := handle.WriteStudents(ctx, students); err != nil {
+						b.Fatal(err)
+					}
+					info, err := os.Stat(path)
+					if err != nil {
+						b.Fatal(err)
+					}
+					b.SetBytes(info.Size())
+
+					for b.Loop() {
+						if err := handle.WriteStudents(ctx, students); err != nil {
+							b.Fatal(err)
+						}
+					}
+				})
+			}
+		}
+	}
+}
+
+func TestWriteBatchSizeDoesNotChangeFile(t *testing.T) {
+	ctx := context.Background()
+	students := testStudents(t, 42, 300)
+	for _, mode := range allModes {
+		var files [][]byte
+		for _, batch := range []int{1, 3, defaultWriteBatchBlocks} {
+			path := tempPath(t, "alunos.dat")
+			handle := openTestFile(t, path, Options{Mode: mode, BlockSize: 1024, WriteBatchBlocks: batch})
+			if err := handle.WriteStudents(ctx, students); err != nil {
+				t.Fatalf("WriteStudents: %v", err)
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			files = append(files, data)
+		}
+		for i := 1; i < len(files); i++ {
+			if string(files[i]) != string(files[0]) {
+				t.Fatalf("%s: arquivo gravado em lotes difere do gravado bloco a bloco", mode)
+			}
+		}
+	}
+}
@@ -0,0 +1,235 @@
+package storage
+
+import (
+	"aeds2-tp1/entity"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+const lengthPrefixSize = 4
+
+var ErrZeroRecord = errors.New("registro com todos os bytes zerados não se distingue do espaço livre do bloco")
+
+type recordCodec struct {
+	schema          *entity.Schema
+	encoding        Encoding
+	fixedSize       int
+	maxVariableSize int
+	lengthPrefixes  int
+	canBeZero       bool
+}
+
+func newRecordCodec(schema *entity.Schema, encoding Encoding) *recordCodec {
+	c := &recordCodec{schema: schema, encoding: encoding, canBeZero: true}
+	for _, field := range schema.Fields {
+		width := fieldWidth(field)
+		c.fixedSize += width
+		c.maxVariableSize += width
+		if field.Type == entity.VarStringField {
+			c.maxVariableSize += lengthPrefixSize
+			c.lengthPrefixes += lengthPrefixSize
+			c.canBeZero = false
+		}
+	}
+	return c
+}
+
+func fieldWidth(field entity.Field) int {
+	switch field.Type {
+	case entity.Int32Field, entity.DateField:
+		return 4
+	case entity.Int64Field, entity.DecimalField:
+		return 8
+	default:
+		return field.Length
+	}
+}
+
+func (c *recordCodec) withEncoding(encoding Encoding) *recordCodec {
+	copied := *c
+	copied.encoding = encoding
+	return &copied
+}
+
+func (c *recordCodec) describe(record entity.Record) string {
+	key := c.schema.KeyField()
+	return fmt.Sprintf("%s %v", key.Name, record[c.schema.KeyIndex()])
+}
+
+func (c *recordCodec) encode(record entity.Record, variable bool) ([]byte, error) {
+	if err := c.schema.ValidateRecord(record); err != nil {
+		return nil, err
+	}
+
+	size := c.fixedSize
+	if variable {
+		size = c.maxVariableSize
+	}
+	data := make([]byte, 0, size)
+	for i, field := range c.schema.Fields {
+		switch field.Type {
+		case entity.Int32Field:
+			data = binary.LittleEndian.AppendUint32(data, uint32(int32(record[i].(int64))))
+		case entity.Int64Field:
+			data = binary.LittleEndian.AppendUint64(data, uint64(record[i].(int64)))
+		case entity.DecimalField:
+			data = binary.LittleEndian.AppendUint64(data, uint64(int64(math.Round(record[i].(float64)*math.Pow10(field.Scale)))))
+		case entity.DateField:
+			date := record[i].(time.Time)
+			data = binary.LittleEndian.AppendUint32(data, uint32(date.Year()*10000+int(date.Month())*100+date.Day()))
+		case entity.StringField, entity.VarStringField:
+			text, err := c.encoding.encode(record[i].(string))
+			if err != nil {
+				return nil, fmt.Errorf("%s, campo %s: %w", c.describe(record), field.Name, err)
+			}
+			if field.Type == entity.VarStringField && variable {
+				data = binary.LittleEndian.AppendUint32(data, uint32(len(text)))
+				data = append(data, text...)
+				continue
+			}
+			width := fieldWidth(field)
+			if len(text) > width {
+				return nil, fmt.Errorf("%w: %s, campo %s ocupa %d bytes em %s e a largura é de %d bytes (em ISO-8859-1 cada caractere ocupa um byte)", ErrFieldTooWide, c.describe(record), field.Name, len(text), c.encoding, width)
+			}
+			data = appendPadded(data, text, width)
+		}
+	}
+
+	if c.canBeZero && isZeroed(data) {
+		return nil, fmt.Errorf("%w: %s", ErrZeroRecord, c.describe(record))
+	}
+	return data, nil
+}
+
+func (c *recordCodec) decode(data []byte, variable bool) (entity.Record, int, error) {
+	record := make(entity.Record, len(c.schema.Fields))
+	offset := 0
+	for i, field := range c.schema.Fields {
+		width := fieldWidth(field)
+		if field.Type == entity.VarStringField && variable {
+			if offset+lengthPrefixSize > len(data) {
+				return nil, 0, fmt.Errorf("dados insuficientes para %s", field.Name)
+			}
+			width = int(binary.LittleEndian.Uint32(data[offset:]))
+			offset += lengthPrefixSize
+		}
+		if width < 0 || offset+width > len(data) {
+			return nil, 0, fmt.Errorf("dados insuficientes para %s", field.Name)
+		}
+		value := data[offset : offset+width]
+		offset += width
+
+		switch field.Type {
+		case entity.Int32Field:
+			record[i] = int64(int32(binary.LittleEndian.Uint32(value)))
+		case entity.Int64Field:
+			record[i] = int64(binary.LittleEndian.Uint64(value))
+		case entity.DecimalField:
+			record[i] = float64(int64(binary.LittleEndian.Uint64(value))) / math.Pow10(field.Scale)
+		case entity.DateField:
+			date := int(binary.LittleEndian.Uint32(value))
+			record[i] = time.Date(date/10000, time.Month(date/100%100), date%100, 0, 0, 0, 0, time.UTC)
+		case entity.StringField, entity.VarStringField:
+			if field.Type == entity.StringField || !variable {
+				value = unpadded(value)
+			}
+			text, err := c.encoding.decode(value)
+			if err != nil {
+				return nil, 0, fmt.Errorf("registro deserializado inválido: campo %s: %w", field.Name, err)
+			}
+			record[i] = text
+		}
+	}
+
+	if err := c.schema.ValidateRecord(record); err != nil {
+		return nil, 0, fmt.Errorf("registro deserializado inválido: %w", err)
+	}
+	return record, offset, nil
+}
+
+func (c *recordCodec) key(data []byte, variable bool) (int64, bool) {
+	offset := 0
+	for i, field := range c.schema.Fields {
+		width := fieldWidth(field)
+		if field.Type == entity.VarStringField && variable {
+			if offset+lengthPrefixSize > len(data) {
+				return 0, false
+			}
+			width = int(binary.LittleEndian.Uint32(data[offset:]))
+			offset += lengthPrefixSize
+		}
+		if offset+width > len(data) {
+			return 0, false
+		}
+		if i == c.schema.KeyIndex() {
+			if field.Type == entity.Int32Field {
+				return int64(int32(binary.LittleEndian.Uint32(data[offset:]))), true
+			}
+			return int64(binary.LittleEndian.Uint64(data[offset:])), true
+		}
+		offset += width
+	}
+	return 0, false
+}
+
+func (c *recordCodec) variableSize(record entity.Record) int {
+	size := 0
+	for i, field := range c.schema.Fields {
+		if field.Type != entity.VarStringField {
+			size += fieldWidth(field)
+			continue
+		}
+		text, _ := record[i].(string)
+		size += lengthPrefixSize + c.encoding.encodedLen(text)
+	}
+	return size
+}
+
+func (c *recordCodec) paddingBytes(data []byte) int {
+	padding := 0
+	offset := 0
+	for _, field := range c.schema.Fields {
+		width := fieldWidth(field)
+		if field.Type == entity.StringField || field.Type == entity.VarStringField {
+			padding += width - len(unpadded(data[offset:offset+width]))
+		}
+		offset += width
+	}
+	return padding
+}
+
+func (c *recordCodec) notFound(key int64) error {
+	return fmt.Errorf("registro com %s %d não encontrado", c.schema.KeyField().Name, key)
+}
+
+func appendPadded(data []byte, text string, width int) []byte {
+	data = append(data, text...)
+	if len(text) == width {
+		return data
+	}
+	data = append(data, 0)
+	for range width - len(text) - 1 {
+		data = append(data, '#')
+	}
+	return data
+}
+
+func unpadded(value []byte) []byte {
+	if end := bytes.IndexByte(value, 0); end >= 0 {
+		return value[:end]
+	}
+	return value
+}
+
+func isZeroed(data []byte) bool {
+	for _, b := range data {
+		if b != 0 {
+			return false
+		}
+	}
+	return true
+}
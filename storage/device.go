@@ -0,0 +1,473 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+type BlockDevice interface {
+	BlockSize() int
+	ReadBlock(blockNum int64, buf []byte) error
+	WriteBlock(blockNum int64, data []byte) error
+	BlockCount() (int64, error)
+	Truncate(blocks int64) error
+	Sync() error
+	Close() error
+}
+
+type deviceLocker interface {
+	lock(ctx context.Context, mode lockMode, timeout time.Duration) error
+	unlock()
+}
+
+type deviceReplacer interface {
+	beginReplace() (BlockDevice, error)
+	commitReplace(staged BlockDevice) error
+	abortReplace(staged BlockDevice)
+}
+
+type BlockFile interface {
+	io.ReaderAt
+	io.WriterAt
+	Name() string
+	Fd() uintptr
+	Stat() (os.FileInfo, error)
+	Truncate(size int64) error
+	Sync() error
+	Close() error
+}
+
+type FileDevice struct {
+	file      BlockFile
+	blockSize int
+	path      string
+	readOnly  bool
+	faults    *Faults
+}
+
+func OpenFileDevice(path string, blockSize int, readOnly bool) (*FileDevice, error) {
+	file, err := openDeviceFile(path, readOnly)
+	if err != nil {
+		return nil, err
+	}
+
+	fd := NewFileDevice(file, blockSize)
+	fd.path = path
+	fd.readOnly = readOnly
+	return fd, nil
+}
+
+func openDeviceFile(path string, readOnly bool) (*os.File, error) {
+	var file *os.File
+	var err error
+	if readOnly {
+		file, err = os.Open(path)
+	} else {
+		file, err = os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0666)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("erro ao abrir arquivo: %w", err)
+	}
+	return file, nil
+}
+
+func NewFileDevice(file BlockFile, blockSize int) *FileDevice {
+	return &FileDevice{
+		file:      file,
+		blockSize: blockSize,
+	}
+}
+
+func (fd *FileDevice) BlockSize() int {
+	return fd.blockSize
+}
+
+func (fd *FileDevice) ReadBlock(blockNum int64, buf []byte) error {
+	if _, err := fd.file.ReadAt(buf[:fd.blockSize], blockNum*int64(fd.blockSize)); err != nil {
+		return fmt.Errorf("erro ao ler bloco %d: %w", blockNum, err)
+	}
+	return nil
+}
+
+func (fd *FileDevice) WriteBlock(blockNum int64, data []byte) error {
+	block := data
+	if len(data) != fd.blockSize {
+		block = getBlockBuffer(fd.blockSize)
+		defer putBlockBuffer(block)
+		copy(block, data)
+	}
+
+	n, err := fd.file.WriteAt(block, blockNum*int64(fd.blockSize))
+	if err != nil {
+		return fmt.Errorf("erro ao gravar bloco %d: %w", blockNum, err)
+	}
+	if n != len(block) {
+		return fmt.Errorf("erro ao gravar bloco %d: %w (%d de %d bytes)", blockNum, io.ErrShortWrite, n, len(block))
+	}
+	return nil
+}
+
+func (fd *FileDevice) writeBlocks(start int64, data []byte) error {
+	last := start + int64(len(data)/fd.blockSize) - 1
+	n, err := fd.file.WriteAt(data, start*int64(fd.blockSize))
+	if err != nil {
+		return fmt.Errorf("erro ao gravar blocos %d a %d: %w", start, last, err)
+	}
+	if n != len(data) {
+		return fmt.Errorf("erro ao gravar blocos %d a %d: %w (%d de %d bytes)", start, last, io.ErrShortWrite, n, len(data))
+	}
+	return nil
+}
+
+func (fd *FileDevice) BlockCount() (int64, error) {
+	fileInfo, err := fd.file.Stat()
+	if err != nil {
+		return 0, fmt.Errorf("erro ao obter informações do arquivo: %w", err)
+	}
+	return fileInfo.Size() / int64(fd.blockSize), nil
+}
+
+func (fd *FileDevice) Truncate(blocks int64) error {
+	if err := fd.file.Truncate(blocks * int64(fd.blockSize)); err != nil {
+		return fmt.Errorf("erro ao truncar arquivo: %w", err)
+	}
+	return nil
+}
+
+func (fd *FileDevice) Sync() error {
+	if err := fd.file.Sync(); err != nil {
+		return fmt.Errorf("erro ao sincronizar arquivo: %w", err)
+	}
+	return nil
+}
+
+func (fd *FileDevice) Close() error {
+	if err := fd.file.Close(); err != nil {
+		return fmt.Errorf("erro ao fechar arquivo: %w", err)
+	}
+	return nil
+}
+
+func (fd *FileDevice) lock(ctx context.Context, mode lockMode, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		if err := lockFile(ctx, fd.file, mode, time.Until(deadline)); err != nil {
+			return err
+		}
+
+		replaced, err := fd.replacedOnDisk()
+		if err != nil || !replaced {
+			if err != nil {
+				unlockFile(fd.file)
+			}
+			return err
+		}
+
+		unlockFile(fd.file)
+		file, err := openDeviceFile(fd.path, fd.readOnly)
+		if err != nil {
+			return err
+		}
+		fd.file.Close()
+		fd.file = file
+	}
+}
+
+func (fd *FileDevice) replacedOnDisk() (bool, error) {
+	if fd.path == "" {
+		return false, nil
+	}
+
+	opened, err := fd.file.Stat()
+	if err != nil {
+		return false, fmt.Errorf("erro ao obter informações do arquivo: %w", err)
+	}
+	current, err := os.Stat(fd.path)
+	if err != nil {
+		return false, fmt.Errorf("erro ao obter informações do arquivo: %w", err)
+	}
+	return !os.SameFile(opened, current), nil
+}
+
+func (fd *FileDevice) unlock() {
+	unlockFile(fd.file)
+}
+
+func (fd *FileDevice) beginReplace() (BlockDevice, error) {
+	if fd.path == "" {
+		return copyReplacer{device: fd}.beginReplace()
+	}
+
+	file, err := os.CreateTemp(filepath.Dir(fd.path), filepath.Base(fd.path)+".tmp-*")
+	if err != nil {
+		return nil, fmt.Errorf("erro ao criar arquivo temporário: %w", err)
+	}
+	if err := matchFileMode(file, fd.path); err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return nil, err
+	}
+	if fd.faults != nil {
+		return NewFileDevice(NewFaultyFile(file, *fd.faults), fd.blockSize), nil
+	}
+	return NewFileDevice(file, fd.blockSize), nil
+}
+
+func matchFileMode(file *os.File, originals ...string) error {
+	for _, original := range originals {
+		info, err := os.Stat(original)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("erro ao obter informações do arquivo: %w", err)
+		}
+		if err := file.Chmod(info.Mode().Perm()); err != nil {
+			return fmt.Errorf("erro ao ajustar as permissões do arquivo temporário: %w", err)
+		}
+		return nil
+	}
+	return nil
+}
+
+func (fd *FileDevice) commitReplace(staged BlockDevice) error {
+	stagedFile, ok := staged.(*FileDevice)
+	if !ok {
+		return copyReplacer{device: fd}.commitReplace(staged)
+	}
+
+	if err := stagedFile.Sync(); err != nil {
+		fd.abortReplace(staged)
+		return err
+	}
+	if err := lockFile(context.Background(), stagedFile.file, exclusiveLock, 0); err != nil {
+		fd.abortReplace(staged)
+		return err
+	}
+	if err := fd.rename(stagedFile.file.Name()); err != nil {
+		unlockFile(stagedFile.file)
+		defer fd.abortReplace(staged)
+		return fd.copyReplace(staged)
+	}
+
+	previous := fd.file
+	fd.file = stagedFile.file
+	previous.Close()
+	return nil
+}
+
+func (fd *FileDevice) copyReplace(staged BlockDevice) error {
+	original, err := fd.file.Stat()
+	if err != nil {
+		return fmt.Errorf("erro ao obter informações do arquivo: %w", err)
+	}
+	if err := (copyReplacer{device: fd}).commitReplace(staged); err != nil {
+		if !errors.Is(err, errRestoreFailed) {
+			os.Chtimes(fd.path, time.Time{}, original.ModTime())
+		}
+		return err
+	}
+	return nil
+}
+
+func (fd *FileDevice) abortReplace(staged BlockDevice) {
+	stagedFile, ok := staged.(*FileDevice)
+	if !ok {
+		return
+	}
+	stagedFile.Close()
+	os.Remove(stagedFile.file.Name())
+}
+
+type MemoryDevice struct {
+	mu            sync.RWMutex
+	blocks        [][]byte
+	blockSize     int
+	version       int64
+	stats         StorageStats
+	statsMode     Mode
+	statsEncoding Encoding
+	statsSchema   uint64
+	statsVersion  int64
+	hasStats      bool
+}
+
+func NewMemoryDevice(blockSize int) *MemoryDevice {
+	return &MemoryDevice{
+		blocks:    make([][]byte, 0),
+		blockSize: blockSize,
+	}
+}
+
+func (md *MemoryDevice) BlockSize() int {
+	return md.blockSize
+}
+
+func (md *MemoryDevice) ReadBlock(blockNum int64, buf []byte) error {
+	md.mu.RLock()
+	defer md.mu.RUnlock()
+
+	if blockNum < 0 || blockNum >= int64(len(md.blocks)) {
+		return fmt.Errorf("erro ao ler bloco %d: bloco inexistente", blockNum)
+	}
+	copy(buf[:md.blockSize], md.blocks[blockNum])
+	return nil
+}
+
+func (md *MemoryDevice) WriteBlock(blockNum int64, data []byte) error {
+	md.mu.Lock()
+	defer md.mu.Unlock()
+
+	if blockNum < 0 || blockNum > int64(len(md.blocks)) {
+		return fmt.Errorf("erro ao gravar bloco %d: posição além do fim do dispositivo", blockNum)
+	}
+
+	block := make([]byte, md.blockSize)
+	copy(block, data)
+	md.version++
+	if blockNum == int64(len(md.blocks)) {
+		md.blocks = append(md.blocks, block)
+	} else {
+		md.blocks[blockNum] = block
+	}
+	return nil
+}
+
+func (md *MemoryDevice) BlockCount() (int64, error) {
+	md.mu.RLock()
+	defer md.mu.RUnlock()
+	return int64(len(md.blocks)), nil
+}
+
+func (md *MemoryDevice) Truncate(blocks int64) error {
+	md.mu.Lock()
+	defer md.mu.Unlock()
+
+	md.version++
+	if blocks < int64(len(md.blocks)) {
+		md.blocks = md.blocks[:blocks]
+	}
+	for int64(len(md.blocks)) < blocks {
+		md.blocks = append(md.blocks, make([]byte, md.blockSize))
+	}
+	return nil
+}
+
+func (md *MemoryDevice) beginReplace() (BlockDevice, error) {
+	return NewMemoryDevice(md.blockSize), nil
+}
+
+func (md *MemoryDevice) commitReplace(staged BlockDevice) error {
+	stagedMemory, ok := staged.(*MemoryDevice)
+	if !ok {
+		return copyReplacer{device: md}.commitReplace(staged)
+	}
+
+	stagedMemory.mu.RLock()
+	blocks := stagedMemory.blocks
+	stagedMemory.mu.RUnlock()
+
+	md.mu.Lock()
+	md.blocks = blocks
+	md.version++
+	md.mu.Unlock()
+	return nil
+}
+
+func (md *MemoryDevice) abortReplace(staged BlockDevice) {}
+
+func (md *MemoryDevice) Sync() error {
+	return nil
+}
+
+func (md *MemoryDevice) Close() error {
+	return nil
+}
+
+var errRestoreFailed = errors.New("erro ao restaurar o conteúdo original")
+
+type copyReplacer struct {
+	device BlockDevice
+}
+
+func (cr copyReplacer) beginReplace() (BlockDevice, error) {
+	return NewMemoryDevice(cr.device.BlockSize()), nil
+}
+
+func (cr copyReplacer) commitReplace(staged BlockDevice) error {
+	backup := NewMemoryDevice(cr.device.BlockSize())
+	if err := copyBlocks(cr.device, backup); err != nil {
+		return err
+	}
+
+	if err := copyBlocks(staged, cr.device); err != nil {
+		if restoreErr := copyBlocks(backup, cr.device); restoreErr != nil {
+			return errors.Join(err, fmt.Errorf("%w: %w", errRestoreFailed, restoreErr))
+		}
+		return err
+	}
+	return nil
+}
+
+func copyBlocks(from, to BlockDevice) error {
+	totalBlocks, err := from.BlockCount()
+	if err != nil {
+		return err
+	}
+
+	block := make([]byte, from.BlockSize())
+	for blockNum := int64(0); blockNum < totalBlocks; blockNum++ {
+		if err := from.ReadBlock(blockNum, block); err != nil {
+			return err
+		}
+		if err := to.WriteBlock(blockNum, block); err != nil {
+			return err
+		}
+	}
+	if err := to.Truncate(totalBlocks); err != nil {
+		return err
+	}
+	return to.Sync()
+}
+
+func (cr copyReplacer) abortReplace(staged BlockDevice) {}
+
+type contextDevice struct {
+	BlockDevice
+	ctx      context.Context
+	tracker  *progressTracker
+	onBlock  func(BlockStats) error
+	onRecord func(size int, count int64)
+}
+
+func withContext(ctx context.Context, device BlockDevice, tracker *progressTracker) contextDevice {
+	return contextDevice{BlockDevice: device, ctx: ctx, tracker: tracker}
+}
+
+func (cd contextDevice) ReadBlock(blockNum int64, buf []byte) error {
+	if err := cd.ctx.Err(); err != nil {
+		return err
+	}
+	if err := cd.BlockDevice.ReadBlock(blockNum, buf); err != nil {
+		return err
+	}
+	cd.tracker.addBlock(cd.BlockSize())
+	return nil
+}
+
+func (cd contextDevice) WriteBlock(blockNum int64, data []byte) error {
+	if err := cd.ctx.Err(); err != nil {
+		return err
+	}
+	if err := cd.BlockDevice.WriteBlock(blockNum, data); err != nil {
+		return err
+	}
+	cd.tracker.addBlock(cd.BlockSize())
+	return nil
+}
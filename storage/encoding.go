@@ -0,0 +1,87 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+type Encoding int
+
+const (
+	UTF8Encoding Encoding = iota
+	Latin1Encoding
+)
+
+var (
+	ErrUnencodableCharacter = errors.New("caractere não representável na codificação do arquivo")
+	ErrInvalidText          = errors.New("texto gravado não é válido na codificação do arquivo")
+	ErrEncodingMismatch     = errors.New("codificação diferente da usada na gravação do arquivo")
+)
+
+func (e Encoding) String() string {
+	switch e {
+	case UTF8Encoding:
+		return "UTF-8"
+	case Latin1Encoding:
+		return "ISO-8859-1"
+	default:
+		return fmt.Sprintf("codificação %d", int(e))
+	}
+}
+
+func (e Encoding) MarshalText() ([]byte, error) {
+	return []byte(e.String()), nil
+}
+
+func ParseEncoding(name string) (Encoding, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "", "utf-8", "utf8":
+		return UTF8Encoding, nil
+	case "latin1", "latin-1", "iso-8859-1", "iso8859-1":
+		return Latin1Encoding, nil
+	default:
+		return 0, fmt.Errorf("codificação inválida: %q (use utf-8 ou latin1)", name)
+	}
+}
+
+func (e Encoding) encode(text string) (string, error) {
+	if e != Latin1Encoding {
+		if !utf8.ValidString(text) {
+			return "", ErrInvalidText
+		}
+		return text, nil
+	}
+
+	encoded := make([]byte, 0, len(text))
+	for _, r := range text {
+		if r > 0xFF {
+			return "", fmt.Errorf("%w: %q em %s", ErrUnencodableCharacter, r, e)
+		}
+		encoded = append(encoded, byte(r))
+	}
+	return string(encoded), nil
+}
+
+func (e Encoding) decode(data []byte) (string, error) {
+	if e != Latin1Encoding {
+		if !utf8.Valid(data) {
+			return "", ErrInvalidText
+		}
+		return string(data), nil
+	}
+
+	runes := make([]rune, len(data))
+	for i, b := range data {
+		runes[i] = rune(b)
+	}
+	return string(runes), nil
+}
+
+func (e Encoding) encodedLen(text string) int {
+	if e == Latin1Encoding {
+		return utf8.RuneCountInString(text)
+	}
+	return len(text)
+}
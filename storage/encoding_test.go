@@ -0,0 +1,109 @@
+package storage
+
+import (
+	"aeds2-tp1/entity"
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestBlockSizeMinimumsByEncoding(t *testing.T) {
+	minimums := map[Mode]int{FixedMode: 167, VariableMode: 183, FragmentedMode: 183}
+	for _, encoding := range []Encoding{UTF8Encoding, Latin1Encoding} {
+		for mode, minimum := range minimums {
+			if _, err := newLayout(Options{Mode: mode, BlockSize: minimum, Encoding: encoding}); err != nil {
+				t.Errorf("%s em %s: bloco de %d bytes recusado: %v", mode, encoding, minimum, err)
+			}
+			if _, err := newLayout(Options{Mode: mode, BlockSize: minimum - 1, Encoding: encoding}); err == nil {
+				t.Errorf("%s em %s: bloco de %d bytes aceito", mode, encoding, minimum-1)
+			}
+		}
+	}
+}
+
+func TestUTF8FilesOpenWith512ByteBlocks(t *testing.T) {
+	students := testStudents(t, 70, 50)
+	for _, mode := range []Mode{FixedMode, VariableMode} {
+		path := tempPath(t, "alunos.dat")
+		handle := openTestFile(t, path, Options{Mode: mode, BlockSize: 512})
+		if err := handle.WriteStudents(context.Background(), students); err != nil {
+			t.Fatalf("%s: WriteStudents: %v", mode, err)
+		}
+		handle.Close()
+
+		reopened := openTestFile(t, path, Options{Mode: mode, BlockSize: 512, ReadOnly: true})
+		assertSameStudents(t, readAllStudents(t, reopened), students)
+	}
+}
+
+func TestWideCharacters(t *testing.T) {
+	students := testStudents(t, 71, 4)
+	students[0].Nome = strings.Repeat("李", 50)
+	students[1].Curso = strings.Repeat("🎓", 30)
+	students[2].FiliacaoMae = strings.Repeat("ç", 30)
+	students[3].FiliacaoPai = strings.Repeat("Ł", 29) + "#"
+	for _, mode := range []Mode{VariableMode, FragmentedMode} {
+		t.Run(mode.String(), func(t *testing.T) {
+			roundTrip(t, mode, 1024, UTF8Encoding, students)
+		})
+	}
+
+	handle, err := OpenDevice(NewMemoryDevice(1024), Options{Mode: FixedMode})
+	if err != nil {
+		t.Fatalf("OpenDevice: %v", err)
+	}
+	for _, student := range students {
+		if err := handle.WriteStudents(context.Background(), []entity.Student{student}); !errors.Is(err, ErrFieldTooWide) {
+			t.Fatalf("fixo em UTF-8 gravou texto maior que a largura em bytes: erro %v", err)
+		}
+	}
+
+	latin1 := students[2:3]
+	for _, mode := range allModes {
+		t.Run(mode.String()+"/latin1", func(t *testing.T) {
+			roundTrip(t, mode, 1024, Latin1Encoding, latin1)
+		})
+	}
+}
+
+func TestEncodingIsCheckedOnOpen(t *testing.T) {
+	ctx := context.Background()
+	path := tempPath(t, "alunos.dat")
+	students := testStudents(t, 72, 20)
+	students[0].Nome = "João Conceição"
+
+	writer := openTestFile(t, path, Options{Mode: VariableMode, BlockSize: 1024, Encoding: Latin1Encoding})
+	if err := writer.WriteStudents(ctx, students); err != nil {
+		t.Fatalf("WriteStudents: %v", err)
+	}
+
+	if handle, err := Open(path, Options{Mode: VariableMode, BlockSize: 1024}); !errors.Is(err, ErrEncodingMismatch) {
+		if handle != nil {
+			handle.Close()
+		}
+		t.Fatalf("arquivo ISO-8859-1 aberto como UTF-8: erro %v, esperado ErrEncodingMismatch", err)
+	}
+	reader := openTestFile(t, path, Options{Mode: VariableMode, BlockSize: 1024, Encoding: Latin1Encoding, ReadOnly: true})
+	assertSameStudents(t, readAllStudents(t, reader), students)
+
+	if err := os.Truncate(path, 0); err != nil {
+		t.Fatal(err)
+	}
+	openTestFile(t, path, Options{Mode: VariableMode, BlockSize: 1024})
+}
+
+func TestEncodingIsCheckedOnMemoryDevice(t *testing.T) {
+	device := NewMemoryDevice(1024)
+	handle, err := OpenDevice(device, Options{Mode: FixedMode, Encoding: Latin1Encoding})
+	if err != nil {
+		t.Fatalf("OpenDevice: %v", err)
+	}
+	if err := handle.WriteStudents(context.Background(), testStudents(t, 73, 5)); err != nil {
+		t.Fatalf("WriteStudents: %v", err)
+	}
+	if _, err := OpenDevice(device, Options{Mode: FixedMode}); !errors.Is(err, ErrEncodingMismatch) {
+		t.Fatalf("dispositivo ISO-8859-1 aberto como UTF-8: erro %v, esperado ErrEncodingMismatch", err)
+	}
+}
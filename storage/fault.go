@@ -0,0 +1,95 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+)
+
+var ErrInjectedFault = errors.New("falha de E/S injetada")
+
+type Faults struct {
+	FailWrite     int
+	ShortWrite    int
+	FailRead      int
+	FlipBitOnRead int
+	FlipBitOffset int
+	FailSync      int
+	FailRename    bool
+}
+
+type FaultyFile struct {
+	*os.File
+	mu     sync.Mutex
+	faults Faults
+	writes int
+	reads  int
+	syncs  int
+}
+
+func NewFaultyFile(file *os.File, faults Faults) *FaultyFile {
+	return &FaultyFile{
+		File:   file,
+		faults: faults,
+	}
+}
+
+func (ff *FaultyFile) WriteAt(p []byte, off int64) (int, error) {
+	ff.mu.Lock()
+	ff.writes++
+	count := ff.writes
+	ff.mu.Unlock()
+
+	if count == ff.faults.FailWrite {
+		return 0, fmt.Errorf("%w: escrita %d", ErrInjectedFault, count)
+	}
+	if count == ff.faults.ShortWrite {
+		return ff.File.WriteAt(p[:len(p)/2], off)
+	}
+	return ff.File.WriteAt(p, off)
+}
+
+func (ff *FaultyFile) ReadAt(p []byte, off int64) (int, error) {
+	ff.mu.Lock()
+	ff.reads++
+	count := ff.reads
+	ff.mu.Unlock()
+
+	if count == ff.faults.FailRead {
+		return 0, fmt.Errorf("%w: leitura %d", ErrInjectedFault, count)
+	}
+	n, err := ff.File.ReadAt(p, off)
+	if count == ff.faults.FlipBitOnRead && ff.faults.FlipBitOffset < n {
+		p[ff.faults.FlipBitOffset] ^= 0x01
+	}
+	return n, err
+}
+
+func (ff *FaultyFile) Sync() error {
+	ff.mu.Lock()
+	ff.syncs++
+	count := ff.syncs
+	ff.mu.Unlock()
+
+	if count == ff.faults.FailSync {
+		return fmt.Errorf("%w: sincronização %d", ErrInjectedFault, count)
+	}
+	return ff.File.Sync()
+}
+
+func OpenFaultyFileDevice(path string, blockSize int, faults Faults) (*FileDevice, error) {
+	fd, err := OpenFileDevice(path, blockSize, false)
+	if err != nil {
+		return nil, err
+	}
+	fd.faults = &faults
+	return fd, nil
+}
+
+func (fd *FileDevice) rename(staged string) error {
+	if fd.faults != nil && fd.faults.FailRename {
+		return fmt.Errorf("%w: renomear %s", ErrInjectedFault, staged)
+	}
+	return os.Rename(staged, fd.path)
+}
@@ -0,0 +1,221 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeOriginalFile(t *testing.T, mode Mode) (string, []byte) {
+	t.Helper()
+	path := tempPath(t, "alunos.dat")
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	handle, err := OpenDevice(NewFileDevice(file, 1024), Options{Mode: mode})
+	if err != nil {
+		t.Fatalf("OpenDevice: %v", err)
+	}
+	if err := handle.WriteStudents(context.Background(), testStudents(t, 31, 60)); err != nil {
+		t.Fatalf("WriteStudents: %v", err)
+	}
+	original, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return path, original
+}
+
+func openFaulty(t *testing.T, path string, mode Mode, faults Faults) *Handle {
+	t.Helper()
+	file, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { file.Close() })
+	handle, err := OpenDevice(NewFileDevice(NewFaultyFile(file, faults), 1024), Options{Mode: mode, ScanWorkers: 1})
+	if err != nil {
+		t.Fatalf("OpenDevice: %v", err)
+	}
+	return handle
+}
+
+func assertFileUnchanged(t *testing.T, path string, original []byte) {
+	t.Helper()
+	current, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(current, original) {
+		t.Fatalf("arquivo original alterado após a falha: %d bytes, antes %d", len(current), len(original))
+	}
+}
+
+func TestWriteFaultsKeepOriginalFile(t *testing.T) {
+	ctx := context.Background()
+	cases := []struct {
+		name   string
+		faults Faults
+		want   error
+	}{
+		{"falha-na-primeira-escrita", Faults{FailWrite: 1}, ErrInjectedFault},
+		{"falha-na-terceira-escrita", Faults{FailWrite: 3}, ErrInjectedFault},
+		{"escrita-parcial", Faults{ShortWrite: 2}, io.ErrShortWrite},
+	}
+	for _, mode := range allModes {
+		for _, tc := range cases {
+			t.Run(mode.String()+"/"+tc.name, func(t *testing.T) {
+				path, original := writeOriginalFile(t, mode)
+
+				handle := openFaulty(t, path, mode, tc.faults)
+				if err := handle.WriteStudents(ctx, testStudents(t, 32, 80)); !errors.Is(err, tc.want) {
+					t.Fatalf("WriteStudents com falha injetada: erro %v, esperado %v", err, tc.want)
+				}
+				assertFileUnchanged(t, path, original)
+
+				handle = openFaulty(t, path, mode, tc.faults)
+				if err := handle.AddStudents(ctx, testStudents(t, 31, 70)[60:]); !errors.Is(err, tc.want) {
+					t.Fatalf("AddStudents com falha injetada: erro %v, esperado %v", err, tc.want)
+				}
+				assertFileUnchanged(t, path, original)
+			})
+		}
+	}
+}
+
+func TestFlippedBitOnReadIsReported(t *testing.T) {
+	for _, mode := range allModes {
+		t.Run(mode.String(), func(t *testing.T) {
+			path, original := writeOriginalFile(t, mode)
+			first := testStudents(t, 31, 1)[0]
+			offset := bytes.Index(original[:1024], []byte(first.CPF))
+			if offset < 0 {
+				t.Fatalf("CPF %s do primeiro aluno não está no primeiro bloco", first.CPF)
+			}
+
+			handle := openFaulty(t, path, mode, Faults{FlipBitOnRead: 1, FlipBitOffset: offset + 3})
+			if students, err := handle.GetAllStudents(context.Background()); !errors.Is(err, ErrCorruptData) {
+				t.Fatalf("leitura com bit invertido: %d alunos, erro %v, esperado ErrCorruptData", len(students), err)
+			}
+			assertFileUnchanged(t, path, original)
+		})
+	}
+}
+
+func TestFileReplaceFaultsKeepOriginalFile(t *testing.T) {
+	ctx := context.Background()
+	cases := []struct {
+		name   string
+		faults Faults
+		want   error
+	}{
+		{"falha-na-escrita-do-temporario", Faults{FailWrite: 1}, ErrInjectedFault},
+		{"falha-na-segunda-escrita-do-temporario", Faults{FailWrite: 2}, ErrInjectedFault},
+		{"escrita-parcial-do-temporario", Faults{ShortWrite: 1}, io.ErrShortWrite},
+		{"falha-na-sincronizacao", Faults{FailSync: 1}, ErrInjectedFault},
+		{"falha-ao-renomear-e-ao-copiar", Faults{FailRename: true, FailRead: 2}, ErrInjectedFault},
+	}
+	for _, mode := range allModes {
+		for _, tc := range cases {
+			t.Run(mode.String()+"/"+tc.name, func(t *testing.T) {
+				path := tempPath(t, "alunos.dat")
+				original := testStudents(t, 33, 60)
+				writer := openTestFile(t, path, Options{Mode: mode, BlockSize: 512, WriteBatchBlocks: 1})
+				if err := writer.WriteStudents(ctx, original); err != nil {
+					t.Fatalf("WriteStudents: %v", err)
+				}
+				writer.Close()
+				data := readFile(t, path)
+				stats := readFile(t, path+statsFileSuffix)
+
+				writes := map[string]func(*Handle) error{
+					"WriteStudents": func(handle *Handle) error {
+						return handle.WriteStudents(ctx, testStudents(t, 34, 80))
+					},
+					"AddStudents": func(handle *Handle) error {
+						return handle.AddStudents(ctx, testStudents(t, 33, 90)[60:])
+					},
+				}
+				for name, write := range writes {
+					device, err := OpenFaultyFileDevice(path, 512, tc.faults)
+					if err != nil {
+						t.Fatal(err)
+					}
+					handle, err := OpenDevice(device, Options{Mode: mode, WriteBatchBlocks: 1})
+					if err != nil {
+						t.Fatalf("OpenDevice: %v", err)
+					}
+					if err := write(handle); !errors.Is(err, tc.want) {
+						t.Fatalf("%s com falha injetada: erro %v, esperado %v", name, err, tc.want)
+					}
+					handle.Close()
+
+					if !bytes.Equal(readFile(t, path), data) {
+						t.Fatalf("%s: arquivo de dados alterado após a falha", name)
+					}
+					if !bytes.Equal(readFile(t, path+statsFileSuffix), stats) {
+						t.Fatalf("%s: arquivo de estatísticas alterado após a falha", name)
+					}
+					assertOnlyDataAndStats(t, path)
+
+					reader := openTestFile(t, path, Options{Mode: mode, BlockSize: 512, ReadOnly: true})
+					if _, found := reader.device.(statsStore).loadStats(mode, false); !found {
+						t.Fatalf("%s: estatísticas descartadas após a falha", name)
+					}
+					assertSameStudents(t, readAllStudents(t, reader), original)
+					reader.Close()
+				}
+			})
+		}
+	}
+}
+
+func TestFailedRenameFallsBackToCopy(t *testing.T) {
+	ctx := context.Background()
+	for _, mode := range allModes {
+		t.Run(mode.String(), func(t *testing.T) {
+			path := tempPath(t, "alunos.dat")
+			writer := openTestFile(t, path, Options{Mode: mode, BlockSize: 512})
+			if err := writer.WriteStudents(ctx, testStudents(t, 35, 60)); err != nil {
+				t.Fatalf("WriteStudents: %v", err)
+			}
+			writer.Close()
+
+			device, err := OpenFaultyFileDevice(path, 512, Faults{FailRename: true})
+			if err != nil {
+				t.Fatal(err)
+			}
+			handle, err := OpenDevice(device, Options{Mode: mode})
+			if err != nil {
+				t.Fatalf("OpenDevice: %v", err)
+			}
+			defer handle.Close()
+			replacement := testStudents(t, 36, 40)
+			if err := handle.WriteStudents(ctx, replacement); err != nil {
+				t.Fatalf("WriteStudents com falha ao renomear: %v", err)
+			}
+			assertSameStudents(t, readAllStudents(t, handle), replacement)
+			assertPersistedStatsMatchScan(t, handle, "cópia após falha ao renomear")
+			assertOnlyDataAndStats(t, path)
+		})
+	}
+}
+
+func assertOnlyDataAndStats(t *testing.T, path string) {
+	t.Helper()
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range entries {
+		if name := entry.Name(); name != filepath.Base(path) && name != filepath.Base(path)+statsFileSuffix {
+			t.Fatalf("arquivo temporário %s deixado para trás", name)
+		}
+	}
+}
@@ -2,23 +2,35 @@ package storage
 
 import (
 	"aeds2-tp1/entity"
-	"encoding/binary"
+	"context"
+	"errors"
 	"fmt"
-	"os"
+	"iter"
+	"sync"
+	"time"
 )
 
+var ErrFieldTooWide = errors.New("campo excede a largura fixa do registro")
+
 type FixedStorage struct {
+	mu              sync.RWMutex
 	blockSize       int
 	fixedRecordSize int
-	stats           StorageStats
+	lockTimeout     time.Duration
+	scanWorkers     int
+	codec           *recordCodec
 }
 
 func NewFixedStorage(blockSize int) (*FixedStorage, error) {
+	return newFixedStorage(blockSize, newRecordCodec(entity.StudentSchema(), UTF8Encoding))
+}
+
+func newFixedStorage(blockSize int, codec *recordCodec) (*FixedStorage, error) {
 	fs := &FixedStorage{
-		blockSize: blockSize,
-		stats: StorageStats{
-			BlockStatsList: make([]BlockStats, 0),
-		},
+		blockSize:       blockSize,
+		fixedRecordSize: codec.fixedSize,
+		scanWorkers:     defaultScanWorkers(),
+		codec:           codec,
 	}
 	
 	if err := fs.ValidateBlockSize(blockSize); err != nil {
@@ -28,15 +40,29 @@ func NewFixedStorage(blockSize int) (*FixedStorage, error) {
 	return fs, nil
 }
 
+func (fs *FixedStorage) SetLockTimeout(timeout time.Duration) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	fs.lockTimeout = timeout
+}
+
+func (fs *FixedStorage) SetEncoding(encoding Encoding) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	fs.codec = fs.codec.withEncoding(encoding)
+}
+
+func (fs *FixedStorage) SetScanWorkers(workers int) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	if workers < 1 {
+		workers = 1
+	}
+	fs.scanWorkers = workers
+}
+
 func (fs *FixedStorage) ValidateBlockSize(blockSize int) error {
-	minSize := 4 +
-		entity.MaxNomeLength +
-		entity.CPFLength +
-		entity.MaxCursoLength +
-		entity.MaxFiliacaoLength +
-		entity.MaxFiliacaoLength +
-		4 +
-		8
+	minSize := fs.codec.fixedSize
 	
 	if blockSize < minSize {
 		return fmt.Errorf("tamanho do bloco (%d bytes) é menor que o tamanho mínimo necessário para um registro (%d bytes)", blockSize, minSize)
@@ -45,146 +71,63 @@ func (fs *FixedStorage) ValidateBlockSize(blockSize int) error {
 	return nil
 }
 
-func (fs *FixedStorage) calculateFixedRecordSize() {
-	fs.fixedRecordSize = 4 +
-		entity.MaxNomeLength +
-		entity.CPFLength +
-		entity.MaxCursoLength +
-		entity.MaxFiliacaoLength +
-		entity.MaxFiliacaoLength +
-		4 +
-		8
-}
+func (fs *FixedStorage) WriteStudents(ctx context.Context, filename string, students []entity.Student) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
 
-func (fs *FixedStorage) WriteStudents(filename string, students []entity.Student) error {
-	fs.calculateFixedRecordSize()
-	
-	file, err := os.Create(filename)
+	handle, err := openFileHandle(filename, fs, fs.lockTimeout, false)
 	if err != nil {
-		return fmt.Errorf("erro ao criar arquivo: %w", err)
+		return err
 	}
-	defer file.Close()
+	defer handle.Close()
 
-	currentBlock := make([]byte, 0, fs.blockSize)
-	currentBlockNumber := 0
+	return handle.WriteStudents(ctx, students)
+}
+
+func (fs *FixedStorage) writeRecordsToDevice(device BlockDevice, records iter.Seq[entity.Record]) error {
+	currentBlock := getBlockBuffer(fs.blockSize)[:0]
+	defer putBlockBuffer(currentBlock)
+	currentBlockNumber := int64(0)
 	blockStats := BlockStats{
 		BlockNumber: currentBlockNumber,
 		BytesUsed:   0,
 		BytesTotal:  fs.blockSize,
 	}
 
-	for i, student := range students {
-		recordData := fs.serializeStudentFixed(student)
-		fs.writeContiguousRecord(&currentBlock, &currentBlockNumber, &blockStats, recordData, file, i == len(students)-1)
+	for record := range records {
+		recordData, err := fs.codec.encode(record, false)
+		if err != nil {
+			return err
+		}
+		if err := fs.writeContiguousRecord(&currentBlock, &currentBlockNumber, &blockStats, recordData, device); err != nil {
+			return err
+		}
+		reportRecords(device, 1)
+		recordRecordSize(device, len(recordData))
 	}
 
 	if len(currentBlock) > 0 {
 		blockStats.OccupancyRate = float64(blockStats.BytesUsed) / float64(blockStats.BytesTotal) * 100
-		fs.stats.BlockStatsList = append(fs.stats.BlockStatsList, blockStats)
-		fs.writeBlock(file, currentBlock)
-		fs.stats.TotalBlocks++
-		fs.stats.TotalBytesUsed += blockStats.BytesUsed
-		fs.stats.TotalBytesTotal += blockStats.BytesTotal
+		if err := fs.writeBlock(device, blockStats, currentBlock); err != nil {
+			return err
+		}
 	}
 
-	fs.calculateFinalStats()
 	return nil
 }
 
-func (fs *FixedStorage) serializeStudentFixed(student entity.Student) []byte {
-	data := make([]byte, 0, fs.fixedRecordSize)
-	
-	matriculaBytes := make([]byte, 4)
-	binary.LittleEndian.PutUint32(matriculaBytes, uint32(student.Matricula))
-	data = append(data, matriculaBytes...)
-	
-	nomeBytes := []byte(student.Nome)
-	maxNomeLen := entity.MaxNomeLength
-	if len(nomeBytes) > maxNomeLen {
-		nomeBytes = nomeBytes[:maxNomeLen]
-	}
-	paddedNome := make([]byte, maxNomeLen)
-	copy(paddedNome, nomeBytes)
-	for i := len(nomeBytes); i < maxNomeLen; i++ {
-		paddedNome[i] = '#'
-	}
-	data = append(data, paddedNome...)
-	
-	cpfBytes := []byte(student.CPF)
-	if len(cpfBytes) > entity.CPFLength {
-		cpfBytes = cpfBytes[:entity.CPFLength]
-	}
-	paddedCPF := make([]byte, entity.CPFLength)
-	copy(paddedCPF, cpfBytes)
-	for i := len(cpfBytes); i < entity.CPFLength; i++ {
-		paddedCPF[i] = '0'
-	}
-	data = append(data, paddedCPF...)
-	
-	cursoBytes := []byte(student.Curso)
-	maxCursoLen := entity.MaxCursoLength
-	if len(cursoBytes) > maxCursoLen {
-		cursoBytes = cursoBytes[:maxCursoLen]
-	}
-	paddedCurso := make([]byte, maxCursoLen)
-	copy(paddedCurso, cursoBytes)
-	for i := len(cursoBytes); i < maxCursoLen; i++ {
-		paddedCurso[i] = '#'
-	}
-	data = append(data, paddedCurso...)
-	
-	maeBytes := []byte(student.FiliacaoMae)
-	maxMaeLen := entity.MaxFiliacaoLength
-	if len(maeBytes) > maxMaeLen {
-		maeBytes = maeBytes[:maxMaeLen]
-	}
-	paddedMae := make([]byte, maxMaeLen)
-	copy(paddedMae, maeBytes)
-	for i := len(maeBytes); i < maxMaeLen; i++ {
-		paddedMae[i] = '#'
-	}
-	data = append(data, paddedMae...)
-	
-	paiBytes := []byte(student.FiliacaoPai)
-	maxPaiLen := entity.MaxFiliacaoLength
-	if len(paiBytes) > maxPaiLen {
-		paiBytes = paiBytes[:maxPaiLen]
-	}
-	paddedPai := make([]byte, maxPaiLen)
-	copy(paddedPai, paiBytes)
-	for i := len(paiBytes); i < maxPaiLen; i++ {
-		paddedPai[i] = '#'
-	}
-	data = append(data, paddedPai...)
-	
-	anoBytes := make([]byte, 4)
-	binary.LittleEndian.PutUint32(anoBytes, uint32(student.AnoIngresso))
-	data = append(data, anoBytes...)
-	
-	caBytes := make([]byte, 8)
-	binary.LittleEndian.PutUint64(caBytes, uint64(student.CA*100))
-	data = append(data, caBytes...)
-	
-	return data
-}
-
-func (fs *FixedStorage) writeContiguousRecord(currentBlock *[]byte, currentBlockNumber *int, blockStats *BlockStats, recordData []byte, file *os.File, isLast bool) {
+func (fs *FixedStorage) writeContiguousRecord(currentBlock *[]byte, currentBlockNumber *int64, blockStats *BlockStats, recordData []byte, device BlockDevice) error {
 	recordSize := len(recordData)
 	
 	if len(*currentBlock)+recordSize > fs.blockSize {
 		if len(*currentBlock) > 0 {
 			blockStats.OccupancyRate = float64(blockStats.BytesUsed) / float64(blockStats.BytesTotal) * 100
-			if blockStats.OccupancyRate < 100 {
-				fs.stats.PartialBlocks++
+			if err := fs.writeBlock(device, *blockStats, *currentBlock); err != nil {
+				return err
 			}
-			fs.stats.BlockStatsList = append(fs.stats.BlockStatsList, *blockStats)
-			fs.writeBlock(file, *currentBlock)
-			fs.stats.TotalBlocks++
-			fs.stats.TotalBytesUsed += blockStats.BytesUsed
-			fs.stats.TotalBytesTotal += blockStats.BytesTotal
 		}
 		
-		*currentBlock = make([]byte, 0, fs.blockSize)
+		*currentBlock = (*currentBlock)[:0]
 		*currentBlockNumber++
 		*blockStats = BlockStats{
 			BlockNumber: *currentBlockNumber,
@@ -196,116 +139,101 @@ func (fs *FixedStorage) writeContiguousRecord(currentBlock *[]byte, currentBlock
 	*currentBlock = append(*currentBlock, recordData...)
 	blockStats.BytesUsed += recordSize
 	blockStats.RecordsCount++
+	blockStats.PaddingBytes += fs.codec.paddingBytes(recordData)
+	return nil
 }
 
-func (fs *FixedStorage) writeBlock(file *os.File, block []byte) {
-	paddedBlock := make([]byte, fs.blockSize)
-	copy(paddedBlock, block)
-	file.Write(paddedBlock)
-}
-
-func (fs *FixedStorage) calculateFinalStats() {
-	if fs.stats.TotalBytesTotal > 0 {
-		fs.stats.EfficiencyRate = float64(fs.stats.TotalBytesUsed) / float64(fs.stats.TotalBytesTotal) * 100
+func (fs *FixedStorage) writeBlock(device BlockDevice, blockStats BlockStats, block []byte) error {
+	blockStats.TailBytes = blockStats.BytesTotal - blockStats.BytesUsed
+	if err := device.WriteBlock(blockStats.BlockNumber, block); err != nil {
+		return err
 	}
+	return recordBlockStats(device, blockStats)
 }
 
-func (fs *FixedStorage) GetStats(filename string) StorageStats {
-	fs.recalculateStatsFromFile(filename)
-	return fs.stats
-}
+func (fs *FixedStorage) GetStats(ctx context.Context, filename string) (StorageStats, error) {
+	fs.mu.RLock()
+	defer fs.mu.RUnlock()
 
-func (fs *FixedStorage) recalculateStatsFromFile(filename string) {
-	fileInfo, err := os.Stat(filename)
+	handle, err := openFileHandle(filename, fs, fs.lockTimeout, true)
 	if err != nil {
-		return
+		return StorageStats{}, err
 	}
+	defer handle.Close()
 
-	totalBlocks := int(fileInfo.Size()) / fs.blockSize
-	fs.stats = StorageStats{
-		TotalBlocks:     totalBlocks,
-		TotalBytesTotal: totalBlocks * fs.blockSize,
-		BlockStatsList:  make([]BlockStats, 0),
-	}
+	return handle.GetBlockStats(ctx)
+}
 
-	file, err := os.Open(filename)
+func (fs *FixedStorage) statsFromDevice(device BlockDevice, totalBlocks int64) (StorageStats, error) {
+	blockStatsList, err := parallelScan(totalBlocks, fs.scanWorkers, func(start, end int64) ([]BlockStats, error) {
+		return fs.blockStatsInRange(device, start, end)
+	})
 	if err != nil {
-		return
+		return StorageStats{}, err
 	}
-	defer file.Close()
 
-	totalUsed := 0
-	for blockNum := 0; blockNum < totalBlocks; blockNum++ {
-		block := make([]byte, fs.blockSize)
-		_, err := file.ReadAt(block, int64(blockNum*fs.blockSize))
-		if err != nil {
-			continue
+	recordSizes := recordSizeCounter{}
+	for _, blockStats := range blockStatsList {
+		if blockStats.RecordsCount > 0 {
+			recordSizes.add(fs.fixedRecordSize, int64(blockStats.RecordsCount))
+		}
+	}
+	return summarizeBlockStats(FixedMode, fs.blockSize, blockStatsList, recordSizes.histogram()), nil
+}
+
+func (fs *FixedStorage) blockStatsInRange(device BlockDevice, start, end int64) ([]BlockStats, error) {
+	blockStatsList := make([]BlockStats, 0, end-start)
+	block := getBlockBuffer(fs.blockSize)
+	defer putBlockBuffer(block)
+	for blockNum := start; blockNum < end; blockNum++ {
+		if err := device.ReadBlock(blockNum, block); err != nil {
+			return nil, err
 		}
 
 		recordsCount := 0
+		paddingBytes := 0
 		offset := 0
 		for offset+fs.fixedRecordSize <= fs.blockSize {
 			if offset+4 > fs.blockSize {
 				break
 			}
 
-			matriculaBytes := block[offset : offset+4]
-			matricula := int(binary.LittleEndian.Uint32(matriculaBytes))
-			
-			if matricula > 0 {
+			if !isZeroed(block[offset : offset+fs.fixedRecordSize]) {
 				recordsCount++
+				paddingBytes += fs.codec.paddingBytes(block[offset : offset+fs.fixedRecordSize])
 			}
 
 			offset += fs.fixedRecordSize
 		}
 
-		bytesUsed := recordsCount * fs.fixedRecordSize
-		totalUsed += bytesUsed
-
-		occupancyRate := float64(bytesUsed) / float64(fs.blockSize) * 100
-		blockStats := BlockStats{
-			BlockNumber:   blockNum,
-			BytesUsed:     bytesUsed,
-			BytesTotal:    fs.blockSize,
-			OccupancyRate: occupancyRate,
-			RecordsCount:  recordsCount,
-		}
-
-		if occupancyRate < 100 && occupancyRate > 0 {
-			fs.stats.PartialBlocks++
-		}
-
-		fs.stats.BlockStatsList = append(fs.stats.BlockStatsList, blockStats)
+		blockStats := newBlockStats(blockNum, recordsCount*fs.fixedRecordSize, fs.blockSize, recordsCount)
+		blockStats.PaddingBytes = paddingBytes
+		blockStatsList = append(blockStatsList, blockStats)
+		reportRecords(device, recordsCount)
 	}
 
-	fs.stats.TotalBytesUsed = totalUsed
-	if fs.stats.TotalBytesTotal > 0 {
-		fs.stats.EfficiencyRate = float64(fs.stats.TotalBytesUsed) / float64(fs.stats.TotalBytesTotal) * 100
-	}
+	return blockStatsList, nil
 }
 
-func (fs *FixedStorage) FindStudentByMatricula(filename string, matricula int) (*entity.Student, error) {
-	file, err := os.Open(filename)
-	if err != nil {
-		return nil, fmt.Errorf("erro ao abrir arquivo: %w", err)
-	}
-	defer file.Close()
+func (fs *FixedStorage) FindStudentByMatricula(ctx context.Context, filename string, matricula int64) (*entity.Student, error) {
+	fs.mu.RLock()
+	defer fs.mu.RUnlock()
 
-	fileInfo, err := file.Stat()
+	handle, err := openFileHandle(filename, fs, fs.lockTimeout, true)
 	if err != nil {
-		return nil, fmt.Errorf("erro ao obter informações do arquivo: %w", err)
+		return nil, err
 	}
+	defer handle.Close()
 
-	totalBlocks := int(fileInfo.Size()) / fs.blockSize
-	return fs.findStudentFixed(file, totalBlocks, matricula)
+	return handle.FindStudentByMatricula(ctx, matricula)
 }
 
-func (fs *FixedStorage) findStudentFixed(file *os.File, totalBlocks int, matricula int) (*entity.Student, error) {
-	for blockNum := 0; blockNum < totalBlocks; blockNum++ {
-		block := make([]byte, fs.blockSize)
-		_, err := file.ReadAt(block, int64(blockNum*fs.blockSize))
-		if err != nil {
-			continue
+func (fs *FixedStorage) findRecord(device BlockDevice, totalBlocks int64, key int64) (entity.Record, error) {
+	block := getBlockBuffer(fs.blockSize)
+	defer putBlockBuffer(block)
+	for blockNum := int64(0); blockNum < totalBlocks; blockNum++ {
+		if err := device.ReadBlock(blockNum, block); err != nil {
+			return nil, err
 		}
 
 		offset := 0
@@ -314,151 +242,130 @@ func (fs *FixedStorage) findStudentFixed(file *os.File, totalBlocks int, matricu
 				break
 			}
 
-			matriculaBytes := block[offset : offset+4]
-			readMatricula := int(binary.LittleEndian.Uint32(matriculaBytes))
-			
-			if readMatricula == matricula {
-				student, err := fs.deserializeStudentFixed(block[offset:offset+fs.fixedRecordSize])
-				if err == nil {
-					return student, nil
+			slot := block[offset : offset+fs.fixedRecordSize]
+			if readKey, ok := fs.codec.key(slot, false); ok && readKey == key && !isZeroed(slot) {
+				record, _, err := fs.codec.decode(slot, false)
+				if err != nil {
+					return nil, corruptRecordError(blockNum, offset, err)
 				}
+				return record, nil
 			}
 
 			offset += fs.fixedRecordSize
 		}
 	}
 
-	return nil, fmt.Errorf("aluno com matrícula %d não encontrado", matricula)
+	return nil, fs.codec.notFound(key)
 }
 
-func (fs *FixedStorage) deserializeStudentFixed(data []byte) (*entity.Student, error) {
-	if len(data) < fs.fixedRecordSize {
-		return nil, fmt.Errorf("dados insuficientes")
-	}
-
-	offset := 0
+func (fs *FixedStorage) GetBlockSize() int {
+	return fs.blockSize
+}
 
-	matricula := int(binary.LittleEndian.Uint32(data[offset : offset+4]))
-	offset += 4
+func (fs *FixedStorage) storageMode() Mode {
+	return FixedMode
+}
 
-	nomeBytes := data[offset : offset+entity.MaxNomeLength]
-	nome := string(nomeBytes)
-	for len(nome) > 0 && nome[len(nome)-1] == '#' {
-		nome = nome[:len(nome)-1]
-	}
-	offset += entity.MaxNomeLength
+func (fs *FixedStorage) recordEncoding() Encoding {
+	return fs.codec.encoding
+}
 
-	cpfBytes := data[offset : offset+entity.CPFLength]
-	cpf := string(cpfBytes)
-	offset += entity.CPFLength
+func (fs *FixedStorage) recordSchema() *entity.Schema {
+	return fs.codec.schema
+}
 
-	cursoBytes := data[offset : offset+entity.MaxCursoLength]
-	curso := string(cursoBytes)
-	for len(curso) > 0 && curso[len(curso)-1] == '#' {
-		curso = curso[:len(curso)-1]
-	}
-	offset += entity.MaxCursoLength
+func (fs *FixedStorage) recordSize(record entity.Record) int {
+	return fs.fixedRecordSize
+}
 
-	maeBytes := data[offset : offset+entity.MaxFiliacaoLength]
-	filiacaoMae := string(maeBytes)
-	for len(filiacaoMae) > 0 && filiacaoMae[len(filiacaoMae)-1] == '#' {
-		filiacaoMae = filiacaoMae[:len(filiacaoMae)-1]
-	}
-	offset += entity.MaxFiliacaoLength
+func (fs *FixedStorage) GetAllStudents(ctx context.Context, filename string) ([]*entity.Student, error) {
+	return fs.FindStudents(ctx, filename, nil)
+}
 
-	paiBytes := data[offset : offset+entity.MaxFiliacaoLength]
-	filiacaoPai := string(paiBytes)
-	for len(filiacaoPai) > 0 && filiacaoPai[len(filiacaoPai)-1] == '#' {
-		filiacaoPai = filiacaoPai[:len(filiacaoPai)-1]
-	}
-	offset += entity.MaxFiliacaoLength
-
-	anoIngresso := int(binary.LittleEndian.Uint32(data[offset : offset+4]))
-	offset += 4
-
-	caValue := binary.LittleEndian.Uint64(data[offset : offset+8])
-	ca := float64(caValue) / 100.0
-
-	student := &entity.Student{
-		Matricula:   matricula,
-		Nome:        nome,
-		CPF:         cpf,
-		Curso:       curso,
-		FiliacaoMae: filiacaoMae,
-		FiliacaoPai: filiacaoPai,
-		AnoIngresso: anoIngresso,
-		CA:          ca,
-	}
+func (fs *FixedStorage) FindStudents(ctx context.Context, filename string, predicate func(*entity.Student) bool) ([]*entity.Student, error) {
+	fs.mu.RLock()
+	defer fs.mu.RUnlock()
 
-	if err := student.Validate(); err != nil {
-		return nil, fmt.Errorf("estudante deserializado inválido: %w", err)
+	handle, err := openFileHandle(filename, fs, fs.lockTimeout, true)
+	if err != nil {
+		return nil, err
 	}
+	defer handle.Close()
 
-	return student, nil
+	return handle.FindStudents(ctx, predicate)
 }
 
-func (fs *FixedStorage) GetBlockSize() int {
-	return fs.blockSize
+func (fs *FixedStorage) readRecords(device BlockDevice, totalBlocks int64, predicate func(entity.Record) bool) ([]entity.Record, error) {
+	return parallelScan(totalBlocks, fs.scanWorkers, func(start, end int64) ([]entity.Record, error) {
+		return fs.readRecordsInRange(device, start, end, predicate)
+	})
 }
 
-func (fs *FixedStorage) GetAllStudents(filename string) ([]*entity.Student, error) {
-	file, err := os.Open(filename)
-	if err != nil {
-		return nil, fmt.Errorf("erro ao abrir arquivo: %w", err)
-	}
-	defer file.Close()
-
-	fileInfo, err := file.Stat()
+func (fs *FixedStorage) readRecordsInRange(device BlockDevice, start, end int64, predicate func(entity.Record) bool) ([]entity.Record, error) {
+	records := make([]entity.Record, 0)
+	err := fs.visitRecordsInRange(device, start, end, func(record entity.Record) bool {
+		if predicate == nil || predicate(record) {
+			records = append(records, record)
+		}
+		return true
+	})
 	if err != nil {
-		return nil, fmt.Errorf("erro ao obter informações do arquivo: %w", err)
+		return nil, err
 	}
+	return records, nil
+}
 
-	totalBlocks := int(fileInfo.Size()) / fs.blockSize
-	students := make([]*entity.Student, 0)
+func (fs *FixedStorage) scanRecords(device BlockDevice, totalBlocks int64, visit func(entity.Record) bool) error {
+	return fs.visitRecordsInRange(device, 0, totalBlocks, visit)
+}
 
-	for blockNum := 0; blockNum < totalBlocks; blockNum++ {
-		block := make([]byte, fs.blockSize)
-		_, err := file.ReadAt(block, int64(blockNum*fs.blockSize))
-		if err != nil {
-			continue
+func (fs *FixedStorage) visitRecordsInRange(device BlockDevice, start, end int64, visit func(entity.Record) bool) error {
+	block := getBlockBuffer(fs.blockSize)
+	defer putBlockBuffer(block)
+	for blockNum := start; blockNum < end; blockNum++ {
+		if err := device.ReadBlock(blockNum, block); err != nil {
+			return err
 		}
 
+		recordsCount := 0
 		offset := 0
 		for offset+fs.fixedRecordSize <= fs.blockSize {
 			if offset+4 > fs.blockSize {
 				break
 			}
 
-			student, err := fs.deserializeStudentFixed(block[offset:offset+fs.fixedRecordSize])
-			if err == nil && student.Matricula > 0 {
-				students = append(students, student)
+			if isZeroed(block[offset : offset+fs.fixedRecordSize]) {
+				offset += fs.fixedRecordSize
+				continue
+			}
+
+			record, _, err := fs.codec.decode(block[offset:offset+fs.fixedRecordSize], false)
+			if err != nil {
+				return corruptRecordError(blockNum, offset, err)
+			}
+			recordsCount++
+			if !visit(record) {
+				reportRecords(device, recordsCount)
+				return nil
 			}
 
 			offset += fs.fixedRecordSize
 		}
+		reportRecords(device, recordsCount)
 	}
 
-	return students, nil
+	return nil
 }
 
-func (fs *FixedStorage) AddStudents(filename string, students []entity.Student) error {
-	existingStudents, err := fs.GetAllStudents(filename)
-	if err != nil {
-		return fmt.Errorf("erro ao ler alunos existentes: %w", err)
-	}
-
-	allStudents := make([]entity.Student, len(existingStudents))
-	for i, s := range existingStudents {
-		allStudents[i] = *s
-	}
-
-	allStudents = append(allStudents, students...)
+func (fs *FixedStorage) AddStudents(ctx context.Context, filename string, students []entity.Student) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
 
-	err = fs.WriteStudents(filename, allStudents)
+	handle, err := openFileHandle(filename, fs, fs.lockTimeout, false)
 	if err != nil {
 		return err
 	}
+	defer handle.Close()
 
-	fs.calculateFinalStats()
-	return nil
+	return handle.AddStudents(ctx, students)
 }
@@ -0,0 +1,742 @@
+package storage
+
+import (
+	"aeds2-tp1/entity"
+	"context"
+	"errors"
+	"fmt"
+	"iter"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+type Mode int
+
+const (
+	FixedMode Mode = iota + 1
+	VariableMode
+	FragmentedMode
+)
+
+func (m Mode) String() string {
+	switch m {
+	case FixedMode:
+		return "fixo"
+	case VariableMode:
+		return "variável"
+	case FragmentedMode:
+		return "espalhado"
+	default:
+		return fmt.Sprintf("modo %d", int(m))
+	}
+}
+
+func (m Mode) MarshalText() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+func (m *Mode) UnmarshalText(text []byte) error {
+	mode, err := ParseMode(string(text))
+	if err != nil {
+		return err
+	}
+	*m = mode
+	return nil
+}
+
+func ParseMode(name string) (Mode, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "fixo":
+		return FixedMode, nil
+	case "variavel", "variável":
+		return VariableMode, nil
+	case "espalhado":
+		return FragmentedMode, nil
+	default:
+		return 0, fmt.Errorf("modo inválido: %q (use fixo, variavel ou espalhado)", name)
+	}
+}
+
+type Options struct {
+	Mode             Mode
+	BlockSize        int
+	LockTimeout      time.Duration
+	ScanWorkers      int
+	WriteBatchBlocks int
+	ReadOnly         bool
+	Encoding         Encoding
+	Schema           *entity.Schema
+}
+
+var (
+	ErrReadOnly       = errors.New("arquivo aberto somente para leitura")
+	ErrNotStudentFile = errors.New("o esquema do arquivo não é o de alunos")
+	ErrSchemaMismatch = errors.New("esquema diferente do usado na gravação do arquivo")
+)
+
+type layout interface {
+	GetBlockSize() int
+	ValidateBlockSize(blockSize int) error
+	storageMode() Mode
+	recordSchema() *entity.Schema
+	recordEncoding() Encoding
+	recordSize(record entity.Record) int
+	SetScanWorkers(workers int)
+	SetEncoding(encoding Encoding)
+	writeRecordsToDevice(device BlockDevice, records iter.Seq[entity.Record]) error
+	readRecords(device BlockDevice, totalBlocks int64, predicate func(entity.Record) bool) ([]entity.Record, error)
+	scanRecords(device BlockDevice, totalBlocks int64, visit func(entity.Record) bool) error
+	findRecord(device BlockDevice, totalBlocks int64, key int64) (entity.Record, error)
+	statsFromDevice(device BlockDevice, totalBlocks int64) (StorageStats, error)
+}
+
+type Handle struct {
+	mu               sync.RWMutex
+	lockMu           sync.Mutex
+	readers          int
+	device           BlockDevice
+	layout           layout
+	lockTimeout      time.Duration
+	writeBatchBlocks int
+	readOnly         bool
+	totalBlocks      int64
+}
+
+func Open(path string, options Options) (*Handle, error) {
+	l, err := newLayout(options)
+	if err != nil {
+		return nil, err
+	}
+
+	handle, err := openFileHandle(path, l, options.LockTimeout, options.ReadOnly)
+	if err != nil {
+		return nil, err
+	}
+
+	handle.writeBatchBlocks = options.WriteBatchBlocks
+	return handle, nil
+}
+
+func OpenDevice(device BlockDevice, options Options) (*Handle, error) {
+	if options.BlockSize == 0 {
+		options.BlockSize = device.BlockSize()
+	}
+	if options.BlockSize != device.BlockSize() {
+		return nil, fmt.Errorf("tamanho do bloco (%d bytes) difere do tamanho do bloco do dispositivo (%d bytes)", options.BlockSize, device.BlockSize())
+	}
+
+	l, err := newLayout(options)
+	if err != nil {
+		return nil, err
+	}
+
+	handle := newHandle(device, l, options.LockTimeout, options.ReadOnly)
+	if err := handle.checkFormat(); err != nil {
+		return nil, err
+	}
+	handle.writeBatchBlocks = options.WriteBatchBlocks
+	return handle, nil
+}
+
+func newLayout(options Options) (layout, error) {
+	schema := options.Schema
+	if schema == nil {
+		schema = entity.StudentSchema()
+	}
+	codec := newRecordCodec(schema, options.Encoding)
+
+	var l layout
+	var err error
+
+	switch options.Mode {
+	case FixedMode:
+		l, err = newFixedStorage(options.BlockSize, codec)
+	case VariableMode:
+		l, err = newVariableStorage(options.BlockSize, codec)
+	case FragmentedMode:
+		l, err = newVariableFragmentedStorage(options.BlockSize, codec)
+	default:
+		return nil, fmt.Errorf("modo de armazenamento inválido: %d", options.Mode)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if options.ScanWorkers > 0 {
+		l.SetScanWorkers(options.ScanWorkers)
+	}
+	return l, nil
+}
+
+func RecordSize(mode Mode, encoding Encoding, student entity.Student) (int, error) {
+	l, err := newLayout(Options{Mode: mode, BlockSize: advisorSampleBlockSize, Encoding: encoding})
+	if err != nil {
+		return 0, err
+	}
+	return l.recordSize(student.Record()), nil
+}
+
+func openFileHandle(path string, l layout, lockTimeout time.Duration, readOnly bool) (*Handle, error) {
+	device, err := OpenFileDevice(path, l.GetBlockSize(), readOnly)
+	if err != nil {
+		return nil, err
+	}
+
+	handle := newHandle(device, l, lockTimeout, readOnly)
+	if err := handle.checkFormat(); err != nil {
+		device.Close()
+		return nil, err
+	}
+	return handle, nil
+}
+
+func newHandle(device BlockDevice, l layout, lockTimeout time.Duration, readOnly bool) *Handle {
+	return &Handle{
+		device:      device,
+		layout:      l,
+		lockTimeout: lockTimeout,
+		readOnly:    readOnly,
+	}
+}
+
+func (h *Handle) checkFormat() error {
+	store, ok := h.device.(statsStore)
+	if !ok {
+		return nil
+	}
+	encoding, schema, found := store.recordedFormat()
+	if !found {
+		return nil
+	}
+	if encoding != h.layout.recordEncoding() {
+		return fmt.Errorf("%w: arquivo gravado em %s, aberto como %s", ErrEncodingMismatch, encoding, h.layout.recordEncoding())
+	}
+	if opened := h.layout.recordSchema(); schema != opened.Fingerprint() {
+		return fmt.Errorf("%w: arquivo gravado com o esquema de impressão digital %016x, aberto com o esquema %s (%016x)", ErrSchemaMismatch, schema, opened.Name, opened.Fingerprint())
+	}
+	return nil
+}
+
+func (h *Handle) BlockSize() int {
+	return h.layout.GetBlockSize()
+}
+
+func (h *Handle) Mode() Mode {
+	return h.layout.storageMode()
+}
+
+func (h *Handle) Schema() *entity.Schema {
+	return h.layout.recordSchema()
+}
+
+func (h *Handle) checkStudentSchema() error {
+	if schema := h.layout.recordSchema(); !schema.Equal(entity.StudentSchema()) {
+		return fmt.Errorf("%w: esquema %s", ErrNotStudentFile, schema.Name)
+	}
+	return nil
+}
+
+func (h *Handle) TotalBlocks() int64 {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.totalBlocks
+}
+
+func (h *Handle) beginRead(ctx context.Context) error {
+	h.mu.RLock()
+
+	h.lockMu.Lock()
+	defer h.lockMu.Unlock()
+
+	if h.readers == 0 {
+		if err := h.lockDevice(ctx, sharedLock); err != nil {
+			h.mu.RUnlock()
+			return err
+		}
+		if err := h.refreshMetadata(); err != nil {
+			h.unlockDevice()
+			h.mu.RUnlock()
+			return err
+		}
+	}
+	h.readers++
+	return nil
+}
+
+func (h *Handle) endRead() {
+	h.lockMu.Lock()
+	h.readers--
+	if h.readers == 0 {
+		h.unlockDevice()
+	}
+	h.lockMu.Unlock()
+
+	h.mu.RUnlock()
+}
+
+func (h *Handle) beginWrite(ctx context.Context) error {
+	if h.readOnly {
+		return ErrReadOnly
+	}
+
+	h.mu.Lock()
+	if err := h.lockDevice(ctx, exclusiveLock); err != nil {
+		h.mu.Unlock()
+		return err
+	}
+	if err := h.refreshMetadata(); err != nil {
+		h.unlockDevice()
+		h.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+func (h *Handle) endWrite() {
+	h.unlockDevice()
+	h.mu.Unlock()
+}
+
+func (h *Handle) lockDevice(ctx context.Context, mode lockMode) error {
+	if locker, ok := h.device.(deviceLocker); ok {
+		return locker.lock(ctx, mode, h.lockTimeout)
+	}
+	return nil
+}
+
+func (h *Handle) unlockDevice() {
+	if locker, ok := h.device.(deviceLocker); ok {
+		locker.unlock()
+	}
+}
+
+func (h *Handle) refreshMetadata() error {
+	totalBlocks, err := h.device.BlockCount()
+	if err != nil {
+		return err
+	}
+
+	h.totalBlocks = totalBlocks
+	return nil
+}
+
+func (h *Handle) WriteStudents(ctx context.Context, students []entity.Student) error {
+	return h.WriteStudentsFrom(ctx, withoutErrors(slices.Values(students)), int64(len(students)))
+}
+
+func (h *Handle) WriteStudentsFrom(ctx context.Context, students iter.Seq2[entity.Student, error], expectedRecords int64) error {
+	if err := h.checkStudentSchema(); err != nil {
+		return err
+	}
+	return h.WriteRecordsFrom(ctx, studentRecords(students), expectedRecords)
+}
+
+func (h *Handle) WriteRecords(ctx context.Context, records []entity.Record) error {
+	return h.WriteRecordsFrom(ctx, withoutErrors(slices.Values(records)), int64(len(records)))
+}
+
+func (h *Handle) WriteRecordsFrom(ctx context.Context, records iter.Seq2[entity.Record, error], expectedRecords int64) error {
+	if err := h.beginWrite(ctx); err != nil {
+		return err
+	}
+	defer h.endWrite()
+
+	return h.rewrite(ctx, expectedRecords, 0, func(device BlockDevice) error {
+		var sourceErr, duplicateErr error
+		if err := h.layout.writeRecordsToDevice(device, uniqueRecords(untilError(records, &sourceErr), h.layout.recordSchema(), &duplicateErr)); err != nil {
+			return err
+		}
+		if sourceErr != nil {
+			return sourceErr
+		}
+		return duplicateErr
+	})
+}
+
+func (h *Handle) AddStudents(ctx context.Context, students []entity.Student) error {
+	return h.AddStudentsFrom(ctx, withoutErrors(slices.Values(students)))
+}
+
+func (h *Handle) AddStudentsFrom(ctx context.Context, students iter.Seq2[entity.Student, error]) error {
+	if err := h.checkStudentSchema(); err != nil {
+		return err
+	}
+	return h.AddRecordsFrom(ctx, studentRecords(students))
+}
+
+func (h *Handle) AddRecords(ctx context.Context, records []entity.Record) error {
+	return h.AddRecordsFrom(ctx, withoutErrors(slices.Values(records)))
+}
+
+func (h *Handle) AddRecordsFrom(ctx context.Context, records iter.Seq2[entity.Record, error]) error {
+	return h.mergeRecords(ctx, nil, records)
+}
+
+func (h *Handle) UpsertStudents(ctx context.Context, students []entity.Student) error {
+	return h.UpsertStudentsFrom(ctx, withoutErrors(slices.Values(students)))
+}
+
+func (h *Handle) UpsertStudentsFrom(ctx context.Context, students iter.Seq2[entity.Student, error]) error {
+	if err := h.checkStudentSchema(); err != nil {
+		return err
+	}
+	return h.UpsertRecordsFrom(ctx, studentRecords(students))
+}
+
+func (h *Handle) UpsertRecords(ctx context.Context, records []entity.Record) error {
+	return h.UpsertRecordsFrom(ctx, withoutErrors(slices.Values(records)))
+}
+
+func (h *Handle) UpsertRecordsFrom(ctx context.Context, records iter.Seq2[entity.Record, error]) error {
+	schema := h.layout.recordSchema()
+	replacements := make(map[int64]entity.Record)
+	order := make([]int64, 0)
+	for record, err := range records {
+		if err != nil {
+			return err
+		}
+		key := schema.Key(record)
+		if _, found := replacements[key]; !found {
+			order = append(order, key)
+		}
+		replacements[key] = record
+	}
+
+	added := func(yield func(entity.Record, error) bool) {
+		for _, key := range order {
+			if record, found := replacements[key]; found && !yield(record, nil) {
+				return
+			}
+		}
+	}
+	return h.mergeRecords(ctx, replacements, added)
+}
+
+func (h *Handle) mergeRecords(ctx context.Context, replacements map[int64]entity.Record, added iter.Seq2[entity.Record, error]) error {
+	if err := h.beginWrite(ctx); err != nil {
+		return err
+	}
+	defer h.endWrite()
+
+	schema := h.layout.recordSchema()
+	source := withContext(ctx, h.device, nil)
+	var readErr error
+	existingRecords := func(yield func(entity.Record) bool) {
+		readErr = h.layout.scanRecords(source, h.totalBlocks, func(record entity.Record) bool {
+			key := schema.Key(record)
+			if replacement, found := replacements[key]; found {
+				delete(replacements, key)
+				return yield(replacement)
+			}
+			return yield(record)
+		})
+	}
+
+	keptBlocks := int64(0)
+	if replacements == nil {
+		keptBlocks = max(h.totalBlocks-1, 0)
+	}
+	return h.rewrite(ctx, 0, keptBlocks, func(device BlockDevice) error {
+		var sourceErr, duplicateErr error
+		if err := h.layout.writeRecordsToDevice(device, uniqueRecords(concatRecords(existingRecords, untilError(added, &sourceErr)), schema, &duplicateErr)); err != nil {
+			return err
+		}
+		if readErr != nil {
+			return fmt.Errorf("erro ao ler registros existentes: %w", readErr)
+		}
+		if sourceErr != nil {
+			return sourceErr
+		}
+		return duplicateErr
+	})
+}
+
+func (h *Handle) MaxMatricula(ctx context.Context) (int64, error) {
+	if err := h.checkStudentSchema(); err != nil {
+		return 0, err
+	}
+	return h.MaxKey(ctx)
+}
+
+func (h *Handle) MaxKey(ctx context.Context) (int64, error) {
+	if err := h.beginRead(ctx); err != nil {
+		return 0, err
+	}
+	defer h.endRead()
+
+	device, tracker := h.track(ctx, h.device, OperationRead, h.totalBlocks, 0)
+	defer tracker.finish()
+
+	schema := h.layout.recordSchema()
+	var highest int64
+	err := h.layout.scanRecords(device, h.totalBlocks, func(record entity.Record) bool {
+		highest = max(highest, schema.Key(record))
+		return true
+	})
+	return highest, err
+}
+
+func (h *Handle) rewrite(ctx context.Context, expectedRecords, keptBlocks int64, write func(device BlockDevice) error) error {
+	replacer, ok := h.device.(deviceReplacer)
+	if !ok {
+		replacer = copyReplacer{device: h.device}
+	}
+
+	stats, err := h.beginStats(keptBlocks)
+	if err != nil {
+		return err
+	}
+
+	staged, err := replacer.beginReplace()
+	if err != nil {
+		stats.abort()
+		return err
+	}
+
+	buffered := newWriteBuffer(staged, h.writeBatchBlocks)
+	device, tracker := h.track(ctx, buffered, OperationWrite, 0, expectedRecords)
+	device.onBlock = stats.add
+	device.onRecord = stats.addRecords
+	err = write(device)
+	if err == nil {
+		err = buffered.flush()
+	}
+	if err == nil {
+		err = ctx.Err()
+	}
+	tracker.finish()
+	if err != nil {
+		replacer.abortReplace(staged)
+		stats.abort()
+		return err
+	}
+
+	if err := replacer.commitReplace(staged); err != nil {
+		stats.abort()
+		return err
+	}
+
+	if err := h.refreshMetadata(); err != nil {
+		stats.abort()
+		return err
+	}
+	return stats.commit()
+}
+
+func (h *Handle) beginStats(keptBlocks int64) (statsWriter, error) {
+	if store, ok := h.device.(statsStore); ok {
+		stats, err := store.beginStats(h.layout.storageMode(), h.layout.recordEncoding(), h.layout.recordSchema().Fingerprint(), keptBlocks)
+		if err != nil || stats != nil {
+			return stats, err
+		}
+	}
+	return discardStats{}, nil
+}
+
+func concatRecords(sequences ...iter.Seq[entity.Record]) iter.Seq[entity.Record] {
+	return func(yield func(entity.Record) bool) {
+		for _, sequence := range sequences {
+			for record := range sequence {
+				if !yield(record) {
+					return
+				}
+			}
+		}
+	}
+}
+
+func studentRecords(students iter.Seq2[entity.Student, error]) iter.Seq2[entity.Record, error] {
+	return func(yield func(entity.Record, error) bool) {
+		for student, err := range students {
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+			if !yield(student.Record(), nil) {
+				return
+			}
+		}
+	}
+}
+
+func withoutErrors[T any](values iter.Seq[T]) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		for value := range values {
+			if !yield(value, nil) {
+				return
+			}
+		}
+	}
+}
+
+func untilError[T any](values iter.Seq2[T, error], err *error) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		for value, valueErr := range values {
+			if valueErr != nil {
+				*err = valueErr
+				return
+			}
+			if !yield(value) {
+				return
+			}
+		}
+	}
+}
+
+func (h *Handle) FindStudentByMatricula(ctx context.Context, matricula int64) (*entity.Student, error) {
+	if err := h.checkStudentSchema(); err != nil {
+		return nil, err
+	}
+	record, err := h.FindRecordByKey(ctx, matricula)
+	if err != nil {
+		return nil, err
+	}
+	return entity.StudentFromRecord(record)
+}
+
+func (h *Handle) FindRecordByKey(ctx context.Context, key int64) (entity.Record, error) {
+	if err := h.beginRead(ctx); err != nil {
+		return nil, err
+	}
+	defer h.endRead()
+
+	device, tracker := h.track(ctx, h.device, OperationFind, h.totalBlocks, 0)
+	defer tracker.finish()
+
+	return h.layout.findRecord(device, h.totalBlocks, key)
+}
+
+func (h *Handle) GetAllStudents(ctx context.Context) ([]*entity.Student, error) {
+	return h.FindStudents(ctx, nil)
+}
+
+func (h *Handle) FindStudents(ctx context.Context, predicate func(*entity.Student) bool) ([]*entity.Student, error) {
+	if err := h.checkStudentSchema(); err != nil {
+		return nil, err
+	}
+
+	var recordPredicate func(entity.Record) bool
+	if predicate != nil {
+		recordPredicate = func(record entity.Record) bool {
+			student, err := entity.StudentFromRecord(record)
+			return err == nil && predicate(student)
+		}
+	}
+	records, err := h.FindRecords(ctx, recordPredicate)
+	if err != nil {
+		return nil, err
+	}
+
+	students := make([]*entity.Student, len(records))
+	for i, record := range records {
+		if students[i], err = entity.StudentFromRecord(record); err != nil {
+			return nil, err
+		}
+	}
+	return students, nil
+}
+
+func (h *Handle) GetAllRecords(ctx context.Context) ([]entity.Record, error) {
+	return h.FindRecords(ctx, nil)
+}
+
+func (h *Handle) FindRecords(ctx context.Context, predicate func(entity.Record) bool) ([]entity.Record, error) {
+	if err := h.beginRead(ctx); err != nil {
+		return nil, err
+	}
+	defer h.endRead()
+
+	device, tracker := h.track(ctx, h.device, OperationRead, h.totalBlocks, 0)
+	defer tracker.finish()
+
+	return h.layout.readRecords(device, h.totalBlocks, predicate)
+}
+
+func (h *Handle) GetStats(ctx context.Context) (StorageStats, error) {
+	stats, err := h.loadStats(ctx, false)
+	stats.BlockStatsList = nil
+	return stats, err
+}
+
+func (h *Handle) GetBlockStats(ctx context.Context) (StorageStats, error) {
+	return h.loadStats(ctx, true)
+}
+
+func (h *Handle) loadStats(ctx context.Context, withBlocks bool) (StorageStats, error) {
+	if err := h.beginRead(ctx); err != nil {
+		return StorageStats{}, err
+	}
+	defer h.endRead()
+
+	if store, ok := h.device.(statsStore); ok {
+		if stats, found := store.loadStats(h.layout.storageMode(), withBlocks); found {
+			return stats, nil
+		}
+	}
+
+	return h.scanStats(ctx)
+}
+
+func (h *Handle) RecomputeStats(ctx context.Context) (StorageStats, error) {
+	if h.readOnly {
+		if err := h.beginRead(ctx); err != nil {
+			return StorageStats{}, err
+		}
+		defer h.endRead()
+
+		return h.scanStats(ctx)
+	}
+
+	if err := h.beginWrite(ctx); err != nil {
+		return StorageStats{}, err
+	}
+	defer h.endWrite()
+
+	stats, err := h.scanStats(ctx)
+	if err != nil {
+		return StorageStats{}, err
+	}
+
+	writer, err := h.beginStats(0)
+	if err != nil {
+		return StorageStats{}, err
+	}
+	for _, blockStats := range stats.BlockStatsList {
+		if err := writer.add(blockStats); err != nil {
+			writer.abort()
+			return StorageStats{}, err
+		}
+	}
+	for _, recordSize := range stats.RecordSizes {
+		writer.addRecords(recordSize.Size, recordSize.Count)
+	}
+	return stats, writer.commit()
+}
+
+func (h *Handle) scanStats(ctx context.Context) (StorageStats, error) {
+	device, tracker := h.track(ctx, h.device, OperationStats, h.totalBlocks, 0)
+	defer tracker.finish()
+
+	return h.layout.statsFromDevice(device, h.totalBlocks)
+}
+
+func (h *Handle) track(ctx context.Context, device BlockDevice, operation string, totalBlocks, totalRecords int64) (contextDevice, *progressTracker) {
+	tracker := newProgressTracker(ctx, operation, totalBlocks, totalRecords)
+	return withContext(ctx, device, tracker), tracker
+}
+
+func (h *Handle) Sync() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return h.device.Sync()
+}
+
+func (h *Handle) Close() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return h.device.Close()
+}
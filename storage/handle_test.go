@@ -0,0 +1,308 @@
+package storage
+
+import (
+	"aeds2-tp1/domain"
+	"aeds2-tp1/entity"
+	"context"
+	"errors"
+	"os"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestHandleMixedReadWriteWorkload(t *testing.T) {
+	for _, mode := range allModes {
+		t.Run(mode.String(), func(t *testing.T) {
+			ctx := context.Background()
+			students := testStudents(t, 1, 400)
+			handle := openTestFile(t, tempPath(t, "alunos.dat"), Options{Mode: mode, BlockSize: 1024, ScanWorkers: 4})
+			if err := handle.WriteStudents(ctx, students[:100]); err != nil {
+				t.Fatalf("WriteStudents: %v", err)
+			}
+
+			var wg sync.WaitGroup
+			errs := make(chan error, 64)
+			for writer := range 3 {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					batch := students[100+writer*100 : 200+writer*100]
+					if err := handle.AddStudents(ctx, batch); err != nil {
+						errs <- err
+					}
+				}()
+			}
+			for range 8 {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for i := range 10 {
+						if _, err := handle.FindStudentByMatricula(ctx, students[i].Matricula); err != nil {
+							errs <- err
+							return
+						}
+						all, err := handle.GetAllStudents(ctx)
+						if err != nil {
+							errs <- err
+							return
+						}
+						if len(all)%100 != 0 {
+							t.Errorf("leitura viu %d alunos, uma gravação incompleta", len(all))
+						}
+						if _, err := handle.GetStats(ctx); err != nil {
+							errs <- err
+							return
+						}
+					}
+				}()
+			}
+			wg.Wait()
+			close(errs)
+			for err := range errs {
+				t.Errorf("operação concorrente falhou: %v", err)
+			}
+
+			if got := readAllStudents(t, handle); len(got) != len(students) {
+				t.Fatalf("arquivo final com %d alunos, esperado %d", len(got), len(students))
+			}
+		})
+	}
+}
+
+func TestHandleConcurrentUpserts(t *testing.T) {
+	ctx := context.Background()
+	students := testStudents(t, 2, 50)
+	handle := openTestFile(t, tempPath(t, "alunos.dat"), Options{Mode: VariableMode, BlockSize: 1024})
+	if err := handle.WriteStudents(ctx, students); err != nil {
+		t.Fatalf("WriteStudents: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	for i := range students {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			updated := students[i]
+			updated.CA = 10
+			if err := handle.UpsertStudents(ctx, []entity.Student{updated}); err != nil {
+				t.Errorf("UpsertStudents: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	for _, student := range readAllStudents(t, handle) {
+		if student.CA != 10 {
+			t.Fatalf("matrícula %d perdeu uma atualização concorrente: CA %.2f", student.Matricula, student.CA)
+		}
+	}
+}
+
+func TestStorageGetStatsReportsOpenError(t *testing.T) {
+	storages := []Storage{}
+	for _, create := range []func(int) (Storage, error){
+		func(blockSize int) (Storage, error) { return NewFixedStorage(blockSize) },
+		func(blockSize int) (Storage, error) { return NewVariableStorage(blockSize) },
+		func(blockSize int) (Storage, error) { return NewVariableFragmentedStorage(blockSize) },
+	} {
+		storage, err := create(1024)
+		if err != nil {
+			t.Fatalf("criar storage: %v", err)
+		}
+		storages = append(storages, storage)
+	}
+
+	missing := tempPath(t, "inexistente.dat")
+	for _, storage := range storages {
+		if stats, err := storage.GetStats(context.Background(), missing); err == nil {
+			t.Errorf("%T.GetStats de arquivo inexistente devolveu %+v sem erro", storage, stats)
+		}
+	}
+}
+
+func TestWriteCancelledAfterLastRecordKeepsFile(t *testing.T) {
+	for _, mode := range allModes {
+		t.Run(mode.String(), func(t *testing.T) {
+			original := testStudents(t, 8, 30)
+			handle := openTestFile(t, tempPath(t, "alunos.dat"), Options{Mode: mode, BlockSize: 1024})
+			if err := handle.WriteStudents(context.Background(), original); err != nil {
+				t.Fatalf("WriteStudents: %v", err)
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			replacement := testStudents(t, 9, 40)
+			students := func(yield func(entity.Student, error) bool) {
+				for _, student := range replacement {
+					if !yield(student, nil) {
+						return
+					}
+				}
+				cancel()
+			}
+			if err := handle.WriteStudentsFrom(ctx, students, 0); !errors.Is(err, context.Canceled) {
+				t.Fatalf("gravação cancelada após o último registro: erro %v", err)
+			}
+			assertSameStudents(t, readAllStudents(t, handle), original)
+		})
+	}
+}
+
+func TestWriteCancelledMidwayKeepsFileAndStats(t *testing.T) {
+	for _, mode := range allModes {
+		t.Run(mode.String(), func(t *testing.T) {
+			path := tempPath(t, "alunos.dat")
+			handle := openTestFile(t, path, Options{Mode: mode, BlockSize: 1024, WriteBatchBlocks: 1})
+			original := testStudents(t, 10, 100)
+			if err := handle.WriteStudents(context.Background(), original); err != nil {
+				t.Fatalf("WriteStudents: %v", err)
+			}
+			data := readFile(t, path)
+			stats := readFile(t, path+statsFileSuffix)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			replacement := testStudents(t, 11, 300)
+			students := func(yield func(entity.Student, error) bool) {
+				for i, student := range replacement {
+					if i == len(replacement)/2 {
+						cancel()
+					}
+					if !yield(student, nil) {
+						return
+					}
+				}
+			}
+			if err := handle.WriteStudentsFrom(ctx, students, int64(len(replacement))); !errors.Is(err, context.Canceled) {
+				t.Fatalf("gravação cancelada no meio: erro %v", err)
+			}
+			if string(readFile(t, path)) != string(data) {
+				t.Fatal("arquivo de dados alterado pela gravação cancelada")
+			}
+			if string(readFile(t, path+statsFileSuffix)) != string(stats) {
+				t.Fatal("arquivo de estatísticas alterado pela gravação cancelada")
+			}
+			assertSameStudents(t, readAllStudents(t, handle), original)
+		})
+	}
+}
+
+func TestWriteKeepsFileMode(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("permissões Unix não se aplicam")
+	}
+	path := tempPath(t, "alunos.dat")
+	if err := os.WriteFile(path, nil, 0o640); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(path, 0o640); err != nil {
+		t.Fatal(err)
+	}
+
+	handle := openTestFile(t, path, Options{Mode: FixedMode, BlockSize: 1024})
+	for i := range 2 {
+		if err := handle.AddStudents(context.Background(), testStudents(t, 12, 20*(i+1))[20*i:]); err != nil {
+			t.Fatalf("AddStudents: %v", err)
+		}
+		for _, file := range []string{path, path + statsFileSuffix} {
+			info, err := os.Stat(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if info.Mode().Perm() != 0o640 {
+				t.Fatalf("gravação %d: %s com permissões %v, esperado %v", i+1, file, info.Mode().Perm(), os.FileMode(0o640))
+			}
+		}
+	}
+}
+
+func TestWriteFromFailingSourceKeepsFile(t *testing.T) {
+	ctx := context.Background()
+	failure := errors.New("falha na origem")
+	for _, mode := range allModes {
+		t.Run(mode.String(), func(t *testing.T) {
+			handle := openTestFile(t, tempPath(t, "alunos.dat"), Options{Mode: mode, BlockSize: 1024})
+			original := testStudents(t, 13, 40)
+			if err := handle.WriteStudents(ctx, original); err != nil {
+				t.Fatalf("WriteStudents: %v", err)
+			}
+
+			failing := func(yield func(entity.Student, error) bool) {
+				for _, student := range testStudents(t, 14, 100)[60:] {
+					if !yield(student, nil) {
+						return
+					}
+				}
+				yield(entity.Student{}, failure)
+			}
+			if err := handle.WriteStudentsFrom(ctx, failing, 0); !errors.Is(err, failure) {
+				t.Fatalf("WriteStudentsFrom: erro %v, esperado a falha da origem", err)
+			}
+			if err := handle.AddStudentsFrom(ctx, failing); !errors.Is(err, failure) {
+				t.Fatalf("AddStudentsFrom: erro %v, esperado a falha da origem", err)
+			}
+			if err := handle.UpsertStudentsFrom(ctx, failing); !errors.Is(err, failure) {
+				t.Fatalf("UpsertStudentsFrom: erro %v, esperado a falha da origem", err)
+			}
+			assertSameStudents(t, readAllStudents(t, handle), original)
+		})
+	}
+}
+
+func TestWriteFromExhaustedGeneratorKeepsFile(t *testing.T) {
+	ctx := context.Background()
+	handle := openTestFile(t, tempPath(t, "alunos.dat"), Options{Mode: FixedMode, BlockSize: 1024})
+	original := testStudents(t, 15, 20)
+	if err := handle.WriteStudents(ctx, original); err != nil {
+		t.Fatalf("WriteStudents: %v", err)
+	}
+
+	generator := domain.NewStudentGeneratorWithSeed(16)
+	if err := generator.StartAt(entity.MaxMatricula - 5); err != nil {
+		t.Fatal(err)
+	}
+	if err := handle.WriteStudentsFrom(ctx, generator.Stream(10), 10); err == nil || !strings.Contains(err.Error(), "esgotadas") {
+		t.Fatalf("gravação de um gerador esgotado: erro %v", err)
+	}
+	assertSameStudents(t, readAllStudents(t, handle), original)
+}
+
+func TestSchemaIsCheckedOnOpen(t *testing.T) {
+	ctx := context.Background()
+	path := tempPath(t, "alunos.dat")
+	students := testStudents(t, 50, 20)
+	writer := openTestFile(t, path, Options{Mode: FixedMode, BlockSize: 4096})
+	if err := writer.WriteStudents(ctx, students); err != nil {
+		t.Fatalf("WriteStudents: %v", err)
+	}
+
+	disciplinas, err := entity.BuiltinSchema("disciplinas")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if handle, err := Open(path, Options{Mode: FixedMode, BlockSize: 4096, Schema: disciplinas, ReadOnly: true}); !errors.Is(err, ErrSchemaMismatch) {
+		if handle != nil {
+			handle.Close()
+		}
+		t.Fatalf("arquivo de alunos aberto com o esquema de disciplinas: erro %v, esperado ErrSchemaMismatch", err)
+	}
+
+	loaded, err := entity.ResolveSchema("../entity/schemas/alunos.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	reader := openTestFile(t, path, Options{Mode: FixedMode, BlockSize: 4096, Schema: loaded, ReadOnly: true})
+	assertSameStudents(t, readAllStudents(t, reader), students)
+
+	device := NewMemoryDevice(4096)
+	handle, err := OpenDevice(device, Options{Mode: FixedMode})
+	if err != nil {
+		t.Fatalf("OpenDevice: %v", err)
+	}
+	if err := handle.WriteStudents(ctx, students); err != nil {
+		t.Fatalf("WriteStudents: %v", err)
+	}
+	if _, err := OpenDevice(device, Options{Mode: FixedMode, Schema: disciplinas}); !errors.Is(err, ErrSchemaMismatch) {
+		t.Fatalf("dispositivo de alunos aberto com o esquema de disciplinas: erro %v, esperado ErrSchemaMismatch", err)
+	}
+}
@@ -0,0 +1,66 @@
+package storage
+
+import (
+	"aeds2-tp1/domain"
+	"aeds2-tp1/entity"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var allModes = []Mode{FixedMode, VariableMode, FragmentedMode}
+
+func testStudents(t testing.TB, seed uint64, count int) []entity.Student {
+	t.Helper()
+	students, err := domain.NewStudentGeneratorWithSeed(seed).Generate(count)
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	return students
+}
+
+func openTestFile(t testing.TB, path string, options Options) *Handle {
+	t.Helper()
+	handle, err := Open(path, options)
+	if err != nil {
+		t.Fatalf("Open(%s, %s, %d): %v", path, options.Mode, options.BlockSize, err)
+	}
+	t.Cleanup(func() { handle.Close() })
+	return handle
+}
+
+func tempPath(t testing.TB, name string) string {
+	t.Helper()
+	return filepath.Join(t.TempDir(), name)
+}
+
+func readAllStudents(t testing.TB, handle *Handle) []*entity.Student {
+	t.Helper()
+	students, err := handle.GetAllStudents(context.Background())
+	if err != nil {
+		t.Fatalf("GetAllStudents: %v", err)
+	}
+	return students
+}
+
+func assertSameStudents(t testing.TB, got []*entity.Student, want []entity.Student) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("lidos %d alunos, esperado %d", len(got), len(want))
+	}
+	for i := range want {
+		if *got[i] != want[i] {
+			t.Fatalf("aluno %d difere:\n lido     %+v\n esperado %+v", i, *got[i], want[i])
+		}
+	}
+}
+
+func readFile(t testing.TB, path string) []byte {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
@@ -1,30 +1,71 @@
 package storage
 
-import "aeds2-tp1/entity"
+import (
+	"aeds2-tp1/entity"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var ErrCorruptData = errors.New("dados corrompidos no arquivo")
+
+func corruptRecordError(blockNum int64, offset int, err error) error {
+	return fmt.Errorf("%w: bloco %d, posição %d: %v", ErrCorruptData, blockNum, offset, err)
+}
 
 type StorageStats struct {
-	TotalBlocks       int
-	TotalBytesUsed    int
-	TotalBytesTotal   int
-	EfficiencyRate    float64
-	PartialBlocks     int
-	BlockStatsList    []BlockStats
+	Mode                   Mode
+	BlockSize              int
+	TotalBlocks            int64
+	TotalBytesUsed         int64
+	TotalBytesTotal        int64
+	EfficiencyRate         float64
+	PartialBlocks          int64
+	TotalPaddingBytes      int64
+	TotalLengthPrefixBytes int64
+	TotalHeaderBytes       int64
+	TotalTailBytes         int64
+	TotalRecords           int64
+	TotalFragments         int64
+	SpanningRecords        int64
+	MaxFragmentsPerRecord  int
+	AvgFragmentsPerRecord  float64
+	ExtraBlockReads        int64
+	BlockStatsList         []BlockStats
+	RecordSizes            []RecordSizeCount
 }
 
 type BlockStats struct {
-	BlockNumber   int
-	BytesUsed     int
-	BytesTotal    int
-	OccupancyRate float64
-	RecordsCount  int
+	BlockNumber            int64
+	BytesUsed              int
+	BytesTotal             int
+	OccupancyRate          float64
+	RecordsCount           int
+	PaddingBytes           int
+	LengthPrefixBytes      int
+	HeaderBytes            int
+	TailBytes              int
+	FragmentsCount         int
+	StartsWithContinuation bool
+	EndsWithContinuation   bool
+}
+
+type RecordSizeCount struct {
+	Size  int
+	Count int64
 }
 
 type Storage interface {
-	WriteStudents(filename string, students []entity.Student) error
-	FindStudentByMatricula(filename string, matricula int) (*entity.Student, error)
-	GetAllStudents(filename string) ([]*entity.Student, error)
-	AddStudents(filename string, students []entity.Student) error
-	GetStats(filename string) StorageStats
+	WriteStudents(ctx context.Context, filename string, students []entity.Student) error
+	FindStudentByMatricula(ctx context.Context, filename string, matricula int64) (*entity.Student, error)
+	GetAllStudents(ctx context.Context, filename string) ([]*entity.Student, error)
+	FindStudents(ctx context.Context, filename string, predicate func(*entity.Student) bool) ([]*entity.Student, error)
+	AddStudents(ctx context.Context, filename string, students []entity.Student) error
+	GetStats(ctx context.Context, filename string) (StorageStats, error)
 	ValidateBlockSize(blockSize int) error
 	GetBlockSize() int
+	SetLockTimeout(timeout time.Duration)
+	SetScanWorkers(workers int)
+	SetEncoding(encoding Encoding)
 }
@@ -0,0 +1,112 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func layoutBlockSizes(mode Mode) []int {
+	if mode == FragmentedMode {
+		return []int{200, 1024, 4096}
+	}
+	return []int{1024, 4096}
+}
+
+func TestLayoutsOnMemoryDevice(t *testing.T) {
+	ctx := context.Background()
+	students := testStudents(t, 21, 500)
+	for _, mode := range allModes {
+		for _, blockSize := range layoutBlockSizes(mode) {
+			t.Run(fmt.Sprintf("%s/%d", mode, blockSize), func(t *testing.T) {
+				device := NewMemoryDevice(blockSize)
+				handle, err := OpenDevice(device, Options{Mode: mode})
+				if err != nil {
+					t.Fatalf("OpenDevice: %v", err)
+				}
+				if err := handle.WriteStudents(ctx, students); err != nil {
+					t.Fatalf("WriteStudents: %v", err)
+				}
+
+				assertSameStudents(t, readAllStudents(t, handle), students)
+				for _, i := range []int{0, 1, len(students) / 2, len(students) - 1} {
+					found, err := handle.FindStudentByMatricula(ctx, students[i].Matricula)
+					if err != nil {
+						t.Fatalf("FindStudentByMatricula(%d): %v", students[i].Matricula, err)
+					}
+					if *found != students[i] {
+						t.Fatalf("FindStudentByMatricula(%d) = %+v, esperado %+v", students[i].Matricula, *found, students[i])
+					}
+				}
+				if _, err := handle.FindStudentByMatricula(ctx, 1); err == nil || !strings.Contains(err.Error(), "não encontrado") {
+					t.Fatalf("busca de matrícula ausente: erro %v", err)
+				}
+
+				stats, err := handle.GetStats(ctx)
+				if err != nil {
+					t.Fatalf("GetStats: %v", err)
+				}
+				blocks, _ := device.BlockCount()
+				if stats.TotalRecords != int64(len(students)) || stats.TotalBlocks != blocks || stats.BlockSize != blockSize || stats.Mode != mode {
+					t.Fatalf("estatísticas %d registros em %d blocos de %d bytes (%s), dispositivo com %d blocos", stats.TotalRecords, stats.TotalBlocks, stats.BlockSize, stats.Mode, blocks)
+				}
+				if mode == FragmentedMode && blockSize == 200 && stats.SpanningRecords == 0 {
+					t.Fatalf("blocos de 200 bytes deveriam espalhar registros entre blocos")
+				}
+			})
+		}
+	}
+}
+
+func TestLayoutsRejectSmallBlocks(t *testing.T) {
+	for _, mode := range allModes {
+		if _, err := OpenDevice(NewMemoryDevice(64), Options{Mode: mode}); err == nil {
+			t.Errorf("%s: bloco de 64 bytes aceito", mode)
+		}
+	}
+}
+
+func TestOpenDeviceRejectsBlockSizeMismatch(t *testing.T) {
+	if _, err := OpenDevice(NewMemoryDevice(1024), Options{Mode: FixedMode, BlockSize: 2048}); err == nil {
+		t.Fatal("OpenDevice aceitou tamanho de bloco diferente do dispositivo")
+	}
+}
+
+func TestMemoryDeviceBounds(t *testing.T) {
+	device := NewMemoryDevice(16)
+	buf := make([]byte, 16)
+	if err := device.ReadBlock(0, buf); err == nil {
+		t.Error("leitura de bloco inexistente sem erro")
+	}
+	if err := device.WriteBlock(1, buf); err == nil {
+		t.Error("gravação além do fim do dispositivo sem erro")
+	}
+	if err := device.WriteBlock(0, []byte("abc")); err != nil {
+		t.Fatalf("WriteBlock: %v", err)
+	}
+	if err := device.ReadBlock(0, buf); err != nil || string(buf[:3]) != "abc" || buf[3] != 0 {
+		t.Fatalf("bloco lido %q, erro %v", buf, err)
+	}
+	if err := device.Truncate(3); err != nil {
+		t.Fatalf("Truncate: %v", err)
+	}
+	if blocks, _ := device.BlockCount(); blocks != 3 {
+		t.Fatalf("após Truncate(3), %d blocos", blocks)
+	}
+}
+
+func TestEmptyDevice(t *testing.T) {
+	for _, mode := range allModes {
+		handle, err := OpenDevice(NewMemoryDevice(1024), Options{Mode: mode})
+		if err != nil {
+			t.Fatalf("OpenDevice: %v", err)
+		}
+		if students := readAllStudents(t, handle); len(students) != 0 {
+			t.Errorf("%s: dispositivo vazio com %d alunos", mode, len(students))
+		}
+		if highest, err := handle.MaxMatricula(context.Background()); err != nil || highest != 0 {
+			t.Errorf("%s: MaxMatricula em dispositivo vazio = %d, %v", mode, highest, err)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+type lockMode int
+
+const (
+	sharedLock lockMode = iota
+	exclusiveLock
+)
+
+const lockRetryInterval = 50 * time.Millisecond
+
+var ErrFileLocked = errors.New("arquivo em uso por outro processo")
+
+var errWouldBlock = errors.New("operação bloquearia")
+
+type lockableFile interface {
+	Name() string
+	Fd() uintptr
+}
+
+func lockFile(ctx context.Context, file lockableFile, mode lockMode, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		err := tryLockFile(file, mode)
+		if err == nil {
+			return nil
+		}
+		if !errors.Is(err, errWouldBlock) {
+			return fmt.Errorf("erro ao bloquear arquivo %s: %w", file.Name(), err)
+		}
+		if timeout <= 0 || time.Now().After(deadline) {
+			return fmt.Errorf("%w: %s", ErrFileLocked, file.Name())
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(lockRetryInterval):
+		}
+	}
+}
@@ -0,0 +1,11 @@
+//go:build !unix && !windows
+
+package storage
+
+func tryLockFile(file lockableFile, mode lockMode) error {
+	return nil
+}
+
+func unlockFile(file lockableFile) error {
+	return nil
+}
@@ -0,0 +1,115 @@
+//go:build unix || windows
+
+package storage
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWriterBlocksOtherHandle(t *testing.T) {
+	ctx := context.Background()
+	path := tempPath(t, "alunos.dat")
+	students := testStudents(t, 3, 20)
+	writer := openTestFile(t, path, Options{Mode: FixedMode, BlockSize: 1024})
+	other := openTestFile(t, path, Options{Mode: FixedMode, BlockSize: 1024})
+	if err := writer.WriteStudents(ctx, students); err != nil {
+		t.Fatalf("WriteStudents: %v", err)
+	}
+
+	if err := writer.beginWrite(ctx); err != nil {
+		t.Fatalf("beginWrite: %v", err)
+	}
+	if _, err := other.GetAllStudents(ctx); !errors.Is(err, ErrFileLocked) {
+		t.Errorf("leitura durante gravação de outro handle: erro %v, esperado ErrFileLocked", err)
+	}
+	if err := other.AddStudents(ctx, testStudents(t, 4, 1)); !errors.Is(err, ErrFileLocked) {
+		t.Errorf("gravação concorrente de outro handle: erro %v, esperado ErrFileLocked", err)
+	}
+	writer.endWrite()
+
+	if got := readAllStudents(t, other); len(got) != len(students) {
+		t.Fatalf("após liberar o bloqueio, lidos %d alunos, esperado %d", len(got), len(students))
+	}
+}
+
+func TestReadersShareLock(t *testing.T) {
+	ctx := context.Background()
+	path := tempPath(t, "alunos.dat")
+	first := openTestFile(t, path, Options{Mode: VariableMode, BlockSize: 1024})
+	second := openTestFile(t, path, Options{Mode: VariableMode, BlockSize: 1024, ReadOnly: true})
+	if err := first.WriteStudents(ctx, testStudents(t, 5, 10)); err != nil {
+		t.Fatalf("WriteStudents: %v", err)
+	}
+
+	if err := first.beginRead(ctx); err != nil {
+		t.Fatalf("beginRead: %v", err)
+	}
+	defer first.endRead()
+	if got := readAllStudents(t, second); len(got) != 10 {
+		t.Fatalf("leitura com bloqueio compartilhado: %d alunos, esperado 10", len(got))
+	}
+}
+
+func TestLockTimeoutWaitsForWriter(t *testing.T) {
+	ctx := context.Background()
+	path := tempPath(t, "alunos.dat")
+	writer := openTestFile(t, path, Options{Mode: FragmentedMode, BlockSize: 1024})
+	waiter := openTestFile(t, path, Options{Mode: FragmentedMode, BlockSize: 1024, LockTimeout: 5 * time.Second})
+	if err := writer.beginWrite(ctx); err != nil {
+		t.Fatalf("beginWrite: %v", err)
+	}
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		writer.endWrite()
+	}()
+
+	if err := waiter.WriteStudents(ctx, testStudents(t, 6, 5)); err != nil {
+		t.Fatalf("gravação deveria esperar o bloqueio ser liberado: %v", err)
+	}
+}
+
+func TestTwoHandlesMixedWorkload(t *testing.T) {
+	ctx := context.Background()
+	path := tempPath(t, "alunos.dat")
+	students := testStudents(t, 7, 300)
+	options := Options{Mode: VariableMode, BlockSize: 1024, LockTimeout: 10 * time.Second}
+	handles := []*Handle{openTestFile(t, path, options), openTestFile(t, path, options)}
+	if err := handles[0].WriteStudents(ctx, students[:100]); err != nil {
+		t.Fatalf("WriteStudents: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	for i, handle := range handles {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			if err := handle.AddStudents(ctx, students[100+i*100:200+i*100]); err != nil {
+				t.Errorf("AddStudents no handle %d: %v", i, err)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for range 10 {
+				all, err := handle.GetAllStudents(ctx)
+				if err != nil {
+					t.Errorf("GetAllStudents no handle %d: %v", i, err)
+					return
+				}
+				if len(all)%100 != 0 {
+					t.Errorf("handle %d viu %d alunos, uma gravação incompleta", i, len(all))
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	for i, handle := range handles {
+		if got := readAllStudents(t, handle); len(got) != len(students) {
+			t.Errorf("handle %d leu %d alunos, esperado %d", i, len(got), len(students))
+		}
+	}
+}
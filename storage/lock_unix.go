@@ -0,0 +1,26 @@
+//go:build unix
+
+package storage
+
+import (
+	"errors"
+
+	"golang.org/x/sys/unix"
+)
+
+func tryLockFile(file lockableFile, mode lockMode) error {
+	how := unix.LOCK_SH
+	if mode == exclusiveLock {
+		how = unix.LOCK_EX
+	}
+
+	err := unix.Flock(int(file.Fd()), how|unix.LOCK_NB)
+	if errors.Is(err, unix.EWOULDBLOCK) {
+		return errWouldBlock
+	}
+	return err
+}
+
+func unlockFile(file lockableFile) error {
+	return unix.Flock(int(file.Fd()), unix.LOCK_UN)
+}
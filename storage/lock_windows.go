@@ -0,0 +1,28 @@
+//go:build windows
+
+package storage
+
+import (
+	"errors"
+
+	"golang.org/x/sys/windows"
+)
+
+func tryLockFile(file lockableFile, mode lockMode) error {
+	flags := uint32(windows.LOCKFILE_FAIL_IMMEDIATELY)
+	if mode == exclusiveLock {
+		flags |= windows.LOCKFILE_EXCLUSIVE_LOCK
+	}
+
+	overlapped := new(windows.Overlapped)
+	err := windows.LockFileEx(windows.Handle(file.Fd()), flags, 0, 1, 0, overlapped)
+	if errors.Is(err, windows.ERROR_LOCK_VIOLATION) {
+		return errWouldBlock
+	}
+	return err
+}
+
+func unlockFile(file lockableFile) error {
+	overlapped := new(windows.Overlapped)
+	return windows.UnlockFileEx(windows.Handle(file.Fd()), 0, 1, 0, overlapped)
+}
@@ -0,0 +1,162 @@
+package storage
+
+import (
+	"runtime"
+	"sync"
+)
+
+const minBlocksPerWorker = 64
+
+type blockRange struct {
+	start int64
+	end   int64
+}
+
+func defaultScanWorkers() int {
+	return runtime.NumCPU()
+}
+
+func partitionBlocks(totalBlocks int64, workers int) []blockRange {
+	if workers < 1 {
+		workers = 1
+	}
+	if maxWorkers := totalBlocks / minBlocksPerWorker; int64(workers) > maxWorkers {
+		workers = int(maxWorkers)
+	}
+	if workers < 1 {
+		workers = 1
+	}
+
+	ranges := make([]blockRange, 0, workers)
+	chunk := totalBlocks / int64(workers)
+	remainder := totalBlocks % int64(workers)
+	start := int64(0)
+	for i := int64(0); i < int64(workers); i++ {
+		end := start + chunk
+		if i < remainder {
+			end++
+		}
+		ranges = append(ranges, blockRange{start: start, end: end})
+		start = end
+	}
+	return ranges
+}
+
+func parallelScan[T any](totalBlocks int64, workers int, scan func(start, end int64) ([]T, error)) ([]T, error) {
+	ranges := partitionBlocks(totalBlocks, workers)
+	if len(ranges) == 1 {
+		return scan(0, totalBlocks)
+	}
+
+	results := make([][]T, len(ranges))
+	errs := make([]error, len(ranges))
+
+	var wg sync.WaitGroup
+	for i, r := range ranges {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i], errs[i] = scan(r.start, r.end)
+		}()
+	}
+	wg.Wait()
+
+	total := 0
+	for i := range ranges {
+		if errs[i] != nil {
+			return nil, errs[i]
+		}
+		total += len(results[i])
+	}
+
+	merged := make([]T, 0, total)
+	for _, result := range results {
+		merged = append(merged, result...)
+	}
+	return merged, nil
+}
+
+func summarizeBlockStats(mode Mode, blockSize int, blockStatsList []BlockStats, recordSizes []RecordSizeCount) StorageStats {
+	totals := newStatsTotals(mode, blockSize)
+	for _, blockStats := range blockStatsList {
+		totals.add(blockStats)
+	}
+	stats := totals.summary(recordSizes)
+	stats.BlockStatsList = blockStatsList
+	return stats
+}
+
+type statsTotals struct {
+	stats   StorageStats
+	pending int
+}
+
+func newStatsTotals(mode Mode, blockSize int) *statsTotals {
+	return &statsTotals{stats: StorageStats{Mode: mode, BlockSize: blockSize}}
+}
+
+func (t *statsTotals) add(blockStats BlockStats) {
+	stats := &t.stats
+	stats.TotalBlocks++
+	stats.TotalBytesUsed += int64(blockStats.BytesUsed)
+	stats.TotalBytesTotal += int64(blockStats.BytesTotal)
+	stats.TotalPaddingBytes += int64(blockStats.PaddingBytes)
+	stats.TotalLengthPrefixBytes += int64(blockStats.LengthPrefixBytes)
+	stats.TotalHeaderBytes += int64(blockStats.HeaderBytes)
+	stats.TotalTailBytes += int64(blockStats.TailBytes)
+	stats.TotalRecords += int64(blockStats.RecordsCount)
+	stats.TotalFragments += int64(blockStats.FragmentsCount)
+	if blockStats.OccupancyRate < 100 && blockStats.OccupancyRate > 0 {
+		stats.PartialBlocks++
+	}
+	t.addSpanning(blockStats)
+}
+
+func (t *statsTotals) finishRecord(fragments int) {
+	t.stats.MaxFragmentsPerRecord = max(t.stats.MaxFragmentsPerRecord, fragments)
+	if fragments > 1 {
+		t.stats.SpanningRecords++
+	}
+}
+
+func (t *statsTotals) addSpanning(blockStats BlockStats) {
+	remaining := blockStats.FragmentsCount
+	if remaining == 0 {
+		t.pending = 0
+		return
+	}
+
+	if blockStats.StartsWithContinuation && t.pending > 0 {
+		t.pending++
+		remaining--
+		if remaining == 0 && blockStats.EndsWithContinuation {
+			return
+		}
+		t.finishRecord(t.pending)
+		t.pending = 0
+	}
+
+	if blockStats.EndsWithContinuation {
+		remaining--
+		t.pending = 1
+	}
+	if remaining > 0 {
+		t.finishRecord(1)
+	}
+}
+
+func (t *statsTotals) summary(recordSizes []RecordSizeCount) StorageStats {
+	stats := t.stats
+	stats.RecordSizes = recordSizes
+	if stats.TotalBytesTotal > 0 {
+		stats.EfficiencyRate = float64(stats.TotalBytesUsed) / float64(stats.TotalBytesTotal) * 100
+	}
+	if stats.TotalFragments == 0 || stats.TotalRecords == 0 {
+		stats.SpanningRecords = 0
+		stats.MaxFragmentsPerRecord = 0
+		return stats
+	}
+	stats.AvgFragmentsPerRecord = float64(stats.TotalFragments) / float64(stats.TotalRecords)
+	stats.ExtraBlockReads = stats.TotalFragments - stats.TotalRecords
+	return stats
+}
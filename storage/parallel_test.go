@@ -0,0 +1,123 @@
+package storage
+
+import (
+	"aeds2-tp1/entity"
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+var scanBlockSizes = map[Mode]int{FixedMode: 1024, VariableMode: 1024, FragmentedMode: 300}
+
+func writeMemoryDevice(t testing.TB, mode Mode, blockSize, count int) *MemoryDevice {
+	t.Helper()
+	device := NewMemoryDevice(blockSize)
+	handle, err := OpenDevice(device, Options{Mode: mode})
+	if err != nil {
+		t.Fatalf("OpenDevice: %v", err)
+	}
+	if err := handle.WriteStudents(context.Background(), testStudents(t, 11, count)); err != nil {
+		t.Fatalf("WriteStudents: %v", err)
+	}
+	return device
+}
+
+func TestPartitionBlocksCoversRangeInOrder(t *testing.T) {
+	for _, totalBlocks := range []int64{0, 1, 63, 64, 129, 1000, 1001} {
+		for _, workers := range []int{1, 3, 8, 64} {
+			next := int64(0)
+			for _, r := range partitionBlocks(totalBlocks, workers) {
+				if r.start != next || r.end < r.start {
+					t.Fatalf("partitionBlocks(%d, %d): faixa %+v fora de ordem (esperado início %d)", totalBlocks, workers, r, next)
+				}
+				next = r.end
+			}
+			if next != totalBlocks {
+				t.Fatalf("partitionBlocks(%d, %d) termina em %d", totalBlocks, workers, next)
+			}
+		}
+	}
+}
+
+func TestParallelScanMatchesSequential(t *testing.T) {
+	ctx := context.Background()
+	for _, mode := range allModes {
+		t.Run(mode.String(), func(t *testing.T) {
+			device := writeMemoryDevice(t, mode, scanBlockSizes[mode], 3000)
+			sequential, err := OpenDevice(device, Options{Mode: mode, ScanWorkers: 1})
+			if err != nil {
+				t.Fatalf("OpenDevice: %v", err)
+			}
+			parallel, err := OpenDevice(device, Options{Mode: mode, ScanWorkers: 8})
+			if err != nil {
+				t.Fatalf("OpenDevice: %v", err)
+			}
+			if blocks, _ := device.BlockCount(); len(partitionBlocks(blocks, 8)) < 2 {
+				t.Fatalf("%d blocos não chegam a ser divididos entre os workers", blocks)
+			}
+
+			want := readAllStudents(t, sequential)
+			if got := readAllStudents(t, parallel); !reflect.DeepEqual(got, want) {
+				t.Fatalf("varredura paralela leu %d alunos em ordem diferente da sequencial (%d)", len(got), len(want))
+			}
+
+			predicate := func(student *entity.Student) bool { return student.Matricula%7 == 0 }
+			wantFound, err := sequential.FindStudents(ctx, predicate)
+			if err != nil {
+				t.Fatalf("FindStudents sequencial: %v", err)
+			}
+			gotFound, err := parallel.FindStudents(ctx, predicate)
+			if err != nil {
+				t.Fatalf("FindStudents paralelo: %v", err)
+			}
+			if !reflect.DeepEqual(gotFound, wantFound) {
+				t.Fatalf("FindStudents paralelo encontrou %d alunos, sequencial %d", len(gotFound), len(wantFound))
+			}
+
+			wantStats, err := sequential.RecomputeStats(ctx)
+			if err != nil {
+				t.Fatalf("RecomputeStats sequencial: %v", err)
+			}
+			gotStats, err := parallel.RecomputeStats(ctx)
+			if err != nil {
+				t.Fatalf("RecomputeStats paralelo: %v", err)
+			}
+			if !reflect.DeepEqual(gotStats, wantStats) {
+				t.Fatalf("estatísticas da varredura paralela diferem da sequencial:\n paralela   %+v\n sequencial %+v", summary(gotStats), summary(wantStats))
+			}
+		})
+	}
+}
+
+func summary(stats StorageStats) StorageStats {
+	stats.BlockStatsList = nil
+	return stats
+}
+
+func BenchmarkScan(b *testing.B) {
+	workerCounts := []int{1, 2, 4, 8}
+	for _, mode := range allModes {
+		device := writeMemoryDevice(b, mode, scanBlockSizes[mode], 20000)
+		for _, workers := range workerCounts {
+			handle, err := OpenDevice(device, Options{Mode: mode, ScanWorkers: workers})
+			if err != nil {
+				b.Fatalf("OpenDevice: %v", err)
+			}
+			b.Run(fmt.Sprintf("%s/GetAllStudents/workers-%d", mode, workers), func(b *testing.B) {
+				for b.Loop() {
+					if _, err := handle.GetAllStudents(context.Background()); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+			b.Run(fmt.Sprintf("%s/stats/workers-%d", mode, workers), func(b *testing.B) {
+				for b.Loop() {
+					if _, err := handle.RecomputeStats(context.Background()); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
+	}
+}
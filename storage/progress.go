@@ -0,0 +1,135 @@
+package storage
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+var progressReportInterval = 100 * time.Millisecond
+
+const (
+	OperationRead  = "leitura"
+	OperationWrite = "gravação"
+	OperationFind  = "busca"
+	OperationStats = "estatísticas"
+)
+
+type Progress struct {
+	Operation    string
+	BlocksDone   int64
+	TotalBlocks  int64
+	RecordsDone  int64
+	TotalRecords int64
+	BytesDone    int64
+	Elapsed      time.Duration
+	ETA          time.Duration
+	Done         bool
+}
+
+type ProgressFunc func(Progress)
+
+type progressKey struct{}
+
+func WithProgress(ctx context.Context, report ProgressFunc) context.Context {
+	return context.WithValue(ctx, progressKey{}, report)
+}
+
+func progressFromContext(ctx context.Context) ProgressFunc {
+	report, _ := ctx.Value(progressKey{}).(ProgressFunc)
+	return report
+}
+
+type progressTracker struct {
+	mu         sync.Mutex
+	report     ProgressFunc
+	start      time.Time
+	lastReport time.Time
+	progress   Progress
+}
+
+func newProgressTracker(ctx context.Context, operation string, totalBlocks, totalRecords int64) *progressTracker {
+	report := progressFromContext(ctx)
+	if report == nil {
+		return nil
+	}
+
+	now := time.Now()
+	return &progressTracker{
+		report:     report,
+		start:      now,
+		lastReport: now,
+		progress: Progress{
+			Operation:    operation,
+			TotalBlocks:  totalBlocks,
+			TotalRecords: totalRecords,
+		},
+	}
+}
+
+func (t *progressTracker) addBlock(bytes int) {
+	if t == nil {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.progress.BlocksDone++
+	t.progress.BytesDone += int64(bytes)
+	t.maybeReport()
+}
+
+func (t *progressTracker) addRecords(count int) {
+	if t == nil {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.progress.RecordsDone += int64(count)
+	t.maybeReport()
+}
+
+func (t *progressTracker) finish() {
+	if t == nil {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.progress.Done = true
+	t.progress.Elapsed = time.Since(t.start)
+	t.progress.ETA = 0
+	t.report(t.progress)
+}
+
+func (t *progressTracker) maybeReport() {
+	now := time.Now()
+	if now.Sub(t.lastReport) < progressReportInterval {
+		return
+	}
+	t.lastReport = now
+
+	t.progress.Elapsed = now.Sub(t.start)
+	t.progress.ETA = 0
+	if fraction := t.fractionDone(); fraction > 0 && fraction < 1 {
+		t.progress.ETA = time.Duration(float64(t.progress.Elapsed) * (1 - fraction) / fraction)
+	}
+	t.report(t.progress)
+}
+
+func (t *progressTracker) fractionDone() float64 {
+	if t.progress.TotalBlocks > 0 {
+		return float64(t.progress.BlocksDone) / float64(t.progress.TotalBlocks)
+	}
+	if t.progress.TotalRecords > 0 {
+		return float64(t.progress.RecordsDone) / float64(t.progress.TotalRecords)
+	}
+	return 0
+}
+
+func reportRecords(device BlockDevice, count int) {
+	if tracked, ok := device.(contextDevice); ok && count > 0 {
+		tracked.tracker.addRecords(count)
+	}
+}
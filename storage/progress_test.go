@@ -0,0 +1,92 @@
+package storage
+
+import (
+	"context"
+	"os"
+	"testing"
+)
+
+func collectProgress(t *testing.T, operation func(ctx context.Context) error) []Progress {
+	t.Helper()
+	reports := make([]Progress, 0)
+	ctx := WithProgress(context.Background(), func(progress Progress) {
+		reports = append(reports, progress)
+	})
+	if err := operation(ctx); err != nil {
+		t.Fatal(err)
+	}
+	return reports
+}
+
+func assertMonotonicProgress(t *testing.T, reports []Progress, operation string, blocks, records, bytes int64) {
+	t.Helper()
+	if len(reports) < 3 {
+		t.Fatalf("%s: apenas %d relatórios de progresso", operation, len(reports))
+	}
+	for i, progress := range reports {
+		if progress.Operation != operation {
+			t.Fatalf("relatório %d da operação %q, esperado %q", i, progress.Operation, operation)
+		}
+		if progress.Done != (i == len(reports)-1) {
+			t.Fatalf("%s: relatório %d com Done=%v", operation, i, progress.Done)
+		}
+		if i == 0 {
+			continue
+		}
+		previous := reports[i-1]
+		if progress.BlocksDone < previous.BlocksDone || progress.RecordsDone < previous.RecordsDone || progress.BytesDone < previous.BytesDone || progress.Elapsed < previous.Elapsed {
+			t.Fatalf("%s: progresso retrocedeu do relatório %d para o %d:\n %+v\n %+v", operation, i-1, i, previous, progress)
+		}
+	}
+
+	final := reports[len(reports)-1]
+	if final.BlocksDone != blocks || final.RecordsDone != records || final.BytesDone != bytes || final.ETA != 0 {
+		t.Fatalf("%s: relatório final %+v, esperado %d blocos, %d registros e %d bytes", operation, final, blocks, records, bytes)
+	}
+}
+
+func TestProgressIsMonotonicAndEndsAtFileSize(t *testing.T) {
+	previousInterval := progressReportInterval
+	progressReportInterval = 0
+	t.Cleanup(func() { progressReportInterval = previousInterval })
+
+	students := testStudents(t, 32, 400)
+	for _, mode := range allModes {
+		t.Run(mode.String(), func(t *testing.T) {
+			path := tempPath(t, "alunos.dat")
+			handle := openTestFile(t, path, Options{Mode: mode, BlockSize: 512, ScanWorkers: 4})
+
+			writes := collectProgress(t, func(ctx context.Context) error {
+				return handle.WriteStudents(ctx, students)
+			})
+			info, err := os.Stat(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			size := info.Size()
+			blocks := size / 512
+			if blocks < 10 {
+				t.Fatalf("arquivo com apenas %d blocos", blocks)
+			}
+			if final := writes[len(writes)-1]; final.TotalRecords != int64(len(students)) {
+				t.Fatalf("gravação com total de %d registros, esperado %d", final.TotalRecords, len(students))
+			}
+			assertMonotonicProgress(t, writes, OperationWrite, blocks, int64(len(students)), size)
+
+			reads := collectProgress(t, func(ctx context.Context) error {
+				_, err := handle.GetAllStudents(ctx)
+				return err
+			})
+			if final := reads[len(reads)-1]; final.TotalBlocks != blocks {
+				t.Fatalf("leitura com total de %d blocos, esperado %d", final.TotalBlocks, blocks)
+			}
+			assertMonotonicProgress(t, reads, OperationRead, blocks, int64(len(students)), size)
+
+			scans := collectProgress(t, func(ctx context.Context) error {
+				_, err := handle.RecomputeStats(ctx)
+				return err
+			})
+			assertMonotonicProgress(t, scans, OperationStats, blocks, int64(len(students)), size)
+		})
+	}
+}
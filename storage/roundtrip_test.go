@@ -0,0 +1,114 @@
+package storage
+
+import (
+	"aeds2-tp1/domain"
+	"aeds2-tp1/entity"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func roundTrip(t *testing.T, mode Mode, blockSize int, encoding Encoding, students []entity.Student) {
+	t.Helper()
+	ctx := context.Background()
+	handle, err := OpenDevice(NewMemoryDevice(blockSize), Options{Mode: mode, Encoding: encoding})
+	if err != nil {
+		t.Fatalf("OpenDevice: %v", err)
+	}
+	if err := handle.WriteStudents(ctx, students); err != nil {
+		t.Fatalf("WriteStudents: %v", err)
+	}
+	assertSameStudents(t, readAllStudents(t, handle), students)
+	for _, student := range students {
+		found, err := handle.FindStudentByMatricula(ctx, student.Matricula)
+		if err != nil {
+			t.Fatalf("FindStudentByMatricula(%d): %v", student.Matricula, err)
+		}
+		if *found != student {
+			t.Fatalf("busca da matrícula %d leu %+v, gravado %+v", student.Matricula, *found, student)
+		}
+	}
+}
+
+func TestEdgeCasesRoundTrip(t *testing.T) {
+	for _, edgeCase := range domain.EdgeCases {
+		for _, encoding := range []Encoding{UTF8Encoding, Latin1Encoding} {
+			if encoding == Latin1Encoding && edgeCase == domain.SpecialCharsEdgeCase {
+				continue
+			}
+			for _, mode := range allModes {
+				for _, blockSize := range []int{1024, 4096} {
+					t.Run(fmt.Sprintf("%s/%s/%s/%d", edgeCase, encoding, mode, blockSize), func(t *testing.T) {
+						students, err := domain.NewStudentGeneratorWithSeed(61).GenerateEdgeCase(edgeCase, 100, domain.EdgeCaseOptions{
+							BlockSize: blockSize,
+							RecordSize: func(student entity.Student) int {
+								size, _ := RecordSize(VariableMode, encoding, student)
+								return size
+							},
+						})
+						if err != nil {
+							t.Fatalf("GenerateEdgeCase: %v", err)
+						}
+						roundTrip(t, mode, blockSize, encoding, students)
+					})
+				}
+			}
+		}
+	}
+}
+
+func TestHashSuffixRoundTrip(t *testing.T) {
+	students := testStudents(t, 62, 6)
+	students[0].Nome = "#"
+	students[1].Nome = "Ana#"
+	students[2].Nome = strings.Repeat("#", entity.MaxNomeLength)
+	students[3].Nome = strings.Repeat("a", entity.MaxNomeLength-1) + "#"
+	students[4].Curso = "Computação##"
+	students[5].FiliacaoPai = strings.Repeat("b", entity.MaxFiliacaoLength-2) + "##"
+	for _, mode := range allModes {
+		t.Run(mode.String(), func(t *testing.T) {
+			roundTrip(t, mode, 1024, UTF8Encoding, students)
+		})
+	}
+}
+
+func TestTextRejectsNUL(t *testing.T) {
+	student := testStudents(t, 63, 1)[0]
+	student.Nome = "Ana\x00Silva"
+	handle, err := OpenDevice(NewMemoryDevice(1024), Options{Mode: FixedMode})
+	if err != nil {
+		t.Fatalf("OpenDevice: %v", err)
+	}
+	if err := handle.WriteStudents(context.Background(), []entity.Student{student}); err == nil {
+		t.Fatal("nome com NUL aceito")
+	}
+}
+
+func TestSeededDatasetIsByteIdentical(t *testing.T) {
+	golden := map[Mode]string{
+		FixedMode:      "c9b4b30a20e7dd428ca4c7c6091652d69bd89badf6c590c8808f146e2ca059ad",
+		VariableMode:   "5e9077d1c8fa44faab17f6f13ff5e7ed03e0e95bf69f224832997ccce2ef0f0a",
+		FragmentedMode: "5b5969845171f2f8067a98bb66b837cc75e8ee64f03c42870b0bf3f6a04424c5",
+	}
+	profile, err := domain.BuiltinProfile("realista")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, mode := range allModes {
+		t.Run(mode.String(), func(t *testing.T) {
+			path := tempPath(t, "alunos.dat")
+			handle := openTestFile(t, path, Options{Mode: mode, BlockSize: 512, Encoding: Latin1Encoding})
+			generator := domain.NewStudentGeneratorWithProfile(44, profile)
+			if err := handle.WriteStudentsFrom(context.Background(), generator.Stream(500), 500); err != nil {
+				t.Fatalf("WriteStudentsFrom: %v", err)
+			}
+			handle.Close()
+			sum := fmt.Sprintf("%x", sha256.Sum256(readFile(t, path)))
+			if sum != golden[mode] {
+				t.Fatalf("alunos.dat gerado com a semente 44 tem SHA-256 %s, esperado %s", sum, golden[mode])
+			}
+		})
+	}
+}
@@ -0,0 +1,575 @@
+package storage
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+)
+
+const (
+	statsFileSuffix = ".stats"
+	statsVersion    = 6
+	statsHeaderSize = 136
+	statsEntrySize  = 28
+	statsSizeEntry  = 12
+)
+
+const (
+	startsWithContinuationFlag = 1 << iota
+	endsWithContinuationFlag
+)
+
+var statsMagic = []byte("AEDSSTAT")
+
+type statsStore interface {
+	beginStats(mode Mode, encoding Encoding, schema uint64, keptBlocks int64) (statsWriter, error)
+	loadStats(mode Mode, withBlocks bool) (StorageStats, bool)
+	recordedFormat() (Encoding, uint64, bool)
+}
+
+type statsWriter interface {
+	add(blockStats BlockStats) error
+	addRecords(size int, count int64)
+	commit() error
+	abort()
+}
+
+func newBlockStats(blockNum int64, bytesUsed, bytesTotal, recordsCount int) BlockStats {
+	return BlockStats{
+		BlockNumber:   blockNum,
+		BytesUsed:     bytesUsed,
+		BytesTotal:    bytesTotal,
+		OccupancyRate: float64(bytesUsed) / float64(bytesTotal) * 100,
+		RecordsCount:  recordsCount,
+		TailBytes:     bytesTotal - bytesUsed,
+	}
+}
+
+func continuationFlags(blockStats BlockStats) uint32 {
+	flags := uint32(0)
+	if blockStats.StartsWithContinuation {
+		flags |= startsWithContinuationFlag
+	}
+	if blockStats.EndsWithContinuation {
+		flags |= endsWithContinuationFlag
+	}
+	return flags
+}
+
+type recordSizeCounter map[int]int64
+
+func (rc recordSizeCounter) add(size int, count int64) {
+	rc[size] += count
+}
+
+func (rc recordSizeCounter) merge(other recordSizeCounter) {
+	for size, count := range other {
+		rc[size] += count
+	}
+}
+
+func (rc recordSizeCounter) histogram() []RecordSizeCount {
+	recordSizes := make([]RecordSizeCount, 0, len(rc))
+	for size, count := range rc {
+		recordSizes = append(recordSizes, RecordSizeCount{Size: size, Count: count})
+	}
+	slices.SortFunc(recordSizes, func(a, b RecordSizeCount) int {
+		return a.Size - b.Size
+	})
+	return recordSizes
+}
+
+type fileStatsWriter struct {
+	device      *FileDevice
+	file        *os.File
+	writer      *bufio.Writer
+	encoding    Encoding
+	schema      uint64
+	blocks      int64
+	keptBlocks  int64
+	totals      *statsTotals
+	recordSizes recordSizeCounter
+}
+
+func StatsPath(path string) string {
+	return path + statsFileSuffix
+}
+
+func (fd *FileDevice) statsPath() string {
+	return StatsPath(fd.path)
+}
+
+func (fd *FileDevice) beginStats(mode Mode, encoding Encoding, schema uint64, keptBlocks int64) (statsWriter, error) {
+	if fd.path == "" {
+		return nil, nil
+	}
+
+	if keptBlocks > 0 {
+		header, ok := fd.currentStatsHeader()
+		if !ok || header.mode != mode || header.blockSize != fd.blockSize || header.encoding != encoding || header.schema != schema || header.blocks < keptBlocks {
+			keptBlocks = 0
+		}
+	}
+
+	file, err := os.CreateTemp(filepath.Dir(fd.path), filepath.Base(fd.statsPath())+".tmp-*")
+	if err != nil {
+		return nil, fmt.Errorf("erro ao criar arquivo de estatísticas: %w", err)
+	}
+	if err := matchFileMode(file, fd.statsPath(), fd.path); err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return nil, err
+	}
+
+	writer := bufio.NewWriter(file)
+	if keptBlocks == 0 {
+		if _, err := writer.Write(make([]byte, statsHeaderSize)); err != nil {
+			file.Close()
+			os.Remove(file.Name())
+			return nil, fmt.Errorf("erro ao gravar estatísticas: %w", err)
+		}
+	}
+
+	return &fileStatsWriter{
+		device:      fd,
+		file:        file,
+		writer:      writer,
+		encoding:    encoding,
+		schema:      schema,
+		keptBlocks:  keptBlocks,
+		totals:      newStatsTotals(mode, fd.blockSize),
+		recordSizes: recordSizeCounter{},
+	}, nil
+}
+
+func (sw *fileStatsWriter) add(blockStats BlockStats) error {
+	if blockStats.BlockNumber != sw.blocks {
+		return fmt.Errorf("erro ao gravar estatísticas: bloco %d fora de ordem (esperado %d)", blockStats.BlockNumber, sw.blocks)
+	}
+
+	sw.totals.add(blockStats)
+	sw.blocks++
+	if blockStats.BlockNumber < sw.keptBlocks {
+		return nil
+	}
+
+	entry := make([]byte, statsEntrySize)
+	binary.LittleEndian.PutUint32(entry[0:4], uint32(blockStats.BytesUsed))
+	binary.LittleEndian.PutUint32(entry[4:8], uint32(blockStats.RecordsCount))
+	binary.LittleEndian.PutUint32(entry[8:12], uint32(blockStats.PaddingBytes))
+	binary.LittleEndian.PutUint32(entry[12:16], uint32(blockStats.LengthPrefixBytes))
+	binary.LittleEndian.PutUint32(entry[16:20], uint32(blockStats.HeaderBytes))
+	binary.LittleEndian.PutUint32(entry[20:24], uint32(blockStats.FragmentsCount))
+	binary.LittleEndian.PutUint32(entry[24:28], continuationFlags(blockStats))
+	if _, err := sw.writer.Write(entry); err != nil {
+		return fmt.Errorf("erro ao gravar estatísticas: %w", err)
+	}
+	return nil
+}
+
+func (sw *fileStatsWriter) addRecords(size int, count int64) {
+	sw.recordSizes.add(size, count)
+}
+
+func writeRecordSizes(writer io.Writer, recordSizes []RecordSizeCount) error {
+	countBytes := make([]byte, 4)
+	binary.LittleEndian.PutUint32(countBytes, uint32(len(recordSizes)))
+	if _, err := writer.Write(countBytes); err != nil {
+		return err
+	}
+
+	entry := make([]byte, statsSizeEntry)
+	for _, recordSize := range recordSizes {
+		binary.LittleEndian.PutUint32(entry[0:4], uint32(recordSize.Size))
+		binary.LittleEndian.PutUint64(entry[4:12], uint64(recordSize.Count))
+		if _, err := writer.Write(entry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (sw *fileStatsWriter) header() ([]byte, error) {
+	dataInfo, err := sw.device.file.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("erro ao obter informações do arquivo: %w", err)
+	}
+
+	stats := sw.totals.summary(nil)
+	header := make([]byte, statsHeaderSize)
+	copy(header[0:8], statsMagic)
+	binary.LittleEndian.PutUint32(header[8:12], statsVersion)
+	binary.LittleEndian.PutUint32(header[12:16], uint32(stats.Mode))
+	binary.LittleEndian.PutUint32(header[16:20], uint32(stats.BlockSize))
+	binary.LittleEndian.PutUint64(header[20:28], uint64(dataInfo.Size()))
+	binary.LittleEndian.PutUint64(header[28:36], uint64(dataInfo.ModTime().UnixNano()))
+	binary.LittleEndian.PutUint64(header[36:44], uint64(stats.TotalBlocks))
+	binary.LittleEndian.PutUint32(header[44:48], uint32(sw.encoding))
+	binary.LittleEndian.PutUint64(header[48:56], uint64(stats.TotalBytesUsed))
+	binary.LittleEndian.PutUint64(header[56:64], uint64(stats.PartialBlocks))
+	binary.LittleEndian.PutUint64(header[64:72], uint64(stats.TotalPaddingBytes))
+	binary.LittleEndian.PutUint64(header[72:80], uint64(stats.TotalLengthPrefixBytes))
+	binary.LittleEndian.PutUint64(header[80:88], uint64(stats.TotalHeaderBytes))
+	binary.LittleEndian.PutUint64(header[88:96], uint64(stats.TotalTailBytes))
+	binary.LittleEndian.PutUint64(header[96:104], uint64(stats.TotalRecords))
+	binary.LittleEndian.PutUint64(header[104:112], uint64(stats.TotalFragments))
+	binary.LittleEndian.PutUint64(header[112:120], uint64(stats.SpanningRecords))
+	binary.LittleEndian.PutUint64(header[120:128], uint64(stats.MaxFragmentsPerRecord))
+	binary.LittleEndian.PutUint64(header[128:136], sw.schema)
+	return header, nil
+}
+
+func (sw *fileStatsWriter) commit() error {
+	if sw.keptBlocks > 0 {
+		return sw.commitAppend()
+	}
+
+	err := writeRecordSizes(sw.writer, sw.recordSizes.histogram())
+	if err == nil {
+		err = sw.writer.Flush()
+	}
+	if err != nil {
+		sw.abort()
+		return fmt.Errorf("erro ao gravar estatísticas: %w", err)
+	}
+
+	header, err := sw.header()
+	if err != nil {
+		sw.abort()
+		return err
+	}
+	if _, err := sw.file.WriteAt(header, 0); err != nil {
+		sw.abort()
+		return fmt.Errorf("erro ao gravar estatísticas: %w", err)
+	}
+
+	if err := sw.file.Sync(); err != nil {
+		sw.abort()
+		return fmt.Errorf("erro ao gravar estatísticas: %w", err)
+	}
+	if err := sw.file.Close(); err != nil {
+		os.Remove(sw.file.Name())
+		return fmt.Errorf("erro ao gravar estatísticas: %w", err)
+	}
+	if err := os.Rename(sw.file.Name(), sw.device.statsPath()); err != nil {
+		os.Remove(sw.file.Name())
+		return fmt.Errorf("erro ao gravar estatísticas: %w", err)
+	}
+	return nil
+}
+
+func (sw *fileStatsWriter) commitAppend() error {
+	defer sw.abort()
+
+	if sw.blocks < sw.keptBlocks {
+		return fmt.Errorf("erro ao gravar estatísticas: %d blocos gravados, %d mantidos", sw.blocks, sw.keptBlocks)
+	}
+	if err := sw.writer.Flush(); err != nil {
+		return fmt.Errorf("erro ao gravar estatísticas: %w", err)
+	}
+	header, err := sw.header()
+	if err != nil {
+		return err
+	}
+
+	stats, err := os.OpenFile(sw.device.statsPath(), os.O_RDWR, 0)
+	if err != nil {
+		return fmt.Errorf("erro ao gravar estatísticas: %w", err)
+	}
+	defer stats.Close()
+
+	if _, err := sw.file.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("erro ao gravar estatísticas: %w", err)
+	}
+	if _, err := stats.Seek(statsHeaderSize+sw.keptBlocks*statsEntrySize, io.SeekStart); err != nil {
+		return fmt.Errorf("erro ao gravar estatísticas: %w", err)
+	}
+	writer := bufio.NewWriter(stats)
+	if _, err := io.Copy(writer, sw.file); err != nil {
+		return fmt.Errorf("erro ao gravar estatísticas: %w", err)
+	}
+	if err := writeRecordSizes(writer, sw.recordSizes.histogram()); err != nil {
+		return fmt.Errorf("erro ao gravar estatísticas: %w", err)
+	}
+	if err := writer.Flush(); err != nil {
+		return fmt.Errorf("erro ao gravar estatísticas: %w", err)
+	}
+	end, err := stats.Seek(0, io.SeekCurrent)
+	if err == nil {
+		err = stats.Truncate(end)
+	}
+	if err == nil {
+		err = stats.Sync()
+	}
+	if err == nil {
+		_, err = stats.WriteAt(header, 0)
+	}
+	if err == nil {
+		err = stats.Sync()
+	}
+	if err != nil {
+		return fmt.Errorf("erro ao gravar estatísticas: %w", err)
+	}
+	return nil
+}
+
+func (sw *fileStatsWriter) abort() {
+	sw.file.Close()
+	os.Remove(sw.file.Name())
+}
+
+type statsHeader struct {
+	mode      Mode
+	blockSize int
+	blocks    int64
+	encoding  Encoding
+	schema    uint64
+	totals    statsTotals
+}
+
+func (fd *FileDevice) readStatsHeader(reader io.Reader) (statsHeader, bool) {
+	header := make([]byte, statsHeaderSize)
+	if _, err := io.ReadFull(reader, header); err != nil {
+		return statsHeader{}, false
+	}
+
+	dataInfo, err := fd.file.Stat()
+	if err != nil {
+		return statsHeader{}, false
+	}
+
+	parsed := statsHeader{
+		mode:      Mode(binary.LittleEndian.Uint32(header[12:16])),
+		blockSize: int(binary.LittleEndian.Uint32(header[16:20])),
+		blocks:    int64(binary.LittleEndian.Uint64(header[36:44])),
+		encoding:  Encoding(binary.LittleEndian.Uint32(header[44:48])),
+		schema:    binary.LittleEndian.Uint64(header[128:136]),
+	}
+	if string(header[0:8]) != string(statsMagic) ||
+		binary.LittleEndian.Uint32(header[8:12]) != statsVersion ||
+		int64(binary.LittleEndian.Uint64(header[20:28])) != dataInfo.Size() ||
+		int64(binary.LittleEndian.Uint64(header[28:36])) != dataInfo.ModTime().UnixNano() ||
+		parsed.blockSize <= 0 ||
+		parsed.blocks != dataInfo.Size()/int64(parsed.blockSize) {
+		return statsHeader{}, false
+	}
+
+	parsed.totals.stats = StorageStats{
+		Mode:                   parsed.mode,
+		BlockSize:              parsed.blockSize,
+		TotalBlocks:            parsed.blocks,
+		TotalBytesUsed:         int64(binary.LittleEndian.Uint64(header[48:56])),
+		TotalBytesTotal:        parsed.blocks * int64(parsed.blockSize),
+		PartialBlocks:          int64(binary.LittleEndian.Uint64(header[56:64])),
+		TotalPaddingBytes:      int64(binary.LittleEndian.Uint64(header[64:72])),
+		TotalLengthPrefixBytes: int64(binary.LittleEndian.Uint64(header[72:80])),
+		TotalHeaderBytes:       int64(binary.LittleEndian.Uint64(header[80:88])),
+		TotalTailBytes:         int64(binary.LittleEndian.Uint64(header[88:96])),
+		TotalRecords:           int64(binary.LittleEndian.Uint64(header[96:104])),
+		TotalFragments:         int64(binary.LittleEndian.Uint64(header[104:112])),
+		SpanningRecords:        int64(binary.LittleEndian.Uint64(header[112:120])),
+		MaxFragmentsPerRecord:  int(binary.LittleEndian.Uint64(header[120:128])),
+	}
+	return parsed, true
+}
+
+func (fd *FileDevice) currentStatsHeader() (statsHeader, bool) {
+	if fd.path == "" {
+		return statsHeader{}, false
+	}
+
+	file, err := os.Open(fd.statsPath())
+	if err != nil {
+		return statsHeader{}, false
+	}
+	defer file.Close()
+
+	return fd.readStatsHeader(file)
+}
+
+func (fd *FileDevice) recordedFormat() (Encoding, uint64, bool) {
+	header, ok := fd.currentStatsHeader()
+	return header.encoding, header.schema, ok
+}
+
+func (fd *FileDevice) loadStats(mode Mode, withBlocks bool) (StorageStats, bool) {
+	if fd.path == "" {
+		return StorageStats{}, false
+	}
+
+	file, err := os.Open(fd.statsPath())
+	if err != nil {
+		return StorageStats{}, false
+	}
+	defer file.Close()
+
+	header, ok := fd.readStatsHeader(file)
+	if !ok || header.mode != mode || header.blockSize != fd.blockSize {
+		return StorageStats{}, false
+	}
+
+	var blockStatsList []BlockStats
+	if withBlocks {
+		blockStatsList, ok = fd.readBlockStats(file, header.blocks)
+	} else {
+		_, err := file.Seek(statsHeaderSize+header.blocks*statsEntrySize, io.SeekStart)
+		ok = err == nil
+	}
+	if !ok {
+		return StorageStats{}, false
+	}
+
+	recordSizes, ok := readRecordSizes(bufio.NewReader(file))
+	if !ok {
+		return StorageStats{}, false
+	}
+	stats := header.totals.summary(recordSizes)
+	stats.BlockStatsList = blockStatsList
+	return stats, true
+}
+
+func (fd *FileDevice) readBlockStats(file *os.File, totalBlocks int64) ([]BlockStats, bool) {
+	reader := bufio.NewReader(io.NewSectionReader(file, statsHeaderSize, totalBlocks*statsEntrySize))
+	blockStatsList := make([]BlockStats, 0, totalBlocks)
+	entry := make([]byte, statsEntrySize)
+	for blockNum := int64(0); blockNum < totalBlocks; blockNum++ {
+		if _, err := io.ReadFull(reader, entry); err != nil {
+			return nil, false
+		}
+		bytesUsed := int(binary.LittleEndian.Uint32(entry[0:4]))
+		recordsCount := int(binary.LittleEndian.Uint32(entry[4:8]))
+		blockStats := newBlockStats(blockNum, bytesUsed, fd.blockSize, recordsCount)
+		blockStats.PaddingBytes = int(binary.LittleEndian.Uint32(entry[8:12]))
+		blockStats.LengthPrefixBytes = int(binary.LittleEndian.Uint32(entry[12:16]))
+		blockStats.HeaderBytes = int(binary.LittleEndian.Uint32(entry[16:20]))
+		blockStats.FragmentsCount = int(binary.LittleEndian.Uint32(entry[20:24]))
+		flags := binary.LittleEndian.Uint32(entry[24:28])
+		blockStats.StartsWithContinuation = flags&startsWithContinuationFlag != 0
+		blockStats.EndsWithContinuation = flags&endsWithContinuationFlag != 0
+		blockStatsList = append(blockStatsList, blockStats)
+	}
+	_, err := file.Seek(statsHeaderSize+totalBlocks*statsEntrySize, io.SeekStart)
+	return blockStatsList, err == nil
+}
+
+func readRecordSizes(reader io.Reader) ([]RecordSizeCount, bool) {
+	countBytes := make([]byte, 4)
+	if _, err := io.ReadFull(reader, countBytes); err != nil {
+		return nil, false
+	}
+	sizesCount := int(binary.LittleEndian.Uint32(countBytes))
+	recordSizes := make([]RecordSizeCount, 0, sizesCount)
+	sizeEntry := make([]byte, statsSizeEntry)
+	for i := 0; i < sizesCount; i++ {
+		if _, err := io.ReadFull(reader, sizeEntry); err != nil {
+			return nil, false
+		}
+		recordSizes = append(recordSizes, RecordSizeCount{
+			Size:  int(binary.LittleEndian.Uint32(sizeEntry[0:4])),
+			Count: int64(binary.LittleEndian.Uint64(sizeEntry[4:12])),
+		})
+	}
+	return recordSizes, true
+}
+
+type memoryStatsWriter struct {
+	device         *MemoryDevice
+	mode           Mode
+	encoding       Encoding
+	schema         uint64
+	blockStatsList []BlockStats
+	recordSizes    recordSizeCounter
+}
+
+func (md *MemoryDevice) beginStats(mode Mode, encoding Encoding, schema uint64, keptBlocks int64) (statsWriter, error) {
+	return &memoryStatsWriter{
+		device:         md,
+		mode:           mode,
+		encoding:       encoding,
+		schema:         schema,
+		blockStatsList: make([]BlockStats, 0),
+		recordSizes:    recordSizeCounter{},
+	}, nil
+}
+
+func (sw *memoryStatsWriter) add(blockStats BlockStats) error {
+	if blockStats.BlockNumber != int64(len(sw.blockStatsList)) {
+		return errors.New("erro ao gravar estatísticas: blocos fora de ordem")
+	}
+	sw.blockStatsList = append(sw.blockStatsList, blockStats)
+	return nil
+}
+
+func (sw *memoryStatsWriter) addRecords(size int, count int64) {
+	sw.recordSizes.add(size, count)
+}
+
+func (sw *memoryStatsWriter) commit() error {
+	sw.device.mu.Lock()
+	defer sw.device.mu.Unlock()
+
+	sw.device.stats = summarizeBlockStats(sw.mode, sw.device.blockSize, sw.blockStatsList, sw.recordSizes.histogram())
+	sw.device.statsMode = sw.mode
+	sw.device.statsEncoding = sw.encoding
+	sw.device.statsSchema = sw.schema
+	sw.device.statsVersion = sw.device.version
+	sw.device.hasStats = true
+	return nil
+}
+
+func (sw *memoryStatsWriter) abort() {}
+
+func (md *MemoryDevice) loadStats(mode Mode, withBlocks bool) (StorageStats, bool) {
+	md.mu.RLock()
+	defer md.mu.RUnlock()
+
+	if !md.hasStats || md.statsMode != mode || md.statsVersion != md.version {
+		return StorageStats{}, false
+	}
+	stats := md.stats
+	stats.BlockStatsList = nil
+	if withBlocks {
+		stats.BlockStatsList = slices.Clone(md.stats.BlockStatsList)
+	}
+	stats.RecordSizes = slices.Clone(md.stats.RecordSizes)
+	return stats, true
+}
+
+func (md *MemoryDevice) recordedFormat() (Encoding, uint64, bool) {
+	md.mu.RLock()
+	defer md.mu.RUnlock()
+
+	return md.statsEncoding, md.statsSchema, md.hasStats && md.statsVersion == md.version
+}
+
+type discardStats struct{}
+
+func (discardStats) add(blockStats BlockStats) error {
+	return nil
+}
+
+func (discardStats) addRecords(size int, count int64) {}
+
+func (discardStats) commit() error {
+	return nil
+}
+
+func (discardStats) abort() {}
+
+func recordBlockStats(device BlockDevice, blockStats BlockStats) error {
+	if tracked, ok := device.(contextDevice); ok && tracked.onBlock != nil {
+		return tracked.onBlock(blockStats)
+	}
+	return nil
+}
+
+func recordRecordSize(device BlockDevice, size int) {
+	if tracked, ok := device.(contextDevice); ok && tracked.onRecord != nil {
+		tracked.onRecord(size, 1)
+	}
+}
@@ -0,0 +1,326 @@
+package storage
+
+import (
+	"aeds2-tp1/entity"
+	"context"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func assertPersistedStatsMatchScan(t *testing.T, handle *Handle, step string) {
+	t.Helper()
+	persisted, found := handle.device.(statsStore).loadStats(handle.Mode(), true)
+	if !found {
+		t.Fatalf("%s: arquivo de estatísticas ausente ou inválido", step)
+	}
+	scanned, err := handle.scanStats(context.Background())
+	if err != nil {
+		t.Fatalf("%s: varredura: %v", step, err)
+	}
+	if !reflect.DeepEqual(persisted, scanned) {
+		t.Fatalf("%s: estatísticas persistidas diferem da varredura:\n persistidas %+v\n varredura   %+v", step, summary(persisted), summary(scanned))
+	}
+	if stats, err := handle.GetBlockStats(context.Background()); err != nil || !reflect.DeepEqual(stats, persisted) {
+		t.Fatalf("%s: GetBlockStats não devolveu as estatísticas persistidas (erro %v)", step, err)
+	}
+	persisted.BlockStatsList = nil
+	if stats, err := handle.GetStats(context.Background()); err != nil || !reflect.DeepEqual(stats, persisted) {
+		t.Fatalf("%s: GetStats não devolveu os totais persistidos (erro %v)", step, err)
+	}
+}
+
+func TestPersistedStatsMatchRescan(t *testing.T) {
+	ctx := context.Background()
+	for _, mode := range allModes {
+		t.Run(mode.String(), func(t *testing.T) {
+			students := testStudents(t, 51, 300)
+			handle := openTestFile(t, tempPath(t, "alunos.dat"), Options{Mode: mode, BlockSize: 1024})
+
+			if err := handle.WriteStudents(ctx, students[:200]); err != nil {
+				t.Fatalf("WriteStudents: %v", err)
+			}
+			assertPersistedStatsMatchScan(t, handle, "gravação")
+
+			if err := handle.AddStudents(ctx, students[200:250]); err != nil {
+				t.Fatalf("AddStudents: %v", err)
+			}
+			assertPersistedStatsMatchScan(t, handle, "inclusão")
+
+			updated := make([]entity.Student, 0, 60)
+			for _, student := range students[:10] {
+				student.Nome = "Nome"
+				updated = append(updated, student)
+			}
+			updated = append(updated, students[250:]...)
+			if err := handle.UpsertStudents(ctx, updated); err != nil {
+				t.Fatalf("UpsertStudents: %v", err)
+			}
+			assertPersistedStatsMatchScan(t, handle, "atualização")
+		})
+	}
+}
+
+func TestGetStatsSkipsBlockEntries(t *testing.T) {
+	ctx := context.Background()
+	path := tempPath(t, "alunos.dat")
+	handle := openTestFile(t, path, Options{Mode: VariableMode, BlockSize: 512})
+	if err := handle.WriteStudents(ctx, testStudents(t, 52, 200)); err != nil {
+		t.Fatalf("WriteStudents: %v", err)
+	}
+	want, err := handle.GetStats(ctx)
+	if err != nil {
+		t.Fatalf("GetStats: %v", err)
+	}
+
+	overwriteStatsEntry(t, path, 0, 0xFF)
+	got, err := handle.GetStats(ctx)
+	if err != nil || !reflect.DeepEqual(got, want) {
+		t.Fatalf("GetStats leu as entradas por bloco: %+v (erro %v), esperado %+v", summary(got), err, summary(want))
+	}
+}
+
+func TestAddKeepsLeadingStatsEntries(t *testing.T) {
+	ctx := context.Background()
+	for _, mode := range allModes {
+		t.Run(mode.String(), func(t *testing.T) {
+			path := tempPath(t, "alunos.dat")
+			handle := openTestFile(t, path, Options{Mode: mode, BlockSize: 512})
+			students := testStudents(t, 53, 150)
+			if err := handle.WriteStudents(ctx, students[:100]); err != nil {
+				t.Fatalf("WriteStudents: %v", err)
+			}
+
+			overwriteStatsEntry(t, path, 0, 0xEE)
+			if err := handle.AddStudents(ctx, students[100:]); err != nil {
+				t.Fatalf("AddStudents: %v", err)
+			}
+			entry := readFile(t, path+statsFileSuffix)[statsHeaderSize : statsHeaderSize+statsEntrySize]
+			if entry[0] != 0xEE {
+				t.Fatal("inclusão regravou as estatísticas dos blocos que não mudaram")
+			}
+
+			if _, err := handle.RecomputeStats(ctx); err != nil {
+				t.Fatalf("RecomputeStats: %v", err)
+			}
+			if err := handle.AddStudents(ctx, testStudents(t, 54, 180)[150:]); err != nil {
+				t.Fatalf("AddStudents: %v", err)
+			}
+			assertPersistedStatsMatchScan(t, handle, "inclusão incremental")
+		})
+	}
+}
+
+func overwriteStatsEntry(t *testing.T, path string, blockNum int64, value byte) {
+	t.Helper()
+	file, err := os.OpenFile(path+statsFileSuffix, os.O_RDWR, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	if _, err := file.WriteAt([]byte{value}, statsHeaderSize+blockNum*statsEntrySize); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func knownStudents(texts ...[4]string) []entity.Student {
+	cpfs := []string{"52998224725", "11144477735", "12345678909"}
+	students := make([]entity.Student, len(texts))
+	for i, text := range texts {
+		students[i] = entity.Student{
+			Matricula:   int64(100000001 + i),
+			Nome:        text[0],
+			CPF:         cpfs[i%len(cpfs)],
+			Curso:       text[1],
+			FiliacaoMae: text[2],
+			FiliacaoPai: text[3],
+			AnoIngresso: 2020,
+			CA:          7.5,
+		}
+	}
+	return students
+}
+
+func occupancy(bytesUsed, bytesTotal int) float64 {
+	return float64(bytesUsed) / float64(bytesTotal) * 100
+}
+
+func writtenBlockStats(t *testing.T, mode Mode, blockSize int, students []entity.Student) StorageStats {
+	t.Helper()
+	ctx := context.Background()
+	handle := openTestFile(t, tempPath(t, "alunos.dat"), Options{Mode: mode, BlockSize: blockSize})
+	if err := handle.WriteStudents(ctx, students); err != nil {
+		t.Fatalf("WriteStudents: %v", err)
+	}
+	assertPersistedStatsMatchScan(t, handle, "gravação")
+	stats, err := handle.GetBlockStats(ctx)
+	if err != nil {
+		t.Fatalf("GetBlockStats: %v", err)
+	}
+	return stats
+}
+
+func TestWasteBreakdownOnKnownDataset(t *testing.T) {
+	students := knownStudents(
+		[4]string{"Ana", "Direito", "Maria", "Jose"},
+		[4]string{"Bruno Costa", "Medicina", "Clara", "Paulo"},
+		[4]string{"Carla", "Fisica", "Rita", "Joao"},
+	)
+	cases := []struct {
+		mode      Mode
+		blockSize int
+		partial   int64
+		blocks    []BlockStats
+	}{
+		{FixedMode, 400, 2, []BlockStats{
+			{BlockNumber: 0, BytesUsed: 334, BytesTotal: 400, OccupancyRate: occupancy(334, 400), RecordsCount: 2, PaddingBytes: 121 + 111, TailBytes: 66},
+			{BlockNumber: 1, BytesUsed: 167, BytesTotal: 400, OccupancyRate: occupancy(167, 400), RecordsCount: 1, PaddingBytes: 121, TailBytes: 233},
+		}},
+		{VariableMode, 190, 2, []BlockStats{
+			{BlockNumber: 0, BytesUsed: 62 + 72, BytesTotal: 190, OccupancyRate: occupancy(134, 190), RecordsCount: 2, LengthPrefixBytes: 32, TailBytes: 56},
+			{BlockNumber: 1, BytesUsed: 62, BytesTotal: 190, OccupancyRate: occupancy(62, 190), RecordsCount: 1, LengthPrefixBytes: 16, TailBytes: 128},
+		}},
+		{FragmentedMode, 200, 1, []BlockStats{
+			{BlockNumber: 0, BytesUsed: 200, BytesTotal: 200, OccupancyRate: 100, RecordsCount: 2, LengthPrefixBytes: 32, HeaderBytes: 15, FragmentsCount: 3, EndsWithContinuation: true},
+			{BlockNumber: 1, BytesUsed: 5 + 11, BytesTotal: 200, OccupancyRate: occupancy(16, 200), RecordsCount: 1, LengthPrefixBytes: 16, HeaderBytes: 5, TailBytes: 184, FragmentsCount: 1, StartsWithContinuation: true},
+		}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.mode.String(), func(t *testing.T) {
+			stats := writtenBlockStats(t, tc.mode, tc.blockSize, students)
+			if !reflect.DeepEqual(stats.BlockStatsList, tc.blocks) {
+				t.Fatalf("estatísticas por bloco:\n lidas    %+v\n esperado %+v", stats.BlockStatsList, tc.blocks)
+			}
+
+			var used, padding, prefixes, headers, tail int64
+			for _, blockStats := range tc.blocks {
+				used += int64(blockStats.BytesUsed)
+				padding += int64(blockStats.PaddingBytes)
+				prefixes += int64(blockStats.LengthPrefixBytes)
+				headers += int64(blockStats.HeaderBytes)
+				tail += int64(blockStats.TailBytes)
+			}
+			total := int64(len(tc.blocks) * tc.blockSize)
+			if stats.TotalBlocks != int64(len(tc.blocks)) || stats.TotalBytesUsed != used || stats.TotalBytesTotal != total ||
+				stats.TotalPaddingBytes != padding || stats.TotalLengthPrefixBytes != prefixes || stats.TotalHeaderBytes != headers ||
+				stats.TotalTailBytes != tail || stats.TotalRecords != 3 || stats.PartialBlocks != tc.partial || stats.EfficiencyRate != float64(used)/float64(total)*100 {
+				t.Fatalf("totais: %+v", summary(stats))
+			}
+			if used+tail != total {
+				t.Fatalf("bytes usados (%d) e sobras (%d) não somam o arquivo (%d)", used, tail, total)
+			}
+		})
+	}
+}
+
+func TestRecordSizeHistogramOnKnownDataset(t *testing.T) {
+	students := knownStudents(
+		[4]string{"Ana", "Direito", "Maria", "Jose"},
+		[4]string{"Bruno Costa", "Medicina", "Clara", "Paulo"},
+		[4]string{"Carla", "Fisica", "Rita", "Joao"},
+	)
+	want := map[Mode][]RecordSizeCount{
+		FixedMode:      {{Size: 167, Count: 3}},
+		VariableMode:   {{Size: 62, Count: 2}, {Size: 72, Count: 1}},
+		FragmentedMode: {{Size: 62, Count: 2}, {Size: 72, Count: 1}},
+	}
+	for _, mode := range allModes {
+		if got := writtenBlockStats(t, mode, 4096, students).RecordSizes; !reflect.DeepEqual(got, want[mode]) {
+			t.Errorf("histograma no modo %s: %v, esperado %v", mode, got, want[mode])
+		}
+	}
+}
+
+func TestSpanningMetricsOnKnownDataset(t *testing.T) {
+	cases := []struct {
+		name          string
+		blockSize     int
+		students      []entity.Student
+		continuations [][2]bool
+		fragments     int64
+		spanning      int64
+		maxFragments  int
+		avgFragments  float64
+		extraReads    int64
+	}{
+		{
+			name:      "ultimo-registro-em-dois-blocos",
+			blockSize: 200,
+			students: knownStudents(
+				[4]string{"Ana", "Direito", "Maria", "Jose"},
+				[4]string{"Bruno Costa", "Medicina", "Clara", "Paulo"},
+				[4]string{"Carla", "Fisica", "Rita", "Joao"},
+			),
+			continuations: [][2]bool{{false, true}, {true, false}},
+			fragments:     4,
+			spanning:      1,
+			maxFragments:  2,
+			avgFragments:  4.0 / 3,
+			extraReads:    1,
+		},
+		{
+			name:      "registro-em-tres-blocos",
+			blockSize: 183,
+			students: knownStudents(
+				[4]string{strings.Repeat("A", 50), strings.Repeat("C", 30), strings.Repeat("M", 30), strings.Repeat("P", 19)},
+				[4]string{strings.Repeat("B", 50), strings.Repeat("D", 30), strings.Repeat("N", 30), strings.Repeat("Q", 30)},
+			),
+			continuations: [][2]bool{{false, true}, {true, true}, {true, false}},
+			fragments:     4,
+			spanning:      1,
+			maxFragments:  3,
+			avgFragments:  2,
+			extraReads:    2,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			stats := writtenBlockStats(t, FragmentedMode, tc.blockSize, tc.students)
+			if len(stats.BlockStatsList) != len(tc.continuations) {
+				t.Fatalf("%d blocos, esperado %d", len(stats.BlockStatsList), len(tc.continuations))
+			}
+			for i, blockStats := range stats.BlockStatsList {
+				if got := [2]bool{blockStats.StartsWithContinuation, blockStats.EndsWithContinuation}; got != tc.continuations[i] {
+					t.Fatalf("bloco %d: começa/termina com continuação %v, esperado %v", i+1, got, tc.continuations[i])
+				}
+			}
+			if stats.TotalRecords != int64(len(tc.students)) || stats.TotalFragments != tc.fragments || stats.SpanningRecords != tc.spanning ||
+				stats.MaxFragmentsPerRecord != tc.maxFragments || stats.AvgFragmentsPerRecord != tc.avgFragments || stats.ExtraBlockReads != tc.extraReads {
+				t.Fatalf("espalhamento: %+v", summary(stats))
+			}
+
+			for _, workers := range []int{1, 2, 3} {
+				handle, err := OpenDevice(NewMemoryDevice(tc.blockSize), Options{Mode: FragmentedMode, ScanWorkers: workers})
+				if err != nil {
+					t.Fatal(err)
+				}
+				if err := handle.WriteStudents(context.Background(), tc.students); err != nil {
+					t.Fatalf("WriteStudents: %v", err)
+				}
+				scanned, err := handle.RecomputeStats(context.Background())
+				handle.Close()
+				if err != nil {
+					t.Fatalf("RecomputeStats: %v", err)
+				}
+				if !reflect.DeepEqual(scanned, stats) {
+					t.Fatalf("varredura com %d workers:\n %+v\nesperado\n %+v", workers, summary(scanned), summary(stats))
+				}
+			}
+		})
+	}
+}
+
+func TestSpanningMetricsAreZeroWithoutFragments(t *testing.T) {
+	students := knownStudents(
+		[4]string{"Ana", "Direito", "Maria", "Jose"},
+		[4]string{"Bruno Costa", "Medicina", "Clara", "Paulo"},
+		[4]string{"Carla", "Fisica", "Rita", "Joao"},
+	)
+	for _, mode := range []Mode{FixedMode, VariableMode} {
+		stats := writtenBlockStats(t, mode, 190, students)
+		if stats.TotalFragments != 0 || stats.SpanningRecords != 0 || stats.MaxFragmentsPerRecord != 0 || stats.AvgFragmentsPerRecord != 0 || stats.ExtraBlockReads != 0 {
+			t.Errorf("modo %s com métricas de espalhamento: %+v", mode, summary(stats))
+		}
+	}
+}
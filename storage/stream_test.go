@@ -0,0 +1,122 @@
+package storage
+
+import (
+	"aeds2-tp1/domain"
+	"aeds2-tp1/entity"
+	"context"
+	"iter"
+	"os"
+	"testing"
+)
+
+func countingStream(students iter.Seq2[entity.Student, error], yielded *int64) iter.Seq2[entity.Student, error] {
+	return func(yield func(entity.Student, error) bool) {
+		for student, err := range students {
+			if err == nil {
+				*yielded++
+			}
+			if !yield(student, err) {
+				return
+			}
+		}
+	}
+}
+
+func TestStreamingWriteSpansManyBatchesWithInt64Totals(t *testing.T) {
+	previousInterval := progressReportInterval
+	progressReportInterval = 0
+	t.Cleanup(func() { progressReportInterval = previousInterval })
+
+	const count = 20000
+	const batch = 4
+	const recordsPerBlock = 512 / 167
+	ctx := context.Background()
+	path := tempPath(t, "alunos.dat")
+	handle := openTestFile(t, path, Options{Mode: FixedMode, BlockSize: 512, WriteBatchBlocks: batch, ScanWorkers: 4})
+
+	var yielded, maxPending int64
+	source := countingStream(domain.NewStudentGeneratorWithSeed(33).Stream(count), &yielded)
+	progressCtx := WithProgress(ctx, func(progress Progress) {
+		maxPending = max(maxPending, yielded-recordsPerBlock*progress.BlocksDone)
+	})
+	if err := handle.WriteStudentsFrom(progressCtx, source, count); err != nil {
+		t.Fatalf("WriteStudentsFrom: %v", err)
+	}
+	if yielded != count {
+		t.Fatalf("fonte produziu %d alunos, esperado %d", yielded, count)
+	}
+	if limit := int64(recordsPerBlock * (batch + 1)); maxPending > limit {
+		t.Fatalf("até %d registros pendentes antes da gravação, esperado no máximo %d", maxPending, limit)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantBlocks := int64((count + recordsPerBlock - 1) / recordsPerBlock)
+	if info.Size() != wantBlocks*512 {
+		t.Fatalf("arquivo com %d bytes, esperado %d", info.Size(), wantBlocks*512)
+	}
+	if wantBlocks <= batch {
+		t.Fatalf("%d blocos cabem em um único lote de %d", wantBlocks, batch)
+	}
+
+	stats, err := handle.GetStats(ctx)
+	if err != nil {
+		t.Fatalf("GetStats: %v", err)
+	}
+	if stats.TotalRecords != count || stats.TotalBlocks != wantBlocks || stats.TotalBytesTotal != info.Size() {
+		t.Fatalf("estatísticas com %d registros, %d blocos e %d bytes, esperado %d, %d e %d", stats.TotalRecords, stats.TotalBlocks, stats.TotalBytesTotal, count, wantBlocks, info.Size())
+	}
+	recomputed, err := handle.RecomputeStats(ctx)
+	if err != nil {
+		t.Fatalf("RecomputeStats: %v", err)
+	}
+	if recomputed.TotalRecords != stats.TotalRecords || recomputed.TotalBlocks != stats.TotalBlocks || recomputed.TotalBytesUsed != stats.TotalBytesUsed {
+		t.Fatalf("recálculo %+v difere das estatísticas persistidas %+v", recomputed, stats)
+	}
+
+	var read int64
+	if _, err := handle.FindStudents(ctx, func(*entity.Student) bool {
+		read++
+		return false
+	}); err != nil {
+		t.Fatalf("FindStudents: %v", err)
+	}
+	if read != count {
+		t.Fatalf("leitura em fluxo visitou %d alunos, esperado %d", read, count)
+	}
+}
+
+func TestFileDeviceAddressesBlocksBeyond4GiB(t *testing.T) {
+	const blockSize = 512
+	device, err := OpenFileDevice(tempPath(t, "esparso.dat"), blockSize, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer device.Close()
+
+	blockNum := int64(1<<32)/blockSize + 5
+	block := make([]byte, blockSize)
+	for i := range block {
+		block[i] = byte(i)
+	}
+	if err := device.WriteBlock(blockNum, block); err != nil {
+		t.Fatalf("WriteBlock(%d): %v", blockNum, err)
+	}
+
+	read := make([]byte, blockSize)
+	if err := device.ReadBlock(blockNum, read); err != nil {
+		t.Fatalf("ReadBlock(%d): %v", blockNum, err)
+	}
+	if string(read) != string(block) {
+		t.Fatalf("bloco %d lido difere do gravado", blockNum)
+	}
+	blocks, err := device.BlockCount()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if blocks != blockNum+1 {
+		t.Fatalf("BlockCount = %d, esperado %d", blocks, blockNum+1)
+	}
+}
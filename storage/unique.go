@@ -0,0 +1,102 @@
+package storage
+
+import (
+	"aeds2-tp1/entity"
+	"errors"
+	"fmt"
+	"iter"
+)
+
+const (
+	keySetPageBits       = 1 << 16
+	keySetDensePages     = 4
+	keySetMinKeysPerPage = 256
+)
+
+var (
+	ErrDuplicateKey       = errors.New("chave duplicada")
+	ErrDuplicateMatricula = ErrDuplicateKey
+)
+
+type DuplicateKeyError struct {
+	Field string
+	Key   int64
+}
+
+func (e *DuplicateKeyError) Error() string {
+	return fmt.Sprintf("%v: %s %d", ErrDuplicateKey, e.Field, e.Key)
+}
+
+func (e *DuplicateKeyError) Is(target error) bool {
+	return target == ErrDuplicateKey
+}
+
+type keySet struct {
+	pages  map[uint64][]uint64
+	sparse map[int64]struct{}
+	count  int
+}
+
+func newKeySet() *keySet {
+	return &keySet{pages: make(map[uint64][]uint64)}
+}
+
+func (s *keySet) add(key int64) bool {
+	if s.sparse != nil {
+		if _, ok := s.sparse[key]; ok {
+			return false
+		}
+		s.sparse[key] = struct{}{}
+		return true
+	}
+
+	index := uint64(key) / keySetPageBits
+	page := s.pages[index]
+	if page == nil {
+		if len(s.pages) >= keySetDensePages && s.count < len(s.pages)*keySetMinKeysPerPage {
+			s.toSparse()
+			return s.add(key)
+		}
+		page = make([]uint64, keySetPageBits/64)
+		s.pages[index] = page
+	}
+
+	bit := uint64(key) % keySetPageBits
+	mask := uint64(1) << (bit % 64)
+	if page[bit/64]&mask != 0 {
+		return false
+	}
+	page[bit/64] |= mask
+	s.count++
+	return true
+}
+
+func (s *keySet) toSparse() {
+	s.sparse = make(map[int64]struct{}, s.count)
+	for index, page := range s.pages {
+		for word, bits := range page {
+			for bit := range 64 {
+				if bits&(uint64(1)<<bit) != 0 {
+					s.sparse[int64(index*keySetPageBits+uint64(word*64+bit))] = struct{}{}
+				}
+			}
+		}
+	}
+	s.pages = nil
+}
+
+func uniqueRecords(records iter.Seq[entity.Record], schema *entity.Schema, err *error) iter.Seq[entity.Record] {
+	return func(yield func(entity.Record) bool) {
+		seen := newKeySet()
+		for record := range records {
+			key := schema.Key(record)
+			if !seen.add(key) {
+				*err = &DuplicateKeyError{Field: schema.KeyField().Name, Key: key}
+				return
+			}
+			if !yield(record) {
+				return
+			}
+		}
+	}
+}
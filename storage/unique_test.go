@@ -0,0 +1,69 @@
+package storage
+
+import (
+	"aeds2-tp1/entity"
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestKeySetDense(t *testing.T) {
+	set := newKeySet()
+	for key := int64(100000001); key <= 100200000; key++ {
+		if !set.add(key) {
+			t.Fatalf("chave %d considerada repetida", key)
+		}
+	}
+	if set.sparse != nil {
+		t.Fatal("chaves consecutivas passaram para o mapa esparso")
+	}
+	if set.add(100150000) {
+		t.Fatal("chave repetida não detectada")
+	}
+}
+
+func TestKeySetSparse(t *testing.T) {
+	set := newKeySet()
+	var keys []int64
+	for i := range int64(1000) {
+		keys = append(keys, i*1_000_003-500_000_000)
+	}
+	for _, key := range keys {
+		if !set.add(key) {
+			t.Fatalf("chave %d considerada repetida", key)
+		}
+	}
+	if set.sparse == nil || len(set.pages) != 0 {
+		t.Fatalf("chaves esparsas mantidas em %d páginas", len(set.pages))
+	}
+	for _, key := range keys {
+		if set.add(key) {
+			t.Fatalf("chave repetida %d não detectada", key)
+		}
+	}
+}
+
+func TestStudentAPIsAcceptLoadedSchema(t *testing.T) {
+	ctx := context.Background()
+	schema, err := entity.ResolveSchema("../entity/schemas/alunos.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	students := testStudents(t, 51, 20)
+	for _, mode := range allModes {
+		handle := openTestFile(t, tempPath(t, "alunos.dat"), Options{Mode: mode, BlockSize: 1024, Schema: schema})
+		if err := handle.WriteStudents(ctx, students); err != nil {
+			t.Fatalf("%s: WriteStudents: %v", mode, err)
+		}
+		assertSameStudents(t, readAllStudents(t, handle), students)
+	}
+
+	disciplinas, err := entity.BuiltinSchema("disciplinas")
+	if err != nil {
+		t.Fatal(err)
+	}
+	handle := openTestFile(t, tempPath(t, "disciplinas.dat"), Options{Mode: FixedMode, BlockSize: 4096, Schema: disciplinas})
+	if err := handle.WriteStudents(ctx, students); !errors.Is(err, ErrNotStudentFile) {
+		t.Fatalf("WriteStudents com esquema de disciplinas: %v", err)
+	}
+}
@@ -2,22 +2,30 @@ package storage
 
 import (
 	"aeds2-tp1/entity"
-	"encoding/binary"
+	"context"
 	"fmt"
-	"os"
+	"iter"
+	"sync"
+	"time"
 )
 
 type VariableStorage struct {
-	blockSize int
-	stats      StorageStats
+	mu          sync.RWMutex
+	blockSize   int
+	lockTimeout time.Duration
+	scanWorkers int
+	codec       *recordCodec
 }
 
 func NewVariableStorage(blockSize int) (*VariableStorage, error) {
+	return newVariableStorage(blockSize, newRecordCodec(entity.StudentSchema(), UTF8Encoding))
+}
+
+func newVariableStorage(blockSize int, codec *recordCodec) (*VariableStorage, error) {
 	vs := &VariableStorage{
-		blockSize: blockSize,
-		stats: StorageStats{
-			BlockStatsList: make([]BlockStats, 0),
-		},
+		blockSize:   blockSize,
+		scanWorkers: defaultScanWorkers(),
+		codec:       codec,
 	}
 	
 	if err := vs.ValidateBlockSize(blockSize); err != nil {
@@ -27,15 +35,29 @@ func NewVariableStorage(blockSize int) (*VariableStorage, error) {
 	return vs, nil
 }
 
+func (vs *VariableStorage) SetLockTimeout(timeout time.Duration) {
+	vs.mu.Lock()
+	defer vs.mu.Unlock()
+	vs.lockTimeout = timeout
+}
+
+func (vs *VariableStorage) SetEncoding(encoding Encoding) {
+	vs.mu.Lock()
+	defer vs.mu.Unlock()
+	vs.codec = vs.codec.withEncoding(encoding)
+}
+
+func (vs *VariableStorage) SetScanWorkers(workers int) {
+	vs.mu.Lock()
+	defer vs.mu.Unlock()
+	if workers < 1 {
+		workers = 1
+	}
+	vs.scanWorkers = workers
+}
+
 func (vs *VariableStorage) ValidateBlockSize(blockSize int) error {
-	minSize := 4 +
-		4 + entity.MaxNomeLength +
-		entity.CPFLength +
-		4 + entity.MaxCursoLength +
-		4 + entity.MaxFiliacaoLength +
-		4 + entity.MaxFiliacaoLength +
-		4 +
-		8
+	minSize := vs.codec.maxVariableSize
 	
 	if blockSize < minSize {
 		return fmt.Errorf("tamanho do bloco (%d bytes) é menor que o tamanho mínimo necessário para um registro variável (%d bytes)", blockSize, minSize)
@@ -44,106 +66,70 @@ func (vs *VariableStorage) ValidateBlockSize(blockSize int) error {
 	return nil
 }
 
-func (vs *VariableStorage) WriteStudents(filename string, students []entity.Student) error {
-	file, err := os.Create(filename)
+func (vs *VariableStorage) WriteStudents(ctx context.Context, filename string, students []entity.Student) error {
+	vs.mu.Lock()
+	defer vs.mu.Unlock()
+
+	handle, err := openFileHandle(filename, vs, vs.lockTimeout, false)
 	if err != nil {
-		return fmt.Errorf("erro ao criar arquivo: %w", err)
+		return err
 	}
-	defer file.Close()
+	defer handle.Close()
 
-	currentBlock := make([]byte, 0, vs.blockSize)
-	currentBlockNumber := 0
+	return handle.WriteStudents(ctx, students)
+}
+
+func (vs *VariableStorage) writeRecordsToDevice(device BlockDevice, records iter.Seq[entity.Record]) error {
+	currentBlock := getBlockBuffer(vs.blockSize)[:0]
+	defer putBlockBuffer(currentBlock)
+	currentBlockNumber := int64(0)
 	blockStats := BlockStats{
 		BlockNumber: currentBlockNumber,
 		BytesUsed:   0,
 		BytesTotal:  vs.blockSize,
 	}
 
-	for i, student := range students {
-		recordData := vs.serializeStudent(student)
+	recordNumber := int64(0)
+	for record := range records {
+		recordNumber++
+		recordData, err := vs.codec.encode(record, true)
+		if err != nil {
+			return err
+		}
 		
 		if len(recordData) > vs.blockSize {
-			return fmt.Errorf("registro do aluno %d (matrícula: %d) excede o tamanho do bloco (%d bytes > %d bytes). Aumente o tamanho do bloco", i+1, student.Matricula, len(recordData), vs.blockSize)
+			return fmt.Errorf("registro %d (%s) excede o tamanho do bloco (%d bytes > %d bytes). Aumente o tamanho do bloco", recordNumber, vs.codec.describe(record), len(recordData), vs.blockSize)
 		}
 		
-		vs.writeContiguousRecord(&currentBlock, &currentBlockNumber, &blockStats, recordData, file, i == len(students)-1)
+		if err := vs.writeContiguousRecord(&currentBlock, &currentBlockNumber, &blockStats, recordData, device); err != nil {
+			return err
+		}
+		reportRecords(device, 1)
+		recordRecordSize(device, len(recordData))
 	}
 
 	if len(currentBlock) > 0 {
 		blockStats.OccupancyRate = float64(blockStats.BytesUsed) / float64(blockStats.BytesTotal) * 100
-		vs.stats.BlockStatsList = append(vs.stats.BlockStatsList, blockStats)
-		vs.writeBlock(file, currentBlock)
-		vs.stats.TotalBlocks++
-		vs.stats.TotalBytesUsed += blockStats.BytesUsed
-		vs.stats.TotalBytesTotal += blockStats.BytesTotal
+		if err := vs.writeBlock(device, blockStats, currentBlock); err != nil {
+			return err
+		}
 	}
 
-	vs.calculateFinalStats()
 	return nil
 }
 
-func (vs *VariableStorage) serializeStudent(student entity.Student) []byte {
-	data := make([]byte, 0)
-	
-	matriculaBytes := make([]byte, 4)
-	binary.LittleEndian.PutUint32(matriculaBytes, uint32(student.Matricula))
-	data = append(data, matriculaBytes...)
-	
-	nomeBytes := []byte(student.Nome)
-	nomeLen := make([]byte, 4)
-	binary.LittleEndian.PutUint32(nomeLen, uint32(len(nomeBytes)))
-	data = append(data, nomeLen...)
-	data = append(data, nomeBytes...)
-	
-	cpfBytes := []byte(student.CPF)
-	data = append(data, cpfBytes...)
-	
-	cursoBytes := []byte(student.Curso)
-	cursoLen := make([]byte, 4)
-	binary.LittleEndian.PutUint32(cursoLen, uint32(len(cursoBytes)))
-	data = append(data, cursoLen...)
-	data = append(data, cursoBytes...)
-	
-	maeBytes := []byte(student.FiliacaoMae)
-	maeLen := make([]byte, 4)
-	binary.LittleEndian.PutUint32(maeLen, uint32(len(maeBytes)))
-	data = append(data, maeLen...)
-	data = append(data, maeBytes...)
-	
-	paiBytes := []byte(student.FiliacaoPai)
-	paiLen := make([]byte, 4)
-	binary.LittleEndian.PutUint32(paiLen, uint32(len(paiBytes)))
-	data = append(data, paiLen...)
-	data = append(data, paiBytes...)
-	
-	anoBytes := make([]byte, 4)
-	binary.LittleEndian.PutUint32(anoBytes, uint32(student.AnoIngresso))
-	data = append(data, anoBytes...)
-	
-	caBytes := make([]byte, 8)
-	binary.LittleEndian.PutUint64(caBytes, uint64(student.CA*100))
-	data = append(data, caBytes...)
-	
-	return data
-}
-
-func (vs *VariableStorage) writeContiguousRecord(currentBlock *[]byte, currentBlockNumber *int, blockStats *BlockStats, recordData []byte, file *os.File, isLast bool) {
+func (vs *VariableStorage) writeContiguousRecord(currentBlock *[]byte, currentBlockNumber *int64, blockStats *BlockStats, recordData []byte, device BlockDevice) error {
 	recordSize := len(recordData)
 	
 	if len(*currentBlock)+recordSize > vs.blockSize {
 		if len(*currentBlock) > 0 {
 			blockStats.OccupancyRate = float64(blockStats.BytesUsed) / float64(blockStats.BytesTotal) * 100
-			if blockStats.OccupancyRate < 100 {
-				vs.stats.PartialBlocks++
+			if err := vs.writeBlock(device, *blockStats, *currentBlock); err != nil {
+				return err
 			}
-			vs.stats.BlockStatsList = append(vs.stats.BlockStatsList, *blockStats)
-			vs.writeBlock(file, *currentBlock)
-			vs.stats.TotalBlocks++
-			vs.stats.TotalBytesUsed += blockStats.BytesUsed
-			vs.stats.TotalBytesTotal += blockStats.BytesTotal
 		}
 		
-		*currentBlock = make([]byte, 0, vs.blockSize)
+		*currentBlock = (*currentBlock)[:0]
 		*currentBlockNumber++
 		*blockStats = BlockStats{
 			BlockNumber: *currentBlockNumber,
@@ -155,50 +141,56 @@ func (vs *VariableStorage) writeContiguousRecord(currentBlock *[]byte, currentBl
 	*currentBlock = append(*currentBlock, recordData...)
 	blockStats.BytesUsed += recordSize
 	blockStats.RecordsCount++
+	blockStats.LengthPrefixBytes += vs.codec.lengthPrefixes
+	return nil
 }
 
-func (vs *VariableStorage) writeBlock(file *os.File, block []byte) {
-	paddedBlock := make([]byte, vs.blockSize)
-	copy(paddedBlock, block)
-	file.Write(paddedBlock)
-}
-
-func (vs *VariableStorage) calculateFinalStats() {
-	if vs.stats.TotalBytesTotal > 0 {
-		vs.stats.EfficiencyRate = float64(vs.stats.TotalBytesUsed) / float64(vs.stats.TotalBytesTotal) * 100
+func (vs *VariableStorage) writeBlock(device BlockDevice, blockStats BlockStats, block []byte) error {
+	blockStats.TailBytes = blockStats.BytesTotal - blockStats.BytesUsed
+	if err := device.WriteBlock(blockStats.BlockNumber, block); err != nil {
+		return err
 	}
+	return recordBlockStats(device, blockStats)
 }
 
-func (vs *VariableStorage) GetStats(filename string) StorageStats {
-	vs.recalculateStatsFromFile(filename)
-	return vs.stats
-}
+func (vs *VariableStorage) GetStats(ctx context.Context, filename string) (StorageStats, error) {
+	vs.mu.RLock()
+	defer vs.mu.RUnlock()
 
-func (vs *VariableStorage) recalculateStatsFromFile(filename string) {
-	fileInfo, err := os.Stat(filename)
+	handle, err := openFileHandle(filename, vs, vs.lockTimeout, true)
 	if err != nil {
-		return
+		return StorageStats{}, err
 	}
+	defer handle.Close()
 
-	totalBlocks := int(fileInfo.Size()) / vs.blockSize
-	vs.stats = StorageStats{
-		TotalBlocks:     totalBlocks,
-		TotalBytesTotal: totalBlocks * vs.blockSize,
-		BlockStatsList:  make([]BlockStats, 0),
-	}
+	return handle.GetBlockStats(ctx)
+}
 
-	file, err := os.Open(filename)
+func (vs *VariableStorage) statsFromDevice(device BlockDevice, totalBlocks int64) (StorageStats, error) {
+	var mu sync.Mutex
+	recordSizes := recordSizeCounter{}
+	blockStatsList, err := parallelScan(totalBlocks, vs.scanWorkers, func(start, end int64) ([]BlockStats, error) {
+		rangeSizes := recordSizeCounter{}
+		blockStatsList, err := vs.blockStatsInRange(device, start, end, rangeSizes)
+		mu.Lock()
+		recordSizes.merge(rangeSizes)
+		mu.Unlock()
+		return blockStatsList, err
+	})
 	if err != nil {
-		return
+		return StorageStats{}, err
 	}
-	defer file.Close()
 
-	totalUsed := 0
-	for blockNum := 0; blockNum < totalBlocks; blockNum++ {
-		block := make([]byte, vs.blockSize)
-		_, err := file.ReadAt(block, int64(blockNum*vs.blockSize))
-		if err != nil {
-			continue
+	return summarizeBlockStats(VariableMode, vs.blockSize, blockStatsList, recordSizes.histogram()), nil
+}
+
+func (vs *VariableStorage) blockStatsInRange(device BlockDevice, start, end int64, recordSizes recordSizeCounter) ([]BlockStats, error) {
+	blockStatsList := make([]BlockStats, 0, end-start)
+	block := getBlockBuffer(vs.blockSize)
+	defer putBlockBuffer(block)
+	for blockNum := start; blockNum < end; blockNum++ {
+		if err := device.ReadBlock(blockNum, block); err != nil {
+			return nil, err
 		}
 
 		bytesUsed := 0
@@ -209,63 +201,48 @@ func (vs *VariableStorage) recalculateStatsFromFile(filename string) {
 				break
 			}
 
-			student, err := vs.deserializeStudentFromBlock(block, offset)
-			if err != nil {
+			if vs.isBlockPadding(block, offset) {
 				break
 			}
+			_, recordSize, err := vs.recordFromBlock(block, offset)
+			if err != nil {
+				return nil, corruptRecordError(blockNum, offset, err)
+			}
 
-			recordSize := vs.getRecordSize(student)
 			bytesUsed += recordSize
 			recordsCount++
+			recordSizes.add(recordSize, 1)
 			offset += recordSize
 		}
 
-		totalUsed += bytesUsed
-
-		occupancyRate := float64(bytesUsed) / float64(vs.blockSize) * 100
-		blockStats := BlockStats{
-			BlockNumber:   blockNum,
-			BytesUsed:     bytesUsed,
-			BytesTotal:    vs.blockSize,
-			OccupancyRate: occupancyRate,
-			RecordsCount:  recordsCount,
-		}
-
-		if occupancyRate < 100 && occupancyRate > 0 {
-			vs.stats.PartialBlocks++
-		}
-
-		vs.stats.BlockStatsList = append(vs.stats.BlockStatsList, blockStats)
+		blockStats := newBlockStats(blockNum, bytesUsed, vs.blockSize, recordsCount)
+		blockStats.LengthPrefixBytes = recordsCount * vs.codec.lengthPrefixes
+		blockStatsList = append(blockStatsList, blockStats)
+		reportRecords(device, recordsCount)
 	}
 
-	vs.stats.TotalBytesUsed = totalUsed
-	if vs.stats.TotalBytesTotal > 0 {
-		vs.stats.EfficiencyRate = float64(vs.stats.TotalBytesUsed) / float64(vs.stats.TotalBytesTotal) * 100
-	}
+	return blockStatsList, nil
 }
 
-func (vs *VariableStorage) FindStudentByMatricula(filename string, matricula int) (*entity.Student, error) {
-	file, err := os.Open(filename)
-	if err != nil {
-		return nil, fmt.Errorf("erro ao abrir arquivo: %w", err)
-	}
-	defer file.Close()
+func (vs *VariableStorage) FindStudentByMatricula(ctx context.Context, filename string, matricula int64) (*entity.Student, error) {
+	vs.mu.RLock()
+	defer vs.mu.RUnlock()
 
-	fileInfo, err := file.Stat()
+	handle, err := openFileHandle(filename, vs, vs.lockTimeout, true)
 	if err != nil {
-		return nil, fmt.Errorf("erro ao obter informações do arquivo: %w", err)
+		return nil, err
 	}
+	defer handle.Close()
 
-	totalBlocks := int(fileInfo.Size()) / vs.blockSize
-	return vs.findStudentContiguous(file, totalBlocks, matricula)
+	return handle.FindStudentByMatricula(ctx, matricula)
 }
 
-func (vs *VariableStorage) findStudentContiguous(file *os.File, totalBlocks int, matricula int) (*entity.Student, error) {
-	for blockNum := 0; blockNum < totalBlocks; blockNum++ {
-		block := make([]byte, vs.blockSize)
-		_, err := file.ReadAt(block, int64(blockNum*vs.blockSize))
-		if err != nil {
-			continue
+func (vs *VariableStorage) findRecord(device BlockDevice, totalBlocks int64, key int64) (entity.Record, error) {
+	block := getBlockBuffer(vs.blockSize)
+	defer putBlockBuffer(block)
+	for blockNum := int64(0); blockNum < totalBlocks; blockNum++ {
+		if err := device.ReadBlock(blockNum, block); err != nil {
+			return nil, err
 		}
 
 		offset := 0
@@ -274,215 +251,143 @@ func (vs *VariableStorage) findStudentContiguous(file *os.File, totalBlocks int,
 				break
 			}
 
-			matriculaBytes := block[offset : offset+4]
-			readMatricula := int(binary.LittleEndian.Uint32(matriculaBytes))
-			
-			if readMatricula == matricula {
-				student, err := vs.deserializeStudentFromBlock(block, offset)
-				if err == nil {
-					return student, nil
-				}
+			if vs.isBlockPadding(block, offset) {
+				break
 			}
-
-			student, err := vs.deserializeStudentFromBlock(block, offset)
+			record, recordSize, err := vs.recordFromBlock(block, offset)
 			if err != nil {
-				break
+				return nil, corruptRecordError(blockNum, offset, err)
 			}
-			offset += vs.getRecordSize(student)
+
+			if vs.codec.schema.Key(record) == key {
+				return record, nil
+			}
+			offset += recordSize
 		}
 	}
 
-	return nil, fmt.Errorf("aluno com matrícula %d não encontrado", matricula)
+	return nil, vs.codec.notFound(key)
 }
 
-func (vs *VariableStorage) deserializeStudentFromBlock(block []byte, offset int) (*entity.Student, error) {
+func (vs *VariableStorage) recordFromBlock(block []byte, offset int) (entity.Record, int, error) {
 	if offset+4 > len(block) {
-		return nil, fmt.Errorf("offset fora dos limites")
+		return nil, 0, fmt.Errorf("offset fora dos limites")
 	}
 
-	recordData := block[offset:]
-	student, err := vs.deserializeStudent(recordData)
-	if err != nil {
-		return nil, err
-	}
-
-	return student, nil
+	return vs.codec.decode(block[offset:], true)
 }
 
-func (vs *VariableStorage) getRecordSize(student *entity.Student) int {
-	size := 4
-	size += 4 + len(student.Nome)
-	size += 11
-	size += 4 + len(student.Curso)
-	size += 4 + len(student.FiliacaoMae)
-	size += 4 + len(student.FiliacaoPai)
-	size += 4
-	size += 8
-	return size
+func (vs *VariableStorage) isBlockPadding(block []byte, offset int) bool {
+	return isZeroed(block[offset:])
 }
 
-func (vs *VariableStorage) deserializeStudent(data []byte) (*entity.Student, error) {
-	if len(data) < 4 {
-		return nil, fmt.Errorf("dados insuficientes")
-	}
-
-	offset := 0
-
-	matricula := int(binary.LittleEndian.Uint32(data[offset : offset+4]))
-	offset += 4
-
-	if offset+4 > len(data) {
-		return nil, fmt.Errorf("dados insuficientes para nome")
-	}
-	nomeLen := int(binary.LittleEndian.Uint32(data[offset : offset+4]))
-	offset += 4
-
-	if offset+nomeLen > len(data) {
-		return nil, fmt.Errorf("dados insuficientes para nome")
-	}
-	nome := string(data[offset : offset+nomeLen])
-	offset += nomeLen
-
-	if offset+11 > len(data) {
-		return nil, fmt.Errorf("dados insuficientes para CPF")
-	}
-	cpf := string(data[offset : offset+11])
-	offset += 11
-
-	if offset+4 > len(data) {
-		return nil, fmt.Errorf("dados insuficientes para curso")
-	}
-	cursoLen := int(binary.LittleEndian.Uint32(data[offset : offset+4]))
-	offset += 4
-
-	if offset+cursoLen > len(data) {
-		return nil, fmt.Errorf("dados insuficientes para curso")
-	}
-	curso := string(data[offset : offset+cursoLen])
-	offset += cursoLen
-
-	if offset+4 > len(data) {
-		return nil, fmt.Errorf("dados insuficientes para filiação mãe")
-	}
-	maeLen := int(binary.LittleEndian.Uint32(data[offset : offset+4]))
-	offset += 4
+func (vs *VariableStorage) GetBlockSize() int {
+	return vs.blockSize
+}
 
-	if offset+maeLen > len(data) {
-		return nil, fmt.Errorf("dados insuficientes para filiação mãe")
-	}
-	filiacaoMae := string(data[offset : offset+maeLen])
-	offset += maeLen
+func (vs *VariableStorage) storageMode() Mode {
+	return VariableMode
+}
 
-	if offset+4 > len(data) {
-		return nil, fmt.Errorf("dados insuficientes para filiação pai")
-	}
-	paiLen := int(binary.LittleEndian.Uint32(data[offset : offset+4]))
-	offset += 4
+func (vs *VariableStorage) recordSize(record entity.Record) int {
+	return vs.codec.variableSize(record)
+}
 
-	if offset+paiLen > len(data) {
-		return nil, fmt.Errorf("dados insuficientes para filiação pai")
-	}
-	filiacaoPai := string(data[offset : offset+paiLen])
-	offset += paiLen
+func (vs *VariableStorage) recordEncoding() Encoding {
+	return vs.codec.encoding
+}
 
-	if offset+4 > len(data) {
-		return nil, fmt.Errorf("dados insuficientes para ano de ingresso")
-	}
-	anoIngresso := int(binary.LittleEndian.Uint32(data[offset : offset+4]))
-	offset += 4
+func (vs *VariableStorage) recordSchema() *entity.Schema {
+	return vs.codec.schema
+}
 
-	if offset+8 > len(data) {
-		return nil, fmt.Errorf("dados insuficientes para CA")
-	}
-	caValue := binary.LittleEndian.Uint64(data[offset : offset+8])
-	ca := float64(caValue) / 100.0
-
-	student := &entity.Student{
-		Matricula:   matricula,
-		Nome:        nome,
-		CPF:         cpf,
-		Curso:       curso,
-		FiliacaoMae: filiacaoMae,
-		FiliacaoPai: filiacaoPai,
-		AnoIngresso: anoIngresso,
-		CA:          ca,
-	}
+func (vs *VariableStorage) GetAllStudents(ctx context.Context, filename string) ([]*entity.Student, error) {
+	return vs.FindStudents(ctx, filename, nil)
+}
 
-	student.TruncateFields()
+func (vs *VariableStorage) FindStudents(ctx context.Context, filename string, predicate func(*entity.Student) bool) ([]*entity.Student, error) {
+	vs.mu.RLock()
+	defer vs.mu.RUnlock()
 
-	if err := student.Validate(); err != nil {
-		return nil, fmt.Errorf("estudante deserializado inválido: %w", err)
+	handle, err := openFileHandle(filename, vs, vs.lockTimeout, true)
+	if err != nil {
+		return nil, err
 	}
+	defer handle.Close()
 
-	return student, nil
+	return handle.FindStudents(ctx, predicate)
 }
 
-func (vs *VariableStorage) GetBlockSize() int {
-	return vs.blockSize
+func (vs *VariableStorage) readRecords(device BlockDevice, totalBlocks int64, predicate func(entity.Record) bool) ([]entity.Record, error) {
+	return parallelScan(totalBlocks, vs.scanWorkers, func(start, end int64) ([]entity.Record, error) {
+		return vs.readRecordsInRange(device, start, end, predicate)
+	})
 }
 
-func (vs *VariableStorage) GetAllStudents(filename string) ([]*entity.Student, error) {
-	file, err := os.Open(filename)
-	if err != nil {
-		return nil, fmt.Errorf("erro ao abrir arquivo: %w", err)
-	}
-	defer file.Close()
-
-	fileInfo, err := file.Stat()
+func (vs *VariableStorage) readRecordsInRange(device BlockDevice, start, end int64, predicate func(entity.Record) bool) ([]entity.Record, error) {
+	records := make([]entity.Record, 0)
+	err := vs.visitRecordsInRange(device, start, end, func(record entity.Record) bool {
+		if predicate == nil || predicate(record) {
+			records = append(records, record)
+		}
+		return true
+	})
 	if err != nil {
-		return nil, fmt.Errorf("erro ao obter informações do arquivo: %w", err)
+		return nil, err
 	}
+	return records, nil
+}
 
-	totalBlocks := int(fileInfo.Size()) / vs.blockSize
-	students := make([]*entity.Student, 0)
+func (vs *VariableStorage) scanRecords(device BlockDevice, totalBlocks int64, visit func(entity.Record) bool) error {
+	return vs.visitRecordsInRange(device, 0, totalBlocks, visit)
+}
 
-	for blockNum := 0; blockNum < totalBlocks; blockNum++ {
-		block := make([]byte, vs.blockSize)
-		_, err := file.ReadAt(block, int64(blockNum*vs.blockSize))
-		if err != nil {
-			continue
+func (vs *VariableStorage) visitRecordsInRange(device BlockDevice, start, end int64, visit func(entity.Record) bool) error {
+	block := getBlockBuffer(vs.blockSize)
+	defer putBlockBuffer(block)
+	for blockNum := start; blockNum < end; blockNum++ {
+		if err := device.ReadBlock(blockNum, block); err != nil {
+			return err
 		}
 
+		recordsCount := 0
 		offset := 0
 		for offset < vs.blockSize {
 			if offset+4 > vs.blockSize {
 				break
 			}
 
-			student, err := vs.deserializeStudentFromBlock(block, offset)
-			if err != nil {
+			if vs.isBlockPadding(block, offset) {
 				break
 			}
+			record, recordSize, err := vs.recordFromBlock(block, offset)
+			if err != nil {
+				return corruptRecordError(blockNum, offset, err)
+			}
+			recordsCount++
 			
-			if student.Matricula > 0 {
-				students = append(students, student)
+			if !visit(record) {
+				reportRecords(device, recordsCount)
+				return nil
 			}
 			
-			offset += vs.getRecordSize(student)
+			offset += recordSize
 		}
+		reportRecords(device, recordsCount)
 	}
 
-	return students, nil
+	return nil
 }
 
-func (vs *VariableStorage) AddStudents(filename string, students []entity.Student) error {
-	existingStudents, err := vs.GetAllStudents(filename)
-	if err != nil {
-		return fmt.Errorf("erro ao ler alunos existentes: %w", err)
-	}
+func (vs *VariableStorage) AddStudents(ctx context.Context, filename string, students []entity.Student) error {
+	vs.mu.Lock()
+	defer vs.mu.Unlock()
 
-	allStudents := make([]entity.Student, len(existingStudents))
-	for i, s := range existingStudents {
-		allStudents[i] = *s
-	}
-
-	allStudents = append(allStudents, students...)
-
-	err = vs.WriteStudents(filename, allStudents)
+	handle, err := openFileHandle(filename, vs, vs.lockTimeout, false)
 	if err != nil {
 		return err
 	}
+	defer handle.Close()
 
-	vs.calculateFinalStats()
-	return nil
+	return handle.AddStudents(ctx, students)
 }
@@ -2,22 +2,39 @@ package storage
 
 import (
 	"aeds2-tp1/entity"
+	"context"
 	"encoding/binary"
+	"errors"
 	"fmt"
-	"os"
+	"iter"
+	"sync"
+	"time"
 )
 
 type VariableFragmentedStorage struct {
-	blockSize int
-	stats     StorageStats
+	mu          sync.RWMutex
+	blockSize   int
+	lockTimeout time.Duration
+	scanWorkers int
+	codec       *recordCodec
+}
+
+const fragmentHeaderSize = 5
+
+type fragment struct {
+	continues bool
+	data      []byte
 }
 
 func NewVariableFragmentedStorage(blockSize int) (*VariableFragmentedStorage, error) {
+	return newVariableFragmentedStorage(blockSize, newRecordCodec(entity.StudentSchema(), UTF8Encoding))
+}
+
+func newVariableFragmentedStorage(blockSize int, codec *recordCodec) (*VariableFragmentedStorage, error) {
 	vfs := &VariableFragmentedStorage{
-		blockSize: blockSize,
-		stats: StorageStats{
-			BlockStatsList: make([]BlockStats, 0),
-		},
+		blockSize:   blockSize,
+		scanWorkers: defaultScanWorkers(),
+		codec:       codec,
 	}
 
 	if err := vfs.ValidateBlockSize(blockSize); err != nil {
@@ -27,15 +44,29 @@ func NewVariableFragmentedStorage(blockSize int) (*VariableFragmentedStorage, er
 	return vfs, nil
 }
 
+func (vfs *VariableFragmentedStorage) SetLockTimeout(timeout time.Duration) {
+	vfs.mu.Lock()
+	defer vfs.mu.Unlock()
+	vfs.lockTimeout = timeout
+}
+
+func (vfs *VariableFragmentedStorage) SetEncoding(encoding Encoding) {
+	vfs.mu.Lock()
+	defer vfs.mu.Unlock()
+	vfs.codec = vfs.codec.withEncoding(encoding)
+}
+
+func (vfs *VariableFragmentedStorage) SetScanWorkers(workers int) {
+	vfs.mu.Lock()
+	defer vfs.mu.Unlock()
+	if workers < 1 {
+		workers = 1
+	}
+	vfs.scanWorkers = workers
+}
+
 func (vfs *VariableFragmentedStorage) ValidateBlockSize(blockSize int) error {
-	minSize := 4 +
-		4 + entity.MaxNomeLength +
-		entity.CPFLength +
-		4 + entity.MaxCursoLength +
-		4 + entity.MaxFiliacaoLength +
-		4 + entity.MaxFiliacaoLength +
-		4 +
-		8
+	minSize := vfs.codec.maxVariableSize
 
 	if blockSize < minSize {
 		return fmt.Errorf("tamanho do bloco (%d bytes) é menor que o tamanho mínimo necessário para um registro variável (%d bytes)", blockSize, minSize)
@@ -44,544 +75,427 @@ func (vfs *VariableFragmentedStorage) ValidateBlockSize(blockSize int) error {
 	return nil
 }
 
-func (vfs *VariableFragmentedStorage) WriteStudents(filename string, students []entity.Student) error {
-	file, err := os.Create(filename)
+func (vfs *VariableFragmentedStorage) WriteStudents(ctx context.Context, filename string, students []entity.Student) error {
+	vfs.mu.Lock()
+	defer vfs.mu.Unlock()
+
+	handle, err := openFileHandle(filename, vfs, vfs.lockTimeout, false)
 	if err != nil {
-		return fmt.Errorf("erro ao criar arquivo: %w", err)
+		return err
 	}
-	defer file.Close()
+	defer handle.Close()
+
+	return handle.WriteStudents(ctx, students)
+}
 
-	currentBlock := make([]byte, 0, vfs.blockSize)
-	currentBlockNumber := 0
+func (vfs *VariableFragmentedStorage) writeRecordsToDevice(device BlockDevice, records iter.Seq[entity.Record]) error {
+	currentBlock := getBlockBuffer(vfs.blockSize)[:0]
+	defer putBlockBuffer(currentBlock)
+	currentBlockNumber := int64(0)
 	blockStats := BlockStats{
 		BlockNumber: currentBlockNumber,
 		BytesUsed:   0,
 		BytesTotal:  vfs.blockSize,
 	}
 
-	for i, student := range students {
-		recordData := vfs.serializeStudent(student)
-		vfs.writeFragmentedRecord(&currentBlock, &currentBlockNumber, &blockStats, recordData, file, i == len(students)-1)
+	for record := range records {
+		recordData, err := vfs.codec.encode(record, true)
+		if err != nil {
+			return err
+		}
+		if err := vfs.writeFragmentedRecord(&currentBlock, &currentBlockNumber, &blockStats, recordData, device); err != nil {
+			return err
+		}
+		reportRecords(device, 1)
+		recordRecordSize(device, len(recordData))
 	}
 
 	if len(currentBlock) > 0 {
 		blockStats.OccupancyRate = float64(blockStats.BytesUsed) / float64(blockStats.BytesTotal) * 100
-		vfs.stats.BlockStatsList = append(vfs.stats.BlockStatsList, blockStats)
-		vfs.writeBlock(file, currentBlock)
-		vfs.stats.TotalBlocks++
-		vfs.stats.TotalBytesUsed += blockStats.BytesUsed
-		vfs.stats.TotalBytesTotal += blockStats.BytesTotal
+		if err := vfs.writeBlock(device, blockStats, currentBlock); err != nil {
+			return err
+		}
 	}
 
-	vfs.calculateFinalStats()
 	return nil
 }
 
-func (vfs *VariableFragmentedStorage) writeFragmentedRecord(currentBlock *[]byte, currentBlockNumber *int, blockStats *BlockStats, recordData []byte, file *os.File, isLast bool) {
-	recordSize := len(recordData)
-	availableSpace := vfs.blockSize - len(*currentBlock)
-
-	if recordSize <= availableSpace {
-		*currentBlock = append(*currentBlock, recordData...)
-		blockStats.BytesUsed += recordSize
-		blockStats.RecordsCount++
-	} else {
-		if len(*currentBlock) > 0 {
-			blockStats.OccupancyRate = float64(blockStats.BytesUsed) / float64(blockStats.BytesTotal) * 100
-			if blockStats.OccupancyRate < 100 {
-				vfs.stats.PartialBlocks++
-			}
-			vfs.stats.BlockStatsList = append(vfs.stats.BlockStatsList, *blockStats)
-			vfs.writeBlock(file, *currentBlock)
-			vfs.stats.TotalBlocks++
-			vfs.stats.TotalBytesUsed += blockStats.BytesUsed
-			vfs.stats.TotalBytesTotal += blockStats.BytesTotal
-		}
+func (vfs *VariableFragmentedStorage) writeFragmentedRecord(currentBlock *[]byte, currentBlockNumber *int64, blockStats *BlockStats, recordData []byte, device BlockDevice) error {
+	remainingData := recordData
 
-		remainingData := recordData
-		headerSize := 5
-		isFirstChunk := true
-
-		for len(remainingData) > 0 {
-			*currentBlock = make([]byte, 0, vfs.blockSize)
-			*currentBlockNumber++
-			*blockStats = BlockStats{
-				BlockNumber: *currentBlockNumber,
-				BytesUsed:   0,
-				BytesTotal:  vfs.blockSize,
-			}
-
-			spaceAvailable := vfs.blockSize - headerSize
-			chunkSize := len(remainingData)
-			if chunkSize > spaceAvailable {
-				chunkSize = spaceAvailable
+	for len(remainingData) > 0 {
+		spaceAvailable := vfs.blockSize - len(*currentBlock) - fragmentHeaderSize
+		if spaceAvailable < 1 {
+			if err := vfs.flushBlock(currentBlock, currentBlockNumber, blockStats, device); err != nil {
+				return err
 			}
+			continue
+		}
 
-			continuationFlag := byte(1)
-			if len(remainingData) <= spaceAvailable {
-				continuationFlag = byte(0)
-			}
+		chunkSize := len(remainingData)
+		if chunkSize > spaceAvailable {
+			chunkSize = spaceAvailable
+		}
 
-			*currentBlock = append(*currentBlock, continuationFlag)
+		continuationFlag := byte(0)
+		if chunkSize < len(remainingData) {
+			continuationFlag = byte(1)
+		}
 
-			sizeBytes := make([]byte, 4)
-			binary.LittleEndian.PutUint32(sizeBytes, uint32(chunkSize))
-			*currentBlock = append(*currentBlock, sizeBytes...)
+		if len(*currentBlock) == 0 && len(remainingData) < len(recordData) {
+			blockStats.StartsWithContinuation = true
+		}
+		blockStats.EndsWithContinuation = continuationFlag == 1
+		blockStats.FragmentsCount++
 
-			*currentBlock = append(*currentBlock, remainingData[:chunkSize]...)
-			blockStats.BytesUsed += headerSize + chunkSize
-			if isFirstChunk {
-				blockStats.RecordsCount++
-				isFirstChunk = false
-			}
+		*currentBlock = append(*currentBlock, continuationFlag)
 
-			remainingData = remainingData[chunkSize:]
+		sizeBytes := make([]byte, 4)
+		binary.LittleEndian.PutUint32(sizeBytes, uint32(chunkSize))
+		*currentBlock = append(*currentBlock, sizeBytes...)
 
-			blockStats.OccupancyRate = float64(blockStats.BytesUsed) / float64(blockStats.BytesTotal) * 100
-			if blockStats.OccupancyRate < 100 {
-				vfs.stats.PartialBlocks++
-			}
-			vfs.stats.BlockStatsList = append(vfs.stats.BlockStatsList, *blockStats)
-			vfs.writeBlock(file, *currentBlock)
-			vfs.stats.TotalBlocks++
-			vfs.stats.TotalBytesUsed += blockStats.BytesUsed
-			vfs.stats.TotalBytesTotal += blockStats.BytesTotal
+		*currentBlock = append(*currentBlock, remainingData[:chunkSize]...)
+		blockStats.BytesUsed += fragmentHeaderSize + chunkSize
+		blockStats.HeaderBytes += fragmentHeaderSize
+		if continuationFlag == 0 {
+			blockStats.RecordsCount++
+			blockStats.LengthPrefixBytes += vfs.codec.lengthPrefixes
 		}
+
+		remainingData = remainingData[chunkSize:]
 	}
-}
 
-func (vfs *VariableFragmentedStorage) serializeStudent(student entity.Student) []byte {
-	data := make([]byte, 0)
-
-	matriculaBytes := make([]byte, 4)
-	binary.LittleEndian.PutUint32(matriculaBytes, uint32(student.Matricula))
-	data = append(data, matriculaBytes...)
-
-	nomeBytes := []byte(student.Nome)
-	nomeLen := make([]byte, 4)
-	binary.LittleEndian.PutUint32(nomeLen, uint32(len(nomeBytes)))
-	data = append(data, nomeLen...)
-	data = append(data, nomeBytes...)
-
-	cpfBytes := []byte(student.CPF)
-	data = append(data, cpfBytes...)
-
-	cursoBytes := []byte(student.Curso)
-	cursoLen := make([]byte, 4)
-	binary.LittleEndian.PutUint32(cursoLen, uint32(len(cursoBytes)))
-	data = append(data, cursoLen...)
-	data = append(data, cursoBytes...)
-
-	maeBytes := []byte(student.FiliacaoMae)
-	maeLen := make([]byte, 4)
-	binary.LittleEndian.PutUint32(maeLen, uint32(len(maeBytes)))
-	data = append(data, maeLen...)
-	data = append(data, maeBytes...)
-
-	paiBytes := []byte(student.FiliacaoPai)
-	paiLen := make([]byte, 4)
-	binary.LittleEndian.PutUint32(paiLen, uint32(len(paiBytes)))
-	data = append(data, paiLen...)
-	data = append(data, paiBytes...)
-
-	anoBytes := make([]byte, 4)
-	binary.LittleEndian.PutUint32(anoBytes, uint32(student.AnoIngresso))
-	data = append(data, anoBytes...)
-
-	caBytes := make([]byte, 8)
-	binary.LittleEndian.PutUint64(caBytes, uint64(student.CA*100))
-	data = append(data, caBytes...)
-
-	return data
+	return nil
 }
 
-func (vfs *VariableFragmentedStorage) writeBlock(file *os.File, block []byte) {
-	paddedBlock := make([]byte, vfs.blockSize)
-	copy(paddedBlock, block)
-	file.Write(paddedBlock)
-}
+func (vfs *VariableFragmentedStorage) flushBlock(currentBlock *[]byte, currentBlockNumber *int64, blockStats *BlockStats, device BlockDevice) error {
+	blockStats.OccupancyRate = float64(blockStats.BytesUsed) / float64(blockStats.BytesTotal) * 100
+	if err := vfs.writeBlock(device, *blockStats, *currentBlock); err != nil {
+		return err
+	}
 
-func (vfs *VariableFragmentedStorage) calculateFinalStats() {
-	if vfs.stats.TotalBytesTotal > 0 {
-		vfs.stats.EfficiencyRate = float64(vfs.stats.TotalBytesUsed) / float64(vfs.stats.TotalBytesTotal) * 100
+	*currentBlock = (*currentBlock)[:0]
+	*currentBlockNumber++
+	*blockStats = BlockStats{
+		BlockNumber: *currentBlockNumber,
+		BytesUsed:   0,
+		BytesTotal:  vfs.blockSize,
 	}
+	return nil
 }
 
-func (vfs *VariableFragmentedStorage) GetStats(filename string) StorageStats {
-	vfs.recalculateStatsFromFile(filename)
-	return vfs.stats
+func (vfs *VariableFragmentedStorage) writeBlock(device BlockDevice, blockStats BlockStats, block []byte) error {
+	blockStats.TailBytes = blockStats.BytesTotal - blockStats.BytesUsed
+	if err := device.WriteBlock(blockStats.BlockNumber, block); err != nil {
+		return err
+	}
+	return recordBlockStats(device, blockStats)
 }
 
-func (vfs *VariableFragmentedStorage) recalculateStatsFromFile(filename string) {
-	fileInfo, err := os.Stat(filename)
+func (vfs *VariableFragmentedStorage) GetStats(ctx context.Context, filename string) (StorageStats, error) {
+	vfs.mu.RLock()
+	defer vfs.mu.RUnlock()
+
+	handle, err := openFileHandle(filename, vfs, vfs.lockTimeout, true)
 	if err != nil {
-		return
+		return StorageStats{}, err
 	}
+	defer handle.Close()
+
+	return handle.GetBlockStats(ctx)
+}
 
-	totalBlocks := int(fileInfo.Size()) / vfs.blockSize
-	vfs.stats = StorageStats{
-		TotalBlocks:     totalBlocks,
-		TotalBytesTotal: totalBlocks * vfs.blockSize,
-		BlockStatsList:  make([]BlockStats, 0),
+func (vfs *VariableFragmentedStorage) statsFromDevice(device BlockDevice, totalBlocks int64) (StorageStats, error) {
+	var mu sync.Mutex
+	recordSizes := recordSizeCounter{}
+	blockStatsList, err := parallelScan(totalBlocks, vfs.scanWorkers, func(start, end int64) ([]BlockStats, error) {
+		rangeSizes := recordSizeCounter{}
+		blockStatsList, err := vfs.blockStatsInRange(device, start, end, totalBlocks, rangeSizes)
+		mu.Lock()
+		recordSizes.merge(rangeSizes)
+		mu.Unlock()
+		return blockStatsList, err
+	})
+	if err != nil {
+		return StorageStats{}, err
 	}
 
-	file, err := os.Open(filename)
+	return summarizeBlockStats(FragmentedMode, vfs.blockSize, blockStatsList, recordSizes.histogram()), nil
+}
+
+func (vfs *VariableFragmentedStorage) blockStatsInRange(device BlockDevice, start, end, totalBlocks int64, recordSizes recordSizeCounter) ([]BlockStats, error) {
+	blockStatsList := make([]BlockStats, 0, end-start)
+	block := getBlockBuffer(vfs.blockSize)
+	defer putBlockBuffer(block)
+
+	skipping, err := vfs.continuesFromPrevious(device, start, block)
 	if err != nil {
-		return
+		return nil, err
 	}
-	defer file.Close()
 
-	totalUsed := 0
-	for blockNum := 0; blockNum < totalBlocks; blockNum++ {
-		block := make([]byte, vfs.blockSize)
-		_, err := file.ReadAt(block, int64(blockNum*vfs.blockSize))
-		if err != nil {
-			continue
+	previousContinues := skipping
+	pending := false
+	pendingSize := 0
+	for blockNum := start; blockNum < totalBlocks; blockNum++ {
+		if blockNum >= end && !pending {
+			break
+		}
+
+		if err := device.ReadBlock(blockNum, block); err != nil {
+			return nil, err
 		}
 
 		bytesUsed := 0
 		recordsCount := 0
-		offset := 0
-		for offset < vfs.blockSize {
-			if offset+5 > vfs.blockSize {
-				break
+		fragments := vfs.parseFragments(block)
+		for _, frag := range fragments {
+			bytesUsed += fragmentHeaderSize + len(frag.data)
+			if !frag.continues {
+				recordsCount++
 			}
 
-			continuationFlag := block[offset]
-			chunkSizeBytes := block[offset+1 : offset+5]
-			chunkSize := int(binary.LittleEndian.Uint32(chunkSizeBytes))
-
-			if offset+5+chunkSize > vfs.blockSize {
-				break
+			if skipping {
+				skipping = frag.continues
+				continue
 			}
-
-			bytesUsed += 5 + chunkSize
-			if continuationFlag == 0 {
-				recordsCount++
+			if !pending && blockNum >= end {
+				break
 			}
 
-			if continuationFlag == 0 {
-				offset += 5 + chunkSize
-			} else {
-				offset = vfs.blockSize
+			pending = true
+			pendingSize += len(frag.data)
+			if !frag.continues {
+				recordSizes.add(pendingSize, 1)
+				pending = false
+				pendingSize = 0
 			}
 		}
 
-		totalUsed += bytesUsed
-
-		occupancyRate := float64(bytesUsed) / float64(vfs.blockSize) * 100
-		blockStats := BlockStats{
-			BlockNumber:   blockNum,
-			BytesUsed:     bytesUsed,
-			BytesTotal:    vfs.blockSize,
-			OccupancyRate: occupancyRate,
-			RecordsCount:  recordsCount,
+		if blockNum >= end {
+			continue
 		}
 
-		if occupancyRate < 100 && occupancyRate > 0 {
-			vfs.stats.PartialBlocks++
+		blockStats := newBlockStats(blockNum, bytesUsed, vfs.blockSize, recordsCount)
+		blockStats.LengthPrefixBytes = recordsCount * vfs.codec.lengthPrefixes
+		blockStats.HeaderBytes = len(fragments) * fragmentHeaderSize
+		blockStats.FragmentsCount = len(fragments)
+		if len(fragments) > 0 {
+			blockStats.StartsWithContinuation = previousContinues
+			blockStats.EndsWithContinuation = fragments[len(fragments)-1].continues
 		}
-
-		vfs.stats.BlockStatsList = append(vfs.stats.BlockStatsList, blockStats)
+		previousContinues = blockStats.EndsWithContinuation
+		blockStatsList = append(blockStatsList, blockStats)
+		reportRecords(device, recordsCount)
 	}
 
-	vfs.stats.TotalBytesUsed = totalUsed
-	if vfs.stats.TotalBytesTotal > 0 {
-		vfs.stats.EfficiencyRate = float64(vfs.stats.TotalBytesUsed) / float64(vfs.stats.TotalBytesTotal) * 100
-	}
+	return blockStatsList, nil
 }
 
-func (vfs *VariableFragmentedStorage) GetBlockSize() int {
-	return vfs.blockSize
+func (vfs *VariableFragmentedStorage) continuesFromPrevious(device BlockDevice, blockNum int64, block []byte) (bool, error) {
+	if blockNum == 0 {
+		return false, nil
+	}
+	if err := device.ReadBlock(blockNum-1, block); err != nil {
+		return false, err
+	}
+	fragments := vfs.parseFragments(block)
+	return len(fragments) > 0 && fragments[len(fragments)-1].continues, nil
 }
 
-func (vfs *VariableFragmentedStorage) FindStudentByMatricula(filename string, matricula int) (*entity.Student, error) {
-	file, err := os.Open(filename)
-	if err != nil {
-		return nil, fmt.Errorf("erro ao abrir arquivo: %w", err)
+func (vfs *VariableFragmentedStorage) parseFragments(block []byte) []fragment {
+	fragments := make([]fragment, 0)
+	offset := 0
+	for offset+fragmentHeaderSize <= len(block) {
+		chunkSize := int(binary.LittleEndian.Uint32(block[offset+1 : offset+fragmentHeaderSize]))
+		if chunkSize == 0 || offset+fragmentHeaderSize+chunkSize > len(block) {
+			break
+		}
+
+		fragments = append(fragments, fragment{
+			continues: block[offset] == 1,
+			data:      block[offset+fragmentHeaderSize : offset+fragmentHeaderSize+chunkSize],
+		})
+		offset += fragmentHeaderSize + chunkSize
 	}
-	defer file.Close()
+	return fragments
+}
 
-	fileInfo, err := file.Stat()
+func (vfs *VariableFragmentedStorage) scanRecordData(device BlockDevice, start, end, totalBlocks int64, visit func(blockNum int64, record []byte) bool) error {
+	block := getBlockBuffer(vfs.blockSize)
+	defer putBlockBuffer(block)
+
+	skipping, err := vfs.continuesFromPrevious(device, start, block)
 	if err != nil {
-		return nil, fmt.Errorf("erro ao obter informações do arquivo: %w", err)
+		return err
 	}
 
-	totalBlocks := int(fileInfo.Size()) / vfs.blockSize
-	return vfs.findStudentFragmented(file, totalBlocks, matricula)
-}
+	var pending []byte
+	pendingStart := int64(0)
+	for blockNum := start; blockNum < totalBlocks; blockNum++ {
+		if blockNum >= end && pending == nil {
+			break
+		}
 
-func (vfs *VariableFragmentedStorage) findStudentFragmented(file *os.File, totalBlocks int, matricula int) (*entity.Student, error) {
-	for blockNum := 0; blockNum < totalBlocks; blockNum++ {
-		block := make([]byte, vfs.blockSize)
-		_, err := file.ReadAt(block, int64(blockNum*vfs.blockSize))
-		if err != nil {
-			continue
+		if err := device.ReadBlock(blockNum, block); err != nil {
+			return err
 		}
 
-		offset := 0
-		for offset < vfs.blockSize {
-			if offset+5 > vfs.blockSize {
-				break
+		for _, frag := range vfs.parseFragments(block) {
+			if skipping {
+				skipping = frag.continues
+				continue
 			}
-
-			continuationFlag := block[offset]
-			chunkSizeBytes := block[offset+1 : offset+5]
-			chunkSize := int(binary.LittleEndian.Uint32(chunkSizeBytes))
-
-			if offset+5+chunkSize > vfs.blockSize {
-				break
+			if blockNum >= end && pending == nil {
+				return nil
 			}
 
-			chunkData := make([]byte, chunkSize)
-			copy(chunkData, block[offset+5:offset+5+chunkSize])
-
-			recordData := chunkData
-			currentBlockNum := blockNum
-			currentContFlag := continuationFlag
-
-			for currentContFlag == 1 {
-				currentBlockNum++
-				if currentBlockNum >= totalBlocks {
-					break
-				}
-
-				nextBlock := make([]byte, vfs.blockSize)
-				_, err := file.ReadAt(nextBlock, int64(currentBlockNum*vfs.blockSize))
-				if err != nil {
-					break
-				}
-
-				nextContFlag := nextBlock[0]
-				nextChunkSizeBytes := nextBlock[1:5]
-				nextChunkSize := int(binary.LittleEndian.Uint32(nextChunkSizeBytes))
-
-				if 5+nextChunkSize <= vfs.blockSize {
-					recordData = append(recordData, nextBlock[5:5+nextChunkSize]...)
-				}
-
-				currentContFlag = nextContFlag
+			if pending == nil {
+				pendingStart = blockNum
 			}
-
-			if len(recordData) >= 4 {
-				readMatricula := int(binary.LittleEndian.Uint32(recordData[:4]))
-				if readMatricula == matricula {
-					student, err := vfs.deserializeStudent(recordData)
-					if err == nil {
-						return student, nil
-					}
-				}
+			pending = append(pending, frag.data...)
+			if frag.continues {
+				continue
 			}
 
-			if continuationFlag == 0 {
-				offset += 5 + chunkSize
-			} else {
-				offset = vfs.blockSize
+			recordData := pending
+			pending = nil
+			if !visit(pendingStart, recordData) {
+				return nil
 			}
 		}
 	}
 
-	return nil, fmt.Errorf("aluno com matrícula %d não encontrado", matricula)
-}
-
-func (vfs *VariableFragmentedStorage) deserializeStudent(data []byte) (*entity.Student, error) {
-	if len(data) < 4 {
-		return nil, fmt.Errorf("dados insuficientes")
-	}
-
-	offset := 0
-
-	matricula := int(binary.LittleEndian.Uint32(data[offset : offset+4]))
-	offset += 4
-
-	if offset+4 > len(data) {
-		return nil, fmt.Errorf("dados insuficientes para nome")
-	}
-	nomeLen := int(binary.LittleEndian.Uint32(data[offset : offset+4]))
-	offset += 4
-
-	if offset+nomeLen > len(data) {
-		return nil, fmt.Errorf("dados insuficientes para nome")
+	if pending != nil {
+		return corruptRecordError(pendingStart, 0, errors.New("registro fragmentado sem fragmento final"))
 	}
-	nome := string(data[offset : offset+nomeLen])
-	offset += nomeLen
+	return nil
+}
 
-	if offset+11 > len(data) {
-		return nil, fmt.Errorf("dados insuficientes para CPF")
-	}
-	cpf := string(data[offset : offset+11])
-	offset += 11
+func (vfs *VariableFragmentedStorage) GetBlockSize() int {
+	return vfs.blockSize
+}
 
-	if offset+4 > len(data) {
-		return nil, fmt.Errorf("dados insuficientes para curso")
-	}
-	cursoLen := int(binary.LittleEndian.Uint32(data[offset : offset+4]))
-	offset += 4
+func (vfs *VariableFragmentedStorage) storageMode() Mode {
+	return FragmentedMode
+}
 
-	if offset+cursoLen > len(data) {
-		return nil, fmt.Errorf("dados insuficientes para curso")
-	}
-	curso := string(data[offset : offset+cursoLen])
-	offset += cursoLen
+func (vfs *VariableFragmentedStorage) recordSize(record entity.Record) int {
+	return vfs.codec.variableSize(record)
+}
 
-	if offset+4 > len(data) {
-		return nil, fmt.Errorf("dados insuficientes para filiação mãe")
-	}
-	maeLen := int(binary.LittleEndian.Uint32(data[offset : offset+4]))
-	offset += 4
+func (vfs *VariableFragmentedStorage) recordEncoding() Encoding {
+	return vfs.codec.encoding
+}
 
-	if offset+maeLen > len(data) {
-		return nil, fmt.Errorf("dados insuficientes para filiação mãe")
-	}
-	filiacaoMae := string(data[offset : offset+maeLen])
-	offset += maeLen
+func (vfs *VariableFragmentedStorage) recordSchema() *entity.Schema {
+	return vfs.codec.schema
+}
 
-	if offset+4 > len(data) {
-		return nil, fmt.Errorf("dados insuficientes para filiação pai")
-	}
-	paiLen := int(binary.LittleEndian.Uint32(data[offset : offset+4]))
-	offset += 4
+func (vfs *VariableFragmentedStorage) FindStudentByMatricula(ctx context.Context, filename string, matricula int64) (*entity.Student, error) {
+	vfs.mu.RLock()
+	defer vfs.mu.RUnlock()
 
-	if offset+paiLen > len(data) {
-		return nil, fmt.Errorf("dados insuficientes para filiação pai")
+	handle, err := openFileHandle(filename, vfs, vfs.lockTimeout, true)
+	if err != nil {
+		return nil, err
 	}
-	filiacaoPai := string(data[offset : offset+paiLen])
-	offset += paiLen
+	defer handle.Close()
 
-	if offset+4 > len(data) {
-		return nil, fmt.Errorf("dados insuficientes para ano de ingresso")
-	}
-	anoIngresso := int(binary.LittleEndian.Uint32(data[offset : offset+4]))
-	offset += 4
+	return handle.FindStudentByMatricula(ctx, matricula)
+}
 
-	if offset+8 > len(data) {
-		return nil, fmt.Errorf("dados insuficientes para CA")
+func (vfs *VariableFragmentedStorage) findRecord(device BlockDevice, totalBlocks int64, key int64) (entity.Record, error) {
+	var found entity.Record
+	var decodeErr error
+	err := vfs.scanRecordData(device, 0, totalBlocks, totalBlocks, func(blockNum int64, recordData []byte) bool {
+		if readKey, ok := vfs.codec.key(recordData, true); !ok || readKey != key {
+			return true
+		}
+		found, _, decodeErr = vfs.codec.decode(recordData, true)
+		if decodeErr != nil {
+			decodeErr = corruptRecordError(blockNum, 0, decodeErr)
+		}
+		return false
+	})
+	if err != nil {
+		return nil, err
 	}
-	caValue := binary.LittleEndian.Uint64(data[offset : offset+8])
-	ca := float64(caValue) / 100.0
-
-	student := &entity.Student{
-		Matricula:   matricula,
-		Nome:        nome,
-		CPF:         cpf,
-		Curso:       curso,
-		FiliacaoMae: filiacaoMae,
-		FiliacaoPai: filiacaoPai,
-		AnoIngresso: anoIngresso,
-		CA:          ca,
+	if decodeErr != nil {
+		return nil, decodeErr
 	}
 
-	student.TruncateFields()
-
-	if err := student.Validate(); err != nil {
-		return nil, fmt.Errorf("estudante deserializado inválido: %w", err)
+	if found == nil {
+		return nil, vfs.codec.notFound(key)
 	}
+	return found, nil
+}
 
-	return student, nil
+func (vfs *VariableFragmentedStorage) GetAllStudents(ctx context.Context, filename string) ([]*entity.Student, error) {
+	return vfs.FindStudents(ctx, filename, nil)
 }
 
-func (vfs *VariableFragmentedStorage) GetAllStudents(filename string) ([]*entity.Student, error) {
-	file, err := os.Open(filename)
-	if err != nil {
-		return nil, fmt.Errorf("erro ao abrir arquivo: %w", err)
-	}
-	defer file.Close()
+func (vfs *VariableFragmentedStorage) FindStudents(ctx context.Context, filename string, predicate func(*entity.Student) bool) ([]*entity.Student, error) {
+	vfs.mu.RLock()
+	defer vfs.mu.RUnlock()
 
-	fileInfo, err := file.Stat()
+	handle, err := openFileHandle(filename, vfs, vfs.lockTimeout, true)
 	if err != nil {
-		return nil, fmt.Errorf("erro ao obter informações do arquivo: %w", err)
+		return nil, err
 	}
+	defer handle.Close()
 
-	totalBlocks := int(fileInfo.Size()) / vfs.blockSize
-	students := make([]*entity.Student, 0)
-
-	for blockNum := 0; blockNum < totalBlocks; blockNum++ {
-		block := make([]byte, vfs.blockSize)
-		_, err := file.ReadAt(block, int64(blockNum*vfs.blockSize))
-		if err != nil {
-			continue
-		}
-
-		offset := 0
-		for offset < vfs.blockSize {
-			if offset+5 > vfs.blockSize {
-				break
-			}
-
-			continuationFlag := block[offset]
-			chunkSizeBytes := block[offset+1 : offset+5]
-			chunkSize := int(binary.LittleEndian.Uint32(chunkSizeBytes))
-
-			if offset+5+chunkSize > vfs.blockSize {
-				break
-			}
-
-			chunkData := make([]byte, chunkSize)
-			copy(chunkData, block[offset+5:offset+5+chunkSize])
-
-			recordData := chunkData
-			currentBlockNum := blockNum
-			currentContFlag := continuationFlag
-
-			for currentContFlag == 1 {
-				currentBlockNum++
-				if currentBlockNum >= totalBlocks {
-					break
-				}
-
-				nextBlock := make([]byte, vfs.blockSize)
-				_, err := file.ReadAt(nextBlock, int64(currentBlockNum*vfs.blockSize))
-				if err != nil {
-					break
-				}
-
-				nextContFlag := nextBlock[0]
-				nextChunkSizeBytes := nextBlock[1:5]
-				nextChunkSize := int(binary.LittleEndian.Uint32(nextChunkSizeBytes))
-
-				if 5+nextChunkSize <= vfs.blockSize {
-					recordData = append(recordData, nextBlock[5:5+nextChunkSize]...)
-				}
-
-				currentContFlag = nextContFlag
-			}
+	return handle.FindStudents(ctx, predicate)
+}
 
-			if len(recordData) >= 4 {
-				student, err := vfs.deserializeStudent(recordData)
-				if err == nil && student.Matricula > 0 {
-					students = append(students, student)
-				}
+func (vfs *VariableFragmentedStorage) readRecords(device BlockDevice, totalBlocks int64, predicate func(entity.Record) bool) ([]entity.Record, error) {
+	return parallelScan(totalBlocks, vfs.scanWorkers, func(start, end int64) ([]entity.Record, error) {
+		records := make([]entity.Record, 0)
+		var decodeErr error
+		err := vfs.scanRecordData(device, start, end, totalBlocks, func(blockNum int64, recordData []byte) bool {
+			record, _, err := vfs.codec.decode(recordData, true)
+			if err != nil {
+				decodeErr = corruptRecordError(blockNum, 0, err)
+				return false
 			}
-
-			if continuationFlag == 0 {
-				offset += 5 + chunkSize
-			} else {
-				offset = vfs.blockSize
+			reportRecords(device, 1)
+			if predicate == nil || predicate(record) {
+				records = append(records, record)
 			}
+			return true
+		})
+		if err != nil {
+			return nil, err
 		}
-	}
-
-	return students, nil
+		return records, decodeErr
+	})
 }
 
-func (vfs *VariableFragmentedStorage) AddStudents(filename string, students []entity.Student) error {
-	existingStudents, err := vfs.GetAllStudents(filename)
+func (vfs *VariableFragmentedStorage) scanRecords(device BlockDevice, totalBlocks int64, visit func(entity.Record) bool) error {
+	var decodeErr error
+	err := vfs.scanRecordData(device, 0, totalBlocks, totalBlocks, func(blockNum int64, recordData []byte) bool {
+		record, _, err := vfs.codec.decode(recordData, true)
+		if err != nil {
+			decodeErr = corruptRecordError(blockNum, 0, err)
+			return false
+		}
+		reportRecords(device, 1)
+		return visit(record)
+	})
 	if err != nil {
-		return fmt.Errorf("erro ao ler alunos existentes: %w", err)
-	}
-
-	allStudents := make([]entity.Student, len(existingStudents))
-	for i, s := range existingStudents {
-		allStudents[i] = *s
+		return err
 	}
+	return decodeErr
+}
 
-	allStudents = append(allStudents, students...)
+func (vfs *VariableFragmentedStorage) AddStudents(ctx context.Context, filename string, students []entity.Student) error {
+	vfs.mu.Lock()
+	defer vfs.mu.Unlock()
 
-	err = vfs.WriteStudents(filename, allStudents)
+	handle, err := openFileHandle(filename, vfs, vfs.lockTimeout, false)
 	if err != nil {
 		return err
 	}
+	defer handle.Close()
 
-	vfs.calculateFinalStats()
-	return nil
+	return handle.AddStudents(ctx, students)
 }